@@ -1,5 +1,7 @@
 package utils
 
+import "time"
+
 type ApiError struct {
 	Err error
 }
@@ -8,14 +10,65 @@ func (e ApiError) Error() string {
 	return "API error: " + e.Err.Error()
 }
 
+func (e ApiError) Unwrap() error {
+	return e.Err
+}
+
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
 type RequestError struct {
-	Err error
+	Err        error
+	Violations []FieldViolation
 }
 
 func (e RequestError) Error() string {
 	return "Bad request: " + e.Err.Error()
 }
 
+func (e RequestError) Unwrap() error {
+	return e.Err
+}
+
+// FieldError reports a single invalid request field
+func FieldError(field string, err error) RequestError {
+	return RequestError{
+		Err:        err,
+		Violations: []FieldViolation{{Field: field, Description: err.Error()}},
+	}
+}
+
+// PreconditionError is returned when a well-formed request can't be applied to the current
+// state, e.g. a withdrawal that would breach the account's overdraft limit
+type PreconditionError struct {
+	Err error
+}
+
+func (e PreconditionError) Error() string {
+	return "Failed precondition: " + e.Err.Error()
+}
+
+func (e PreconditionError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitError is returned when a caller has used up their allowance of calls, and can try
+// again after RetryAfter
+type RateLimitError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e RateLimitError) Error() string {
+	return "Rate limited: " + e.Err.Error()
+}
+
+func (e RateLimitError) Unwrap() error {
+	return e.Err
+}
+
 type ApiErrReason int
 
 const (
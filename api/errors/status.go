@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	errorDomain string = "chariottakehome"
+
+	// Postgres error code raised when a UNIQUE constraint is violated
+	uniqueViolationCode string = "23505"
+)
+
+// ToStatus translates an error returned by a service into a gRPC status. Errors that aren't
+// recognised are reported as Internal so database details never reach the client.
+func ToStatus(err error) *status.Status {
+	var (
+		requestErr      RequestError
+		preconditionErr PreconditionError
+		rateLimitErr    RateLimitError
+		pgErr           *pgconn.PgError
+		statusErr       interface{ GRPCStatus() *status.Status }
+	)
+
+	switch {
+	case errors.As(err, &requestErr):
+		return newStatus(codes.InvalidArgument, requestErr.Error(), "INVALID_REQUEST", badRequest(requestErr.Violations))
+	case errors.As(err, &preconditionErr):
+		return newStatus(codes.FailedPrecondition, preconditionErr.Error(), "FAILED_PRECONDITION")
+	case errors.As(err, &rateLimitErr):
+		return newStatus(codes.ResourceExhausted, rateLimitErr.Error(), "RATE_LIMITED", &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(rateLimitErr.RetryAfter),
+		})
+	case errors.As(err, &statusErr):
+		return statusErr.GRPCStatus()
+	case errors.Is(err, pgx.ErrNoRows):
+		return newStatus(codes.NotFound, "resource not found", "NOT_FOUND")
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode:
+		return newStatus(codes.AlreadyExists, "resource already exists", "ALREADY_EXISTS")
+	default:
+		return newStatus(codes.Internal, Internal.Error(), "INTERNAL")
+	}
+}
+
+// IsServerError reports whether code means the server failed, rather than the request being
+// refused
+func IsServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+func newStatus(code codes.Code, msg string, reason string, details ...protoadapt.MessageV1) *status.Status {
+	st := status.New(code, msg)
+
+	details = append(details, &errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: errorDomain,
+	})
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+
+	return withDetails
+}
+
+func badRequest(violations []FieldViolation) *errdetails.BadRequest {
+	fieldViolations := make([]*errdetails.BadRequest_FieldViolation, 0, len(violations))
+	for _, v := range violations {
+		fieldViolations = append(fieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	return &errdetails.BadRequest{FieldViolations: fieldViolations}
+}
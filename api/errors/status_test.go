@@ -0,0 +1,91 @@
+package utils_test
+
+import (
+	e "chariottakehome/api/errors"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+func TestToStatusCodes(t *testing.T) {
+	cases := []struct {
+		err  error
+		code codes.Code
+	}{
+		{e.RequestError{Err: errors.New("bad")}, codes.InvalidArgument},
+		{e.PreconditionError{Err: errors.New("insufficient funds")}, codes.FailedPrecondition},
+		{e.RateLimitError{Err: errors.New("too many calls")}, codes.ResourceExhausted},
+		{e.ApiError{Err: pgx.ErrNoRows}, codes.NotFound},
+		{e.ApiError{Err: fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505"})}, codes.AlreadyExists},
+		{e.ApiError{Err: e.Internal}, codes.Internal},
+		{errors.New("connection refused"), codes.Internal},
+	}
+
+	for _, c := range cases {
+		if got := e.ToStatus(c.err).Code(); got != c.code {
+			t.Fatalf("Expected %s for '%s', got %s", c.code, c.err, got)
+		}
+	}
+}
+
+func TestToStatusHidesInternalErrors(t *testing.T) {
+	st := e.ToStatus(e.ApiError{Err: errors.New("relation \"accounts\" does not exist")})
+
+	if st.Message() != e.Internal.Error() {
+		t.Fatalf("Internal error leaked to the client: '%s'", st.Message())
+	}
+}
+
+func TestToStatusFieldViolations(t *testing.T) {
+	st := e.ToStatus(e.FieldError("account_id", errors.New("Invalid identifier")))
+
+	var badRequest *errdetails.BadRequest
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.BadRequest); ok {
+			badRequest = d
+		}
+	}
+
+	if badRequest == nil {
+		t.Fatal("Expected a BadRequest detail")
+	}
+
+	violations := badRequest.GetFieldViolations()
+	if len(violations) != 1 || violations[0].GetField() != "account_id" {
+		t.Fatalf("Unexpected field violations: %v", violations)
+	}
+}
+
+func TestToStatusRetryInfo(t *testing.T) {
+	st := e.ToStatus(e.RateLimitError{Err: errors.New("too many calls"), RetryAfter: 1500 * time.Millisecond})
+
+	var retryInfo *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			retryInfo = d
+		}
+	}
+
+	if retryInfo == nil || retryInfo.GetRetryDelay().AsDuration() != 1500*time.Millisecond {
+		t.Fatalf("Expected a RetryInfo detail of 1.5s, got %v", retryInfo)
+	}
+}
+
+func TestIsServerError(t *testing.T) {
+	for _, code := range []codes.Code{codes.Internal, codes.Unavailable, codes.DeadlineExceeded} {
+		if !e.IsServerError(code) {
+			t.Fatalf("Expected %s to be a server error", code)
+		}
+	}
+	for _, code := range []codes.Code{codes.OK, codes.InvalidArgument, codes.NotFound, codes.ResourceExhausted} {
+		if e.IsServerError(code) {
+			t.Fatalf("Expected %s not to be a server error", code)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	e "chariottakehome/api/errors"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errRouteNotFound    = status.New(codes.NotFound, "no route matches the request path")
+	errMethodNotAllowed = status.New(codes.Unimplemented, "method not allowed for this path")
+)
+
+// errorBody is the JSON body of every failed request, derived from the gRPC status the call
+// failed with
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	// Code is the gRPC status code name, e.g. INVALID_ARGUMENT
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Reason is the machine-readable cause from the status' ErrorInfo, e.g. FAILED_PRECONDITION
+	Reason          string           `json:"reason,omitempty"`
+	FieldViolations []fieldViolation `json:"field_violations,omitempty"`
+}
+
+type fieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// statusFromError returns the status a call failed with. Errors from the client itself, rather
+// than the server, are reported as Internal so their details don't leak.
+func statusFromError(err error) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	return e.ToStatus(e.ApiError{Err: err})
+}
+
+func writeError(w http.ResponseWriter, st *status.Status) {
+	detail := errorDetail{
+		Code:    codeName(st.Code()),
+		Message: st.Message(),
+	}
+
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			detail.Reason = d.GetReason()
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				detail.FieldViolations = append(detail.FieldViolations, fieldViolation{Field: v.GetField(), Description: v.GetDescription()})
+			}
+		case *errdetails.RetryInfo:
+			// Retry-After only has whole seconds, rounded up so retrying on time succeeds
+			seconds := math.Ceil(d.GetRetryDelay().AsDuration().Seconds())
+			w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+		}
+	}
+
+	statusCode := httpStatus(st.Code())
+	if st == errMethodNotAllowed {
+		statusCode = http.StatusMethodNotAllowed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(errorBody{Error: detail})
+}
+
+// codeName returns the canonical name of code, e.g. INVALID_ARGUMENT for InvalidArgument
+func codeName(code codes.Code) string {
+	var b strings.Builder
+	for i, r := range code.String() {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	return b.String()
+}
+
+// httpStatus maps a gRPC status code onto the closest HTTP status
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// Nginx's non-standard "client closed request"
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		// Not 412, which is about conditional request headers
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"fmt"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// queryNames renames fields in query strings. Everything else uses the field's proto name.
+var queryNames = map[string]string{
+	"start_cursor": "cursor",
+}
+
+// fieldForQuery returns the field a query parameter sets
+func fieldForQuery(name string) string {
+	for field, queryName := range queryNames {
+		if queryName == name {
+			return field
+		}
+	}
+
+	return name
+}
+
+// queryName returns the query parameter a field is set by
+func queryName(field string) string {
+	if name, ok := queryNames[field]; ok {
+		return name
+	}
+
+	return field
+}
+
+// setField parses value into the named field of msg, appending to it if it's repeated
+func setField(msg proto.Message, name string, value string) error {
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		return fmt.Errorf("unknown field %q", name)
+	}
+	if fd.Message() != nil || fd.IsMap() {
+		return fmt.Errorf("%s can only be set in the body", name)
+	}
+
+	v, err := parseScalar(fd, value)
+	if err != nil {
+		return err
+	}
+
+	if fd.IsList() {
+		m.Mutable(fd).List().Append(v)
+	} else {
+		m.Set(fd, v)
+	}
+
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("%q is not a boolean", value)
+		}
+		return protoreflect.ValueOfBool(b), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("%q is not a 32-bit integer", value)
+		}
+		return protoreflect.ValueOfInt32(int32(n)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("%q is not a 64-bit integer", value)
+		}
+		return protoreflect.ValueOfInt64(n), nil
+	default:
+		return protoreflect.Value{}, fmt.Errorf("%s fields can't be set from a string", fd.Kind())
+	}
+}
@@ -0,0 +1,179 @@
+// Package gateway serves the gRPC services as REST/JSON for clients that can't speak gRPC. Each
+// route is forwarded to the gRPC server as a regular call, so authentication, authorization, and
+// error handling are exactly the same as for gRPC clients.
+package gateway
+
+import (
+	e "chariottakehome/api/errors"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Bodies larger than this are rejected, every request message is a handful of short fields
+const maxBodySize int64 = 1 << 20
+
+var (
+	marshalOptions = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	// Unknown fields are rejected so a typo doesn't silently drop a field
+	unmarshalOptions = protojson.UnmarshalOptions{}
+)
+
+type Gateway struct {
+	conn   grpc.ClientConnInterface
+	routes []route
+	spec   []byte
+}
+
+// New returns a gateway forwarding calls over conn, which should be connected to the server the
+// services are registered on
+func New(conn grpc.ClientConnInterface) (*Gateway, error) {
+	all := routes()
+
+	spec, err := json.MarshalIndent(openApi(all), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI spec: %w", err)
+	}
+
+	return &Gateway{conn: conn, routes: all, spec: spec}, nil
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path == "/openapi.json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(g.spec)
+		return
+	}
+
+	route, params, allowed := g.match(r.Method, r.URL.Path)
+	if route == nil {
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, errMethodNotAllowed)
+		} else {
+			writeError(w, errRouteNotFound)
+		}
+		return
+	}
+
+	req, err := route.bind(r, params)
+	if err != nil {
+		writeError(w, e.ToStatus(err))
+		return
+	}
+
+	ctx := outgoingContext(r)
+	resp := route.response.ProtoReflect().New().Interface()
+	var header metadata.MD
+	err = g.conn.Invoke(ctx, route.fullMethod, req, resp, grpc.Header(&header))
+	// The server's request ID is returned whether the call succeeded or not
+	if requestId := header.Get("x-request-id"); len(requestId) > 0 {
+		w.Header().Set("X-Request-Id", requestId[0])
+	}
+	if err != nil {
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	body, err := marshalOptions.Marshal(resp)
+	if err != nil {
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// match finds the route for method and path. When the path matches but the method doesn't, it
+// returns the methods that are allowed instead.
+func (g *Gateway) match(method string, path string) (*route, map[string]string, []string) {
+	segments := splitPath(path)
+
+	var allowed []string
+	for i := range g.routes {
+		params, ok := g.routes[i].matchPath(segments)
+		if !ok {
+			continue
+		}
+		if g.routes[i].method == method {
+			return &g.routes[i], params, nil
+		}
+		allowed = append(allowed, g.routes[i].method)
+	}
+
+	return nil, nil, allowed
+}
+
+// forwardedHeaders are passed on to the gRPC server as call metadata: the credentials, the
+// request ID, and the trace context so a caller's trace continues through the call
+var forwardedHeaders = []string{"Authorization", "X-Request-Id", "Traceparent", "Tracestate"}
+
+func outgoingContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	for _, header := range forwardedHeaders {
+		if value := r.Header.Get(header); value != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(header), value)
+		}
+	}
+
+	// Every call reaches the gRPC server from here, so it's told who the client is to rate limit
+	// public methods and failed authentications by. Set rather than forwarded, so clients can't
+	// choose it.
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-forwarded-for", host)
+	}
+
+	return ctx
+}
+
+// bind builds the route's request message from the body, then the query string, then the path
+func (rt *route) bind(r *http.Request, params map[string]string) (proto.Message, error) {
+	req := rt.request.ProtoReflect().New().Interface()
+
+	if rt.hasBody() {
+		body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+		if err != nil {
+			return nil, e.FieldError("body", err)
+		}
+		if len(strings.TrimSpace(string(body))) > 0 {
+			if err := unmarshalOptions.Unmarshal(body, req); err != nil {
+				return nil, e.FieldError("body", errors.New(strings.TrimPrefix(err.Error(), "proto: ")))
+			}
+		}
+	}
+
+	for name, values := range r.URL.Query() {
+		field := fieldForQuery(name)
+		if rt.isPathParam(field) {
+			return nil, e.FieldError(name, errors.New("already set by the path"))
+		}
+		for _, value := range values {
+			if err := setField(req, field, value); err != nil {
+				return nil, e.FieldError(name, err)
+			}
+		}
+	}
+
+	for name, value := range params {
+		if err := setField(req, name, value); err != nil {
+			return nil, e.FieldError(name, err)
+		}
+	}
+
+	return req, nil
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
@@ -0,0 +1,227 @@
+package gateway
+
+import (
+	e "chariottakehome/api/errors"
+	accountspb "chariottakehome/api/services/accounts"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// fakeAccountService records the last request it was sent
+type fakeAccountService struct {
+	accountspb.UnimplementedAccountServiceServer
+	authorization []string
+	forwardedFor  []string
+	deposit       *accountspb.DepositFundsRequest
+	list          *accountspb.ListTransactionsRequest
+}
+
+func (s *fakeAccountService) DepositFunds(ctx context.Context, req *accountspb.DepositFundsRequest) (*accountspb.Transaction, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.authorization = md.Get("authorization")
+	s.forwardedFor = md.Get("x-forwarded-for")
+	s.deposit = req
+
+	if req.GetAmountMinor() <= 0 {
+		return nil, e.ToStatus(e.FieldError("amount_minor", errors.New("amount must be greater than zero"))).Err()
+	}
+
+	return &accountspb.Transaction{AccountId: req.GetAccountId(), AmountMinor: req.GetAmountMinor()}, nil
+}
+
+func (s *fakeAccountService) WithdrawFunds(ctx context.Context, req *accountspb.WithdrawFundsRequest) (*accountspb.Transaction, error) {
+	return nil, e.ToStatus(e.RateLimitError{Err: errors.New("too many calls"), RetryAfter: 1200 * time.Millisecond}).Err()
+}
+
+func (s *fakeAccountService) ListTransactions(ctx context.Context, req *accountspb.ListTransactionsRequest) (*accountspb.ListTransactionsResponse, error) {
+	s.list = req
+	return &accountspb.ListTransactionsResponse{}, nil
+}
+
+func newTestGateway(t *testing.T) (*Gateway, *fakeAccountService) {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	service := &fakeAccountService{}
+	accountspb.RegisterAccountServiceServer(server, service)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	gateway, err := New(conn)
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %s", err)
+	}
+
+	return gateway, service
+}
+
+func serve(gateway *Gateway, method string, target string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	req.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	gateway.ServeHTTP(w, req)
+
+	return w
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorDetail {
+	var body errorBody
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode error body: %s", err)
+	}
+
+	return body.Error
+}
+
+func TestForwardsCall(t *testing.T) {
+	gateway, service := newTestGateway(t)
+
+	w := serve(gateway, http.MethodPost, "/v1/accounts/acct123/deposits", `{"amount_minor": "500", "description": "pay"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	if service.deposit.GetAccountId() != "acct123" || service.deposit.GetAmountMinor() != 500 || service.deposit.GetDescription() != "pay" {
+		t.Fatalf("Request wasn't bound from the path and body: %v", service.deposit)
+	}
+	if len(service.authorization) != 1 || service.authorization[0] != "Bearer secret" {
+		t.Fatalf("Expected credentials to be forwarded, got %v", service.authorization)
+	}
+	if len(service.forwardedFor) != 1 || service.forwardedFor[0] != "192.0.2.1" {
+		t.Fatalf("Expected the client's address to be passed on, got %v", service.forwardedFor)
+	}
+
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %s", err)
+	}
+	if resp["account_id"] != "acct123" || resp["amount_minor"] != "500" {
+		t.Fatalf("Unexpected response %v", resp)
+	}
+}
+
+func TestBindsQuery(t *testing.T) {
+	gateway, service := newTestGateway(t)
+
+	w := serve(gateway, http.MethodGet, "/v1/accounts/acct123/transactions?cursor=abc&page_size=5&min_amount=10", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	if service.list.GetStartCursor() != "abc" || service.list.GetPageSize() != 5 || service.list.GetMinAmount() != 10 || service.list.MaxAmount != nil {
+		t.Fatalf("Request wasn't bound from the query: %v", service.list)
+	}
+
+	w = serve(gateway, http.MethodGet, "/v1/accounts/acct123/transactions?page_size=many", "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", w.Code)
+	}
+	if detail := decodeError(t, w); len(detail.FieldViolations) != 1 || detail.FieldViolations[0].Field != "page_size" {
+		t.Fatalf("Expected a page_size violation, got %+v", detail)
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	gateway, _ := newTestGateway(t)
+
+	w := serve(gateway, http.MethodPost, "/v1/accounts/acct123/deposits", `{}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", w.Code)
+	}
+	detail := decodeError(t, w)
+	if detail.Code != "INVALID_ARGUMENT" || detail.Reason != "INVALID_REQUEST" || len(detail.FieldViolations) != 1 || detail.FieldViolations[0].Field != "amount_minor" {
+		t.Fatalf("Unexpected error body %+v", detail)
+	}
+
+	w = serve(gateway, http.MethodPost, "/v1/accounts/acct123/deposits", `{"amount_minr": "5"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected unknown fields to be rejected, got %d", w.Code)
+	}
+
+	w = serve(gateway, http.MethodGet, "/v1/accounts/acct123/balance", "")
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("Expected 501 from an unimplemented method, got %d", w.Code)
+	}
+
+	w = serve(gateway, http.MethodPost, "/v1/accounts/acct123/withdrawals", `{"amount_minor": "5"}`)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" || decodeError(t, w).Reason != "RATE_LIMITED" {
+		t.Fatalf("Expected 429 retrying after 2s, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	w = serve(gateway, http.MethodGet, "/v1/nothing", "")
+	if w.Code != http.StatusNotFound || decodeError(t, w).Code != "NOT_FOUND" {
+		t.Fatalf("Expected 404, got %d", w.Code)
+	}
+
+	w = serve(gateway, http.MethodDelete, "/v1/accounts/acct123/deposits", "")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Fatalf("Expected 405 allowing POST, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestRoutesBindToRequestFields(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, rt := range routes() {
+		key := rt.method + " " + rt.pattern
+		if seen[key] {
+			t.Fatalf("Duplicate route %s", key)
+		}
+		seen[key] = true
+
+		fields := rt.request.ProtoReflect().Descriptor().Fields()
+		for _, param := range rt.pathParams() {
+			if fields.ByName(protoreflect.Name(param)) == nil {
+				t.Fatalf("%s binds {%s}, which isn't a field of %s", key, param, rt.request.ProtoReflect().Descriptor().Name())
+			}
+		}
+	}
+}
+
+func TestServesSpec(t *testing.T) {
+	gateway, _ := newTestGateway(t)
+
+	w := serve(gateway, http.MethodGet, "/openapi.json", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+
+	var doc openApiDoc
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode spec: %s", err)
+	}
+
+	deposit := doc.Paths["/v1/accounts/{account_id}/deposits"]["post"]
+	if deposit == nil || deposit.OperationId != "DepositFunds" || deposit.RequestBody == nil {
+		t.Fatalf("Expected the deposit route in the spec, got %+v", deposit)
+	}
+	if _, ok := doc.Components.Schemas["DepositFundsRequest"]; !ok {
+		t.Fatalf("Expected the request schema to be included")
+	}
+	if len(doc.Security) != 1 || doc.Security[0]["bearer"] == nil {
+		t.Fatalf("Expected every operation to require a bearer token, got %+v", doc.Security)
+	}
+}
@@ -0,0 +1,220 @@
+package gateway
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The spec is built from the routes and the request and response messages' descriptors, so it
+// can't drift from what the gateway actually accepts
+
+type openApiDoc struct {
+	OpenApi    string                           `json:"openapi"`
+	Info       openApiInfo                      `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+	Security   []map[string][]string            `json:"security"`
+}
+
+type openApiInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type operation struct {
+	OperationId string              `json:"operationId"`
+	Tags        []string            `json:"tags"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Items      *schema            `json:"items,omitempty"`
+	Properties map[string]*schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Deprecated bool               `json:"deprecated,omitempty"`
+}
+
+type components struct {
+	Schemas         map[string]*schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+func openApi(routes []route) openApiDoc {
+	doc := openApiDoc{
+		OpenApi: "3.0.3",
+		Info:    openApiInfo{Title: "Chariot Take Home API", Version: "v1"},
+		Paths:   make(map[string]map[string]*operation),
+		Components: components{
+			Schemas: map[string]*schema{"Error": errorSchema()},
+			SecuritySchemes: map[string]securityScheme{
+				"bearer": {Type: "http", Scheme: "bearer"},
+			},
+		},
+		Security: []map[string][]string{{"bearer": {}}},
+	}
+
+	for i := range routes {
+		rt := &routes[i]
+		if doc.Paths[rt.pattern] == nil {
+			doc.Paths[rt.pattern] = make(map[string]*operation)
+		}
+		doc.Paths[rt.pattern][strings.ToLower(rt.method)] = rt.operation(doc.Components.Schemas)
+	}
+
+	return doc
+}
+
+func (rt *route) operation(schemas map[string]*schema) *operation {
+	op := &operation{
+		OperationId: rt.operationName(),
+		Tags:        []string{rt.serviceName()},
+		Responses: map[string]response{
+			"200": {
+				Description: "OK",
+				Content:     jsonContent(messageRef(rt.response.ProtoReflect().Descriptor(), schemas)),
+			},
+			"default": {
+				Description: "Error",
+				Content:     jsonContent(&schema{Ref: "#/components/schemas/Error"}),
+			},
+		},
+	}
+
+	request := rt.request.ProtoReflect().Descriptor()
+	for _, name := range rt.pathParams() {
+		fd := request.Fields().ByName(protoreflect.Name(name))
+		op.Parameters = append(op.Parameters, parameter{Name: name, In: "path", Required: true, Schema: fieldSchema(fd, schemas)})
+	}
+
+	if rt.hasBody() {
+		op.RequestBody = &requestBody{Required: true, Content: jsonContent(messageRef(request, schemas))}
+		return op
+	}
+
+	fields := request.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if rt.isPathParam(string(fd.Name())) {
+			continue
+		}
+		op.Parameters = append(op.Parameters, parameter{Name: queryName(string(fd.Name())), In: "query", Schema: fieldSchema(fd, schemas)})
+	}
+
+	return op
+}
+
+// messageRef returns a reference to md's schema, adding it and any messages it uses to schemas
+func messageRef(md protoreflect.MessageDescriptor, schemas map[string]*schema) *schema {
+	name := string(md.Name())
+	ref := &schema{Ref: "#/components/schemas/" + name}
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+	// Added before its fields so a recursive message doesn't loop
+	schemas[name] = s
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		s.Properties[string(fd.Name())] = fieldSchema(fd, schemas)
+	}
+
+	return ref
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor, schemas map[string]*schema) *schema {
+	var s *schema
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		s = messageRef(fd.Message(), schemas)
+	case protoreflect.BoolKind:
+		s = &schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		s = &schema{Type: "integer", Format: "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// 64-bit integers are strings in proto JSON, since they don't fit in a JavaScript number
+		s = &schema{Type: "string", Format: "int64"}
+	default:
+		s = &schema{Type: "string"}
+	}
+
+	if fd.IsList() {
+		s = &schema{Type: "array", Items: s}
+	}
+
+	if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok && opts.GetDeprecated() {
+		if s.Ref != "" {
+			// Siblings of $ref are ignored in OpenAPI 3.0
+			return s
+		}
+		s.Deprecated = true
+	}
+
+	return s
+}
+
+func errorSchema() *schema {
+	violation := &schema{
+		Type: "object",
+		Properties: map[string]*schema{
+			"field":       {Type: "string"},
+			"description": {Type: "string"},
+		},
+	}
+
+	return &schema{
+		Type:     "object",
+		Required: []string{"error"},
+		Properties: map[string]*schema{
+			"error": {
+				Type:     "object",
+				Required: []string{"code", "message"},
+				Properties: map[string]*schema{
+					"code":             {Type: "string"},
+					"message":          {Type: "string"},
+					"reason":           {Type: "string"},
+					"field_violations": {Type: "array", Items: violation},
+				},
+			},
+		},
+	}
+}
+
+func jsonContent(s *schema) map[string]mediaType {
+	return map[string]mediaType{"application/json": {Schema: s}}
+}
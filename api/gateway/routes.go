@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	accountspb "chariottakehome/api/services/accounts"
+	userspb "chariottakehome/api/services/users"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type route struct {
+	method string
+	// pattern is a path whose {field} segments are bound to request fields
+	pattern    string
+	segments   []string
+	fullMethod string
+	request    proto.Message
+	response   proto.Message
+}
+
+func newRoute(method string, pattern string, fullMethod string, request proto.Message, response proto.Message) route {
+	return route{
+		method:     method,
+		pattern:    pattern,
+		segments:   splitPath(pattern),
+		fullMethod: fullMethod,
+		request:    request,
+		response:   response,
+	}
+}
+
+// routes lists every REST route. WatchAccountEvents streams, so it's only available over gRPC.
+func routes() []route {
+	return []route{
+		// AccountService
+		newRoute(http.MethodPost, "/v1/accounts", accountspb.AccountService_CreateAccount_FullMethodName, &accountspb.CreateAccountRequest{}, &accountspb.Account{}),
+		newRoute(http.MethodGet, "/v1/accounts", accountspb.AccountService_ListAccounts_FullMethodName, &accountspb.ListAccountsRequest{}, &accountspb.ListAccountsResponse{}),
+		newRoute(http.MethodGet, "/v1/accounts/{account_id}", accountspb.AccountService_GetAccount_FullMethodName, &accountspb.GetAccountRequest{}, &accountspb.Account{}),
+		newRoute(http.MethodPatch, "/v1/accounts/{account_id}", accountspb.AccountService_UpdateAccount_FullMethodName, &accountspb.UpdateAccountRequest{}, &accountspb.Account{}),
+		newRoute(http.MethodGet, "/v1/accounts/{account_id}/balance", accountspb.AccountService_GetBalance_FullMethodName, &accountspb.GetBalanceRequest{}, &accountspb.GetBalanceResponse{}),
+		newRoute(http.MethodPost, "/v1/accounts/{account_id}/deposits", accountspb.AccountService_DepositFunds_FullMethodName, &accountspb.DepositFundsRequest{}, &accountspb.Transaction{}),
+		newRoute(http.MethodPost, "/v1/accounts/{account_id}/withdrawals", accountspb.AccountService_WithdrawFunds_FullMethodName, &accountspb.WithdrawFundsRequest{}, &accountspb.Transaction{}),
+		newRoute(http.MethodPost, "/v1/accounts/{account_id}/authorizations", accountspb.AccountService_AuthorizeWithdrawal_FullMethodName, &accountspb.AuthorizeWithdrawalRequest{}, &accountspb.Transaction{}),
+		newRoute(http.MethodPost, "/v1/accounts/{source_account_id}/transfers", accountspb.AccountService_AccountTransfer_FullMethodName, &accountspb.AccountTransferRequest{}, &accountspb.AccountTransferResponse{}),
+		newRoute(http.MethodGet, "/v1/accounts/{account_id}/transactions", accountspb.AccountService_ListTransactions_FullMethodName, &accountspb.ListTransactionsRequest{}, &accountspb.ListTransactionsResponse{}),
+		newRoute(http.MethodGet, "/v1/accounts/{account_id}/members", accountspb.AccountService_ListAccountMembers_FullMethodName, &accountspb.ListAccountMembersRequest{}, &accountspb.ListAccountMembersResponse{}),
+		newRoute(http.MethodPut, "/v1/accounts/{account_id}/members/{user_id}", accountspb.AccountService_AddAccountMember_FullMethodName, &accountspb.AddAccountMemberRequest{}, &accountspb.AccountMember{}),
+		newRoute(http.MethodDelete, "/v1/accounts/{account_id}/members/{user_id}", accountspb.AccountService_RemoveAccountMember_FullMethodName, &accountspb.RemoveAccountMemberRequest{}, &accountspb.AccountMember{}),
+		newRoute(http.MethodGet, "/v1/accounts/{account_id}/limits", accountspb.AccountService_GetAccountLimits_FullMethodName, &accountspb.GetAccountLimitsRequest{}, &accountspb.AccountLimits{}),
+		newRoute(http.MethodPut, "/v1/accounts/{account_id}/limits", accountspb.AccountService_SetAccountLimits_FullMethodName, &accountspb.SetAccountLimitsRequest{}, &accountspb.AccountLimits{}),
+		newRoute(http.MethodGet, "/v1/accounts/{account_id}/limits/usage", accountspb.AccountService_GetAccountLimitUsage_FullMethodName, &accountspb.GetAccountLimitUsageRequest{}, &accountspb.GetAccountLimitUsageResponse{}),
+		newRoute(http.MethodGet, "/v1/limit-tiers/{name}", accountspb.AccountService_GetLimitTier_FullMethodName, &accountspb.GetLimitTierRequest{}, &accountspb.LimitTier{}),
+		newRoute(http.MethodPut, "/v1/limit-tiers/{name}", accountspb.AccountService_SetLimitTier_FullMethodName, &accountspb.SetLimitTierRequest{}, &accountspb.LimitTier{}),
+		newRoute(http.MethodPost, "/v1/transactions/{transaction_id}/capture", accountspb.AccountService_CaptureTransaction_FullMethodName, &accountspb.CaptureTransactionRequest{}, &accountspb.Transaction{}),
+		newRoute(http.MethodPost, "/v1/transactions/{transaction_id}/void", accountspb.AccountService_VoidTransaction_FullMethodName, &accountspb.VoidTransactionRequest{}, &accountspb.Transaction{}),
+		newRoute(http.MethodPost, "/v1/transactions/{transaction_id}/reversals", accountspb.AccountService_ReverseTransaction_FullMethodName, &accountspb.ReverseTransactionRequest{}, &accountspb.JournalEntry{}),
+		newRoute(http.MethodGet, "/v1/journal-entries/{journal_entry_id}", accountspb.AccountService_GetJournalEntry_FullMethodName, &accountspb.GetJournalEntryRequest{}, &accountspb.JournalEntry{}),
+
+		// UserService
+		newRoute(http.MethodPost, "/v1/users", userspb.UserService_CreateUser_FullMethodName, &userspb.CreateUserRequest{}, &userspb.User{}),
+		newRoute(http.MethodGet, "/v1/users", userspb.UserService_ListUsers_FullMethodName, &userspb.ListUsersRequest{}, &userspb.ListUsersResponse{}),
+		// Listed before /v1/users/{user_id}, which would otherwise match it
+		newRoute(http.MethodGet, "/v1/users/by-email", userspb.UserService_GetUserByEmail_FullMethodName, &userspb.GetUserByEmailRequest{}, &userspb.User{}),
+		newRoute(http.MethodGet, "/v1/users/{user_id}", userspb.UserService_GetUser_FullMethodName, &userspb.GetUserRequest{}, &userspb.User{}),
+		newRoute(http.MethodPost, "/v1/users/{user_id}/webhook-endpoints", userspb.UserService_CreateWebhookEndpoint_FullMethodName, &userspb.CreateWebhookEndpointRequest{}, &userspb.WebhookEndpoint{}),
+		newRoute(http.MethodGet, "/v1/users/{user_id}/webhook-endpoints", userspb.UserService_ListWebhookEndpoints_FullMethodName, &userspb.ListWebhookEndpointsRequest{}, &userspb.ListWebhookEndpointsResponse{}),
+		newRoute(http.MethodDelete, "/v1/webhook-endpoints/{endpoint_id}", userspb.UserService_DeleteWebhookEndpoint_FullMethodName, &userspb.DeleteWebhookEndpointRequest{}, &userspb.WebhookEndpoint{}),
+		newRoute(http.MethodGet, "/v1/webhook-endpoints/{endpoint_id}/deliveries", userspb.UserService_ListWebhookDeliveries_FullMethodName, &userspb.ListWebhookDeliveriesRequest{}, &userspb.ListWebhookDeliveriesResponse{}),
+		newRoute(http.MethodPost, "/v1/webhook-deliveries/{delivery_id}/replay", userspb.UserService_ReplayWebhookDelivery_FullMethodName, &userspb.ReplayWebhookDeliveryRequest{}, &userspb.WebhookDelivery{}),
+		newRoute(http.MethodPost, "/v1/users/{user_id}/api-keys", userspb.UserService_CreateApiKey_FullMethodName, &userspb.CreateApiKeyRequest{}, &userspb.ApiKey{}),
+		newRoute(http.MethodGet, "/v1/users/{user_id}/api-keys", userspb.UserService_ListApiKeys_FullMethodName, &userspb.ListApiKeysRequest{}, &userspb.ListApiKeysResponse{}),
+		newRoute(http.MethodDelete, "/v1/api-keys/{api_key_id}", userspb.UserService_RevokeApiKey_FullMethodName, &userspb.RevokeApiKeyRequest{}, &userspb.ApiKey{}),
+	}
+}
+
+// hasBody reports whether the request message is read from the body rather than the query string
+func (rt *route) hasBody() bool {
+	return rt.method == http.MethodPost || rt.method == http.MethodPut || rt.method == http.MethodPatch
+}
+
+// pathParams returns the names of the fields bound from the path, in order
+func (rt *route) pathParams() []string {
+	var params []string
+	for _, segment := range rt.segments {
+		if name, ok := paramName(segment); ok {
+			params = append(params, name)
+		}
+	}
+
+	return params
+}
+
+func (rt *route) isPathParam(field string) bool {
+	for _, param := range rt.pathParams() {
+		if param == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (rt *route) matchPath(segments []string) (map[string]string, bool) {
+	if len(segments) != len(rt.segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range rt.segments {
+		if name, ok := paramName(segment); ok {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[name] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func paramName(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+
+	return "", false
+}
+
+// operationName is the RPC's method name, e.g. DepositFunds
+func (rt *route) operationName() string {
+	return rt.fullMethod[strings.LastIndex(rt.fullMethod, "/")+1:]
+}
+
+// serviceName is the RPC's unqualified service name, e.g. AccountService
+func (rt *route) serviceName() string {
+	service := strings.TrimPrefix(rt.fullMethod[:strings.LastIndex(rt.fullMethod, "/")], "/")
+	return service[strings.LastIndex(service, ".")+1:]
+}
@@ -3,76 +3,232 @@ package accountservice
 import (
 	e "chariottakehome/api/errors"
 	"chariottakehome/internal/accounts"
+	"chariottakehome/internal/auth"
+	"chariottakehome/internal/cursor"
+	"chariottakehome/internal/events"
 	id "chariottakehome/internal/identifier"
+	"chariottakehome/internal/money"
 	"context"
+	"errors"
+	"math"
 	"time"
 )
 
 type AccountService struct {
 	UnimplementedAccountServiceServer
 	Repo accounts.AccountRepository
+
+	// Events and EventBroker serve WatchAccountEvents
+	Events      events.EventRepository
+	EventBroker *events.Broker
+
+	// Cursors signs the pagination cursors handed to clients
+	Cursors *cursor.Signer
+
+	// MaxTransactionAmount caps a single money movement, DefaultMaxTransactionAmount if unset
+	MaxTransactionAmount int64
+
+	// HoldTTL is how long an authorization hold lasts before it lapses, DefaultHoldTTL if unset
+	HoldTTL time.Duration
 }
 
+const (
+	DefaultHoldTTL time.Duration = 7 * 24 * time.Hour
+
+	watchBatchSize    int           = 100
+	watchPollInterval time.Duration = 5 * time.Second
+)
+
 func (s *AccountService) CreateAccount(ctx context.Context, req *CreateAccountRequest) (*Account, error) {
 	userId, err := id.FromString(req.GetUserId())
 	if err != nil {
-		return nil, e.RequestError{Err: err}
+		return nil, e.FieldError("user_id", err)
+	}
+
+	overdraftLimit := requestAmount(req.GetOverdraftLimitMinor(), req.GetOverdraftLimit())
+	if overdraftLimit < 0 {
+		return nil, e.FieldError(overdraftLimitField(req.GetOverdraftLimitMinor()), errors.New("overdraft limit cannot be negative"))
+	}
+
+	// Accounts are held in USD unless the client asks otherwise
+	currency := money.USD
+	if req.GetCurrency() != "" {
+		currency, err = money.ParseCurrency(req.GetCurrency())
+		if err != nil {
+			return nil, e.FieldError("currency", err)
+		}
+	}
+
+	if err := authorizeUser(ctx, userId, auth.Write); err != nil {
+		return nil, err
+	}
+	if overdraftLimit != 0 {
+		if err := authorizeOperator(ctx); err != nil {
+			return nil, err
+		}
 	}
 
-	account, err := s.Repo.CreateAccount(ctx, userId, req.Name)
+	account, err := s.Repo.CreateAccount(ctx, userId, req.Name, currency, overdraftLimit)
 	if err != nil {
-		return nil, e.ApiError{Err: e.Internal}
+		return nil, e.ApiError{Err: err}
 	}
 
 	return toProtoAccount(account), nil
 }
 
-func (s *AccountService) DepositFunds(ctx context.Context, req *DepositFundsRequest) (*Transaction, error) {
+func (s *AccountService) UpdateAccount(ctx context.Context, req *UpdateAccountRequest) (*Account, error) {
 	accountId, err := id.FromString(req.GetAccountId())
 	if err != nil {
-		return nil, e.RequestError{Err: err}
+		return nil, e.FieldError("account_id", err)
 	}
-	amount := req.GetAmount()
-	description := req.GetDescription()
 
-	transaction, err := s.Repo.DepositFunds(ctx, accountId, int(amount), description)
+	// The int64 field wins when both are set, even at zero, since it's only set on purpose
+	var overdraftLimit *int64
+	field := "overdraft_limit"
+	switch {
+	case req.OverdraftLimitMinor != nil:
+		limit := req.GetOverdraftLimitMinor()
+		overdraftLimit, field = &limit, "overdraft_limit_minor"
+	case req.OverdraftLimit != nil:
+		limit := int64(req.GetOverdraftLimit())
+		overdraftLimit = &limit
+	}
+	if overdraftLimit != nil && *overdraftLimit < 0 {
+		return nil, e.FieldError(field, errors.New("overdraft limit cannot be negative"))
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.Admin); err != nil {
+		return nil, err
+	}
+	if overdraftLimit != nil {
+		if err := authorizeOperator(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	account, err := s.Repo.UpdateAccount(ctx, accountId, req.Name, overdraftLimit)
 	if err != nil {
-		return nil, e.ApiError{Err: e.Internal}
+		return nil, e.ApiError{Err: err}
 	}
 
-	return toProtoTransaction(transaction), nil
+	return toProtoAccount(account), nil
 }
 
-func (s *AccountService) WithdrawFunds(ctx context.Context, req *WithdrawFundsRequest) (*Transaction, error) {
+func (s *AccountService) GetAccount(ctx context.Context, req *GetAccountRequest) (*Account, error) {
 	accountId, err := id.FromString(req.GetAccountId())
 	if err != nil {
-		return nil, e.RequestError{Err: err}
+		return nil, e.FieldError("account_id", err)
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.View); err != nil {
+		return nil, err
+	}
+
+	account, err := s.Repo.GetAccount(ctx, accountId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	return toProtoAccount(account), nil
+}
+
+func (s *AccountService) ListAccounts(ctx context.Context, req *ListAccountsRequest) (*ListAccountsResponse, error) {
+	userId, err := id.FromString(req.GetUserId())
+	if err != nil {
+		return nil, e.FieldError("user_id", err)
+	}
+
+	if err := authorizeUser(ctx, userId, auth.Read); err != nil {
+		return nil, err
+	}
+
+	var startCursor *id.Identifier
+	startCursorStr := req.GetStartCursor()
+	if startCursorStr != "" {
+		id, err := id.FromString(startCursorStr)
+		if err != nil {
+			return nil, e.FieldError("start_cursor", err)
+		}
+
+		startCursor = &id
+	}
+
+	pageSize, err := parsePageSize(req.GetPageSize())
+	if err != nil {
+		return nil, e.FieldError("page_size", err)
 	}
-	amount := req.GetAmount()
-	description := req.GetDescription()
 
-	transaction, err := s.Repo.WithdrawFunds(ctx, accountId, int(amount), description)
+	resp, err := s.Repo.ListAccounts(ctx, userId, startCursor, pageSize)
 	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	protoAccounts := make([]*Account, 0)
+	for i := 0; i < len(resp.Accounts); i++ {
+		protoAccounts = append(protoAccounts, toProtoAccount(&resp.Accounts[i]))
+	}
+
+	nextCursor := ""
+	if resp.NextCursor != nil {
+		nextCursor = resp.NextCursor.String()
+	}
+
+	return &ListAccountsResponse{
+		Accounts:   protoAccounts,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+func (s *AccountService) DepositFunds(ctx context.Context, req *DepositFundsRequest) (*Transaction, error) {
+	accountId, amount, err := s.validateDeposit(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.Deposit); err != nil {
 		return nil, err
 	}
 
+	transaction, err := s.Repo.DepositFunds(ctx, accountId, amount, req.GetDescription(), req.GetIdempotencyKey())
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
 	return toProtoTransaction(transaction), nil
 }
 
-func (s *AccountService) AccountTransfer(ctx context.Context, req *AccountTransferRequest) (*AccountTransferResponse, error) {
-	sourceAccountId, err := id.FromString(req.GetSourceAccountId())
+func (s *AccountService) WithdrawFunds(ctx context.Context, req *WithdrawFundsRequest) (*Transaction, error) {
+	accountId, amount, err := s.validateWithdrawal(req)
 	if err != nil {
-		return nil, e.RequestError{Err: err}
+		return nil, err
 	}
 
-	destAccountId, err := id.FromString(req.GetDestinationAccountId())
+	if err := s.authorizeAccount(ctx, accountId, accounts.Withdraw); err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.Repo.WithdrawFunds(ctx, accountId, amount, req.GetDescription(), req.GetIdempotencyKey())
 	if err != nil {
-		return nil, e.RequestError{Err: err}
+		return nil, toServiceError(err)
 	}
 
-	resp, err := s.Repo.AccountTransfer(ctx, sourceAccountId, destAccountId, int(req.GetAmount()), req.GetDescription())
+	return toProtoTransaction(transaction), nil
+}
+
+func (s *AccountService) AccountTransfer(ctx context.Context, req *AccountTransferRequest) (*AccountTransferResponse, error) {
+	sourceAccountId, destAccountId, amount, err := s.validateTransfer(req)
 	if err != nil {
-		return nil, e.ApiError{Err: e.Internal}
+		return nil, err
+	}
+
+	// Money can be sent to anyone's account, but only taken from the caller's own
+	if err := s.authorizeAccount(ctx, sourceAccountId, accounts.Transfer); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Repo.AccountTransfer(ctx, sourceAccountId, destAccountId, amount, req.GetDescription(), req.GetIdempotencyKey())
+	if err != nil {
+		return nil, toServiceError(err)
 	}
 
 	return &AccountTransferResponse{
@@ -82,30 +238,18 @@ func (s *AccountService) AccountTransfer(ctx context.Context, req *AccountTransf
 }
 
 func (s *AccountService) ListTransactions(ctx context.Context, req *ListTransactionsRequest) (*ListTransactionsResponse, error) {
-	accountId, err := id.FromString(req.GetAccountId())
+	accountId, page, filter, err := s.validateListTransactions(req)
 	if err != nil {
-		return nil, e.RequestError{Err: err}
-	}
-
-	var startCursor *id.Identifier
-	startCursorStr := req.GetStartCursor()
-	if startCursorStr != "" {
-		id, err := id.FromString(startCursorStr)
-		if err != nil {
-			return nil, e.RequestError{Err: err}
-		}
-
-		startCursor = &id
+		return nil, err
 	}
 
-	pageSize := 15
-	if req.GetPageSize() > 0 {
-		pageSize = int(req.GetPageSize())
+	if err := s.authorizeAccount(ctx, accountId, accounts.View); err != nil {
+		return nil, err
 	}
 
-	resp, err := s.Repo.ListTransactions(ctx, accountId, startCursor, pageSize)
+	resp, err := s.Repo.ListTransactions(ctx, accountId, page, filter)
 	if err != nil {
-		return nil, e.ApiError{Err: e.Internal}
+		return nil, e.ApiError{Err: err}
 	}
 
 	protoTransactions := make([]*Transaction, 0)
@@ -113,50 +257,282 @@ func (s *AccountService) ListTransactions(ctx context.Context, req *ListTransact
 		protoTransactions = append(protoTransactions, toProtoTransaction(&resp.Transactions[i]))
 	}
 
-	nextCursor := ""
-	if resp.NextCursor != nil {
-		nextCursor = resp.NextCursor.String()
+	// A page read forwards came from an earlier page if it had a cursor, and one read backwards
+	// always came from a later page
+	var nextCursor, prevCursor string
+	if n := len(resp.Transactions); n > 0 {
+		scope := transactionsScope(accountId, filter)
+		first, last := resp.Transactions[0].Id, resp.Transactions[n-1].Id
+
+		if page.Direction == accounts.Backward || resp.HasMore {
+			nextCursor = s.Cursors.Encode(cursor.Cursor{Position: last, Direction: cursor.Forward}, scope)
+		}
+		if (page.Direction == accounts.Forward && page.Cursor != nil) || (page.Direction == accounts.Backward && resp.HasMore) {
+			prevCursor = s.Cursors.Encode(cursor.Cursor{Position: first, Direction: cursor.Backward}, scope)
+		}
 	}
 
 	return &ListTransactionsResponse{
 		Transactions: protoTransactions,
 		NextCursor:   nextCursor,
+		PrevCursor:   prevCursor,
+		HasMore:      resp.HasMore,
 	}, nil
 }
 
 func (s *AccountService) GetBalance(ctx context.Context, req *GetBalanceRequest) (*GetBalanceResponse, error) {
 	accountId, err := id.FromString(req.GetAccountId())
 	if err != nil {
-		return nil, e.RequestError{Err: err}
+		return nil, e.FieldError("account_id", err)
 	}
 
-	reqTimestamp := req.GetTimestamp()
-	timestamp := time.Now().UTC()
-	if reqTimestamp != "" {
-		timestamp, err = time.Parse(time.DateTime, reqTimestamp)
+	// No timestamp means the current balance
+	var timestamp *time.Time
+	if req.GetTimestamp() != "" {
+		t, err := time.Parse(time.DateTime, req.GetTimestamp())
 		if err != nil {
-			return nil, e.RequestError{Err: err}
+			return nil, e.FieldError("timestamp", err)
 		}
+		timestamp = &t
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.View); err != nil {
+		return nil, err
 	}
 
-	amount, err := s.Repo.GetBalance(ctx, accountId, timestamp)
+	balance, err := s.Repo.GetBalance(ctx, accountId, timestamp)
 	if err != nil {
-		return nil, e.ApiError{Err: e.Internal}
+		return nil, e.ApiError{Err: err}
 	}
 
 	return &GetBalanceResponse{
-		Amount: int32(amount),
+		Amount:      legacyInt32(balance.Amount),
+		AmountMinor: balance.Amount,
+		Currency:    balance.Currency.Code(),
 	}, nil
 }
 
+func (s *AccountService) GetJournalEntry(ctx context.Context, req *GetJournalEntryRequest) (*JournalEntry, error) {
+	journalEntryId, err := id.FromString(req.GetJournalEntryId())
+	if err != nil {
+		return nil, e.FieldError("journal_entry_id", err)
+	}
+
+	entry, err := s.Repo.GetJournalEntry(ctx, journalEntryId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	if err := s.authorizeJournalEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return toProtoJournalEntry(entry), nil
+}
+
+func (s *AccountService) AuthorizeWithdrawal(ctx context.Context, req *AuthorizeWithdrawalRequest) (*Transaction, error) {
+	accountId, err := s.validateAuthorization(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.Withdraw); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().UTC().Add(s.holdTTL())
+	transaction, err := s.Repo.AuthorizeWithdrawal(ctx, accountId, req.GetAmount(), req.GetDescription(), req.GetIdempotencyKey(), expiresAt)
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
+	return toProtoTransaction(transaction), nil
+}
+
+func (s *AccountService) CaptureTransaction(ctx context.Context, req *CaptureTransactionRequest) (*Transaction, error) {
+	transactionId, err := validateCapture(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeTransaction(ctx, transactionId, accounts.Withdraw); err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.Repo.CaptureTransaction(ctx, transactionId, req.Amount)
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
+	return toProtoTransaction(transaction), nil
+}
+
+func (s *AccountService) VoidTransaction(ctx context.Context, req *VoidTransactionRequest) (*Transaction, error) {
+	transactionId, err := id.FromString(req.GetTransactionId())
+	if err != nil {
+		return nil, e.FieldError("transaction_id", err)
+	}
+
+	if err := s.authorizeTransaction(ctx, transactionId, accounts.Withdraw); err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.Repo.VoidTransaction(ctx, transactionId)
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
+	return toProtoTransaction(transaction), nil
+}
+
+func (s *AccountService) ReverseTransaction(ctx context.Context, req *ReverseTransactionRequest) (*JournalEntry, error) {
+	transactionId, err := validateReversal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reversing a transfer takes money back out of the other side's account
+	if err := authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.Repo.ReverseTransaction(ctx, transactionId, req.Amount, req.GetDescription(), req.GetIdempotencyKey())
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
+	return toProtoJournalEntry(entry), nil
+}
+
+// WatchAccountEvents streams the account's events from the outbox, starting after start_cursor
+// and then following new events as they're published until the client goes away
+func (s *AccountService) WatchAccountEvents(req *WatchAccountEventsRequest, stream AccountService_WatchAccountEventsServer) error {
+	ctx := stream.Context()
+
+	accountId, err := id.FromString(req.GetAccountId())
+	if err != nil {
+		return e.FieldError("account_id", err)
+	}
+
+	var after *id.Identifier
+	if req.GetStartCursor() != "" {
+		eventId, err := id.FromString(req.GetStartCursor())
+		if err != nil {
+			return e.FieldError("start_cursor", err)
+		}
+		after = &eventId
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.View); err != nil {
+		return err
+	}
+
+	for {
+		// Taken before reading so an event published in between still wakes us
+		updated := s.EventBroker.Updated()
+
+		batch, err := s.Events.ListAccountEvents(ctx, accountId, after, watchBatchSize)
+		if errors.Is(err, events.ErrUnknownEvent) {
+			return e.FieldError("start_cursor", err)
+		}
+		if err != nil {
+			return e.ApiError{Err: err}
+		}
+
+		for i := range batch {
+			if err := stream.Send(toProtoAccountEvent(&batch[i])); err != nil {
+				return err
+			}
+			after = &batch[i].Id
+		}
+
+		if len(batch) == watchBatchSize {
+			continue
+		}
+
+		// Polling picks up events relayed by other instances
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-updated:
+		case <-time.After(watchPollInterval):
+		}
+	}
+}
+
+func (s *AccountService) holdTTL() time.Duration {
+	if s.HoldTTL > 0 {
+		return s.HoldTTL
+	}
+	return DefaultHoldTTL
+}
+
+// toServiceError maps repo errors caused by the request onto the api error types
+func toServiceError(err error) error {
+	switch {
+	case errors.Is(err, accounts.ErrIdempotencyConflict):
+		return e.FieldError("idempotency_key", err)
+	case errors.Is(err, accounts.ErrNotAHold):
+		return e.FieldError("transaction_id", err)
+	case errors.Is(err, accounts.ErrCaptureExceedsHold), errors.Is(err, accounts.ErrReversalExceedsOriginal):
+		return e.FieldError("amount", err)
+	case errors.Is(err, accounts.ErrInsufficientFunds), errors.Is(err, accounts.ErrCurrencyMismatch), errors.Is(err, money.ErrOverflow),
+		errors.Is(err, accounts.ErrHoldNotPending), errors.Is(err, accounts.ErrHoldExpired),
+		errors.Is(err, accounts.ErrNotReversible), errors.Is(err, accounts.ErrAlreadyReversed),
+		errors.Is(err, accounts.ErrLastAdmin), errors.Is(err, accounts.ErrDailyWithdrawalLimit),
+		errors.Is(err, accounts.ErrHourlyTransferLimit), errors.Is(err, accounts.ErrMonthlyOutboundLimit):
+		return e.PreconditionError{Err: err}
+	case errors.Is(err, accounts.ErrSystemAccount):
+		return e.RequestError{Err: err}
+	default:
+		return e.ApiError{Err: err}
+	}
+}
+
+// requestAmount prefers the int64 amount field, falling back to the deprecated int32 one for
+// clients that haven't moved over yet
+func requestAmount(amountMinor int64, legacyAmount int32) int64 {
+	if amountMinor != 0 {
+		return amountMinor
+	}
+	return int64(legacyAmount)
+}
+
+// overdraftLimitField names whichever overdraft limit field requestAmount took the limit from
+func overdraftLimitField(limitMinor int64) string {
+	if limitMinor != 0 {
+		return "overdraft_limit_minor"
+	}
+	return "overdraft_limit"
+}
+
+// legacyInt32 fills the deprecated int32 fields, saturating rather than wrapping values that
+// don't fit so old clients never see a balance with the wrong sign
+func legacyInt32(amount int64) int32 {
+	switch {
+	case amount > math.MaxInt32:
+		return math.MaxInt32
+	case amount < math.MinInt32:
+		return math.MinInt32
+	default:
+		return int32(amount)
+	}
+}
+
 func toProtoAccount(account *accounts.Account) *Account {
 	return &Account{
-		Id:        account.Id.String(),
-		UserId:    account.UserId.String(),
-		Name:      account.Name,
-		Balance:   int32(account.Balance),
-		CreatedAt: account.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: account.UpdatedAt.Format(time.RFC3339),
+		Id:                  account.Id.String(),
+		UserId:              account.UserId.String(),
+		Name:                account.Name,
+		Balance:             legacyInt32(account.Balance),
+		BalanceMinor:        account.Balance,
+		LedgerBalance:       account.Balance,
+		AvailableBalance:    account.AvailableBalance(),
+		OverdraftLimit:      legacyInt32(account.OverdraftLimit),
+		OverdraftLimitMinor: account.OverdraftLimit,
+		Currency:            account.Currency.Code(),
+		CreatedAt:           account.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:           account.UpdatedAt.Format(time.RFC3339),
 	}
 }
 
@@ -165,13 +541,69 @@ func toProtoTransaction(transaction *accounts.Transaction) *Transaction {
 	if transaction.Description != nil {
 		description = *transaction.Description
 	}
+	journalEntryId := ""
+	if transaction.JournalEntryId != nil {
+		journalEntryId = transaction.JournalEntryId.String()
+	}
+	var authorizedAmount int64
+	if transaction.AuthorizedAmount != nil {
+		authorizedAmount = *transaction.AuthorizedAmount
+	}
+	expiresAt := ""
+	if transaction.ExpiresAt != nil {
+		expiresAt = transaction.ExpiresAt.Format(time.RFC3339)
+	}
+	reversalOf := ""
+	if transaction.ReversalOf != nil {
+		reversalOf = transaction.ReversalOf.String()
+	}
 	return &Transaction{
-		Id:              transaction.Id.String(),
-		AccountId:       transaction.AccountId.String(),
-		Amount:          int32(transaction.Amount),
-		TransactionType: transaction.TransactionType.String(),
-		TransactionDate: transaction.TransactionDate.Format(time.RFC3339),
-		Description:     description,
-		Status:          transaction.Status.String(),
+		Id:               transaction.Id.String(),
+		AccountId:        transaction.AccountId.String(),
+		Amount:           legacyInt32(transaction.Amount),
+		AmountMinor:      transaction.Amount,
+		TransactionType:  transaction.TransactionType.String(),
+		TransactionDate:  transaction.TransactionDate.Format(time.RFC3339),
+		Description:      description,
+		Status:           transaction.Status.String(),
+		JournalEntryId:   journalEntryId,
+		Currency:         transaction.Currency.Code(),
+		AuthorizedAmount: authorizedAmount,
+		ExpiresAt:        expiresAt,
+		ReversalOf:       reversalOf,
+	}
+}
+
+func toProtoAccountEvent(event *events.Event) *AccountEvent {
+	accountIds := make([]string, 0, len(event.AccountIds))
+	for _, accountId := range event.AccountIds {
+		accountIds = append(accountIds, accountId.String())
+	}
+
+	return &AccountEvent{
+		Id:         event.Id.String(),
+		Type:       string(event.Type),
+		AccountIds: accountIds,
+		Payload:    string(event.Payload),
+		CreatedAt:  event.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func toProtoJournalEntry(entry *accounts.JournalEntry) *JournalEntry {
+	description := ""
+	if entry.Description != nil {
+		description = *entry.Description
+	}
+
+	legs := make([]*Transaction, 0, len(entry.Legs))
+	for i := 0; i < len(entry.Legs); i++ {
+		legs = append(legs, toProtoTransaction(&entry.Legs[i]))
+	}
+
+	return &JournalEntry{
+		Id:          entry.Id.String(),
+		Description: description,
+		CreatedAt:   entry.CreatedAt.Format(time.RFC3339),
+		Legs:        legs,
 	}
 }
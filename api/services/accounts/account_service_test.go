@@ -0,0 +1,44 @@
+package accountservice
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLegacyInt32Saturates(t *testing.T) {
+	cases := []struct {
+		amount   int64
+		expected int32
+	}{
+		{0, 0},
+		{-500, -500},
+		{math.MaxInt32, math.MaxInt32},
+		{math.MinInt32, math.MinInt32},
+		// Balances past int32 stick at the bound instead of wrapping to the opposite sign
+		{math.MaxInt32 + 1, math.MaxInt32},
+		{math.MinInt32 - 1, math.MinInt32},
+		{math.MaxInt64, math.MaxInt32},
+	}
+
+	for _, c := range cases {
+		if actual := legacyInt32(c.amount); actual != c.expected {
+			t.Fatalf("Expected %d for %d, got %d", c.expected, c.amount, actual)
+		}
+	}
+}
+
+func TestRequestAmountPrefersMinorField(t *testing.T) {
+	if amount := requestAmount(5_000_000_000, 100); amount != 5_000_000_000 {
+		t.Fatalf("Expected the int64 field to win, got %d", amount)
+	}
+	if amount := requestAmount(0, 100); amount != 100 {
+		t.Fatalf("Expected the legacy field when the int64 one is unset, got %d", amount)
+	}
+
+	if field := overdraftLimitField(5_000_000_000); field != "overdraft_limit_minor" {
+		t.Fatalf("Expected overdraft_limit_minor, got %s", field)
+	}
+	if field := overdraftLimitField(0); field != "overdraft_limit" {
+		t.Fatalf("Expected overdraft_limit, got %s", field)
+	}
+}
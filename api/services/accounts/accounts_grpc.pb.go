@@ -19,12 +19,29 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	AccountService_CreateAccount_FullMethodName    = "/users.AccountService/CreateAccount"
-	AccountService_DepositFunds_FullMethodName     = "/users.AccountService/DepositFunds"
-	AccountService_WithdrawFunds_FullMethodName    = "/users.AccountService/WithdrawFunds"
-	AccountService_AccountTransfer_FullMethodName  = "/users.AccountService/AccountTransfer"
-	AccountService_ListTransactions_FullMethodName = "/users.AccountService/ListTransactions"
-	AccountService_GetBalance_FullMethodName       = "/users.AccountService/GetBalance"
+	AccountService_CreateAccount_FullMethodName        = "/users.AccountService/CreateAccount"
+	AccountService_UpdateAccount_FullMethodName        = "/users.AccountService/UpdateAccount"
+	AccountService_GetAccount_FullMethodName           = "/users.AccountService/GetAccount"
+	AccountService_ListAccounts_FullMethodName         = "/users.AccountService/ListAccounts"
+	AccountService_DepositFunds_FullMethodName         = "/users.AccountService/DepositFunds"
+	AccountService_WithdrawFunds_FullMethodName        = "/users.AccountService/WithdrawFunds"
+	AccountService_AccountTransfer_FullMethodName      = "/users.AccountService/AccountTransfer"
+	AccountService_ListTransactions_FullMethodName     = "/users.AccountService/ListTransactions"
+	AccountService_GetBalance_FullMethodName           = "/users.AccountService/GetBalance"
+	AccountService_GetJournalEntry_FullMethodName      = "/users.AccountService/GetJournalEntry"
+	AccountService_AuthorizeWithdrawal_FullMethodName  = "/users.AccountService/AuthorizeWithdrawal"
+	AccountService_CaptureTransaction_FullMethodName   = "/users.AccountService/CaptureTransaction"
+	AccountService_VoidTransaction_FullMethodName      = "/users.AccountService/VoidTransaction"
+	AccountService_ReverseTransaction_FullMethodName   = "/users.AccountService/ReverseTransaction"
+	AccountService_WatchAccountEvents_FullMethodName   = "/users.AccountService/WatchAccountEvents"
+	AccountService_AddAccountMember_FullMethodName     = "/users.AccountService/AddAccountMember"
+	AccountService_RemoveAccountMember_FullMethodName  = "/users.AccountService/RemoveAccountMember"
+	AccountService_ListAccountMembers_FullMethodName   = "/users.AccountService/ListAccountMembers"
+	AccountService_SetLimitTier_FullMethodName         = "/users.AccountService/SetLimitTier"
+	AccountService_GetLimitTier_FullMethodName         = "/users.AccountService/GetLimitTier"
+	AccountService_SetAccountLimits_FullMethodName     = "/users.AccountService/SetAccountLimits"
+	AccountService_GetAccountLimits_FullMethodName     = "/users.AccountService/GetAccountLimits"
+	AccountService_GetAccountLimitUsage_FullMethodName = "/users.AccountService/GetAccountLimitUsage"
 )
 
 // AccountServiceClient is the client API for AccountService service.
@@ -32,11 +49,28 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AccountServiceClient interface {
 	CreateAccount(ctx context.Context, in *CreateAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	UpdateAccount(ctx context.Context, in *UpdateAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error)
 	DepositFunds(ctx context.Context, in *DepositFundsRequest, opts ...grpc.CallOption) (*Transaction, error)
 	WithdrawFunds(ctx context.Context, in *WithdrawFundsRequest, opts ...grpc.CallOption) (*Transaction, error)
 	AccountTransfer(ctx context.Context, in *AccountTransferRequest, opts ...grpc.CallOption) (*AccountTransferResponse, error)
 	ListTransactions(ctx context.Context, in *ListTransactionsRequest, opts ...grpc.CallOption) (*ListTransactionsResponse, error)
 	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error)
+	GetJournalEntry(ctx context.Context, in *GetJournalEntryRequest, opts ...grpc.CallOption) (*JournalEntry, error)
+	AuthorizeWithdrawal(ctx context.Context, in *AuthorizeWithdrawalRequest, opts ...grpc.CallOption) (*Transaction, error)
+	CaptureTransaction(ctx context.Context, in *CaptureTransactionRequest, opts ...grpc.CallOption) (*Transaction, error)
+	VoidTransaction(ctx context.Context, in *VoidTransactionRequest, opts ...grpc.CallOption) (*Transaction, error)
+	ReverseTransaction(ctx context.Context, in *ReverseTransactionRequest, opts ...grpc.CallOption) (*JournalEntry, error)
+	WatchAccountEvents(ctx context.Context, in *WatchAccountEventsRequest, opts ...grpc.CallOption) (AccountService_WatchAccountEventsClient, error)
+	AddAccountMember(ctx context.Context, in *AddAccountMemberRequest, opts ...grpc.CallOption) (*AccountMember, error)
+	RemoveAccountMember(ctx context.Context, in *RemoveAccountMemberRequest, opts ...grpc.CallOption) (*AccountMember, error)
+	ListAccountMembers(ctx context.Context, in *ListAccountMembersRequest, opts ...grpc.CallOption) (*ListAccountMembersResponse, error)
+	SetLimitTier(ctx context.Context, in *SetLimitTierRequest, opts ...grpc.CallOption) (*LimitTier, error)
+	GetLimitTier(ctx context.Context, in *GetLimitTierRequest, opts ...grpc.CallOption) (*LimitTier, error)
+	SetAccountLimits(ctx context.Context, in *SetAccountLimitsRequest, opts ...grpc.CallOption) (*AccountLimits, error)
+	GetAccountLimits(ctx context.Context, in *GetAccountLimitsRequest, opts ...grpc.CallOption) (*AccountLimits, error)
+	GetAccountLimitUsage(ctx context.Context, in *GetAccountLimitUsageRequest, opts ...grpc.CallOption) (*GetAccountLimitUsageResponse, error)
 }
 
 type accountServiceClient struct {
@@ -57,6 +91,36 @@ func (c *accountServiceClient) CreateAccount(ctx context.Context, in *CreateAcco
 	return out, nil
 }
 
+func (c *accountServiceClient) UpdateAccount(ctx context.Context, in *UpdateAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Account)
+	err := c.cc.Invoke(ctx, AccountService_UpdateAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Account)
+	err := c.cc.Invoke(ctx, AccountService_GetAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAccountsResponse)
+	err := c.cc.Invoke(ctx, AccountService_ListAccounts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *accountServiceClient) DepositFunds(ctx context.Context, in *DepositFundsRequest, opts ...grpc.CallOption) (*Transaction, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Transaction)
@@ -107,16 +171,196 @@ func (c *accountServiceClient) GetBalance(ctx context.Context, in *GetBalanceReq
 	return out, nil
 }
 
+func (c *accountServiceClient) GetJournalEntry(ctx context.Context, in *GetJournalEntryRequest, opts ...grpc.CallOption) (*JournalEntry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JournalEntry)
+	err := c.cc.Invoke(ctx, AccountService_GetJournalEntry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) AuthorizeWithdrawal(ctx context.Context, in *AuthorizeWithdrawalRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Transaction)
+	err := c.cc.Invoke(ctx, AccountService_AuthorizeWithdrawal_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) CaptureTransaction(ctx context.Context, in *CaptureTransactionRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Transaction)
+	err := c.cc.Invoke(ctx, AccountService_CaptureTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) VoidTransaction(ctx context.Context, in *VoidTransactionRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Transaction)
+	err := c.cc.Invoke(ctx, AccountService_VoidTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) ReverseTransaction(ctx context.Context, in *ReverseTransactionRequest, opts ...grpc.CallOption) (*JournalEntry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JournalEntry)
+	err := c.cc.Invoke(ctx, AccountService_ReverseTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) WatchAccountEvents(ctx context.Context, in *WatchAccountEventsRequest, opts ...grpc.CallOption) (AccountService_WatchAccountEventsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AccountService_ServiceDesc.Streams[0], AccountService_WatchAccountEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &accountServiceWatchAccountEventsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AccountService_WatchAccountEventsClient interface {
+	Recv() (*AccountEvent, error)
+	grpc.ClientStream
+}
+
+type accountServiceWatchAccountEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *accountServiceWatchAccountEventsClient) Recv() (*AccountEvent, error) {
+	m := new(AccountEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *accountServiceClient) AddAccountMember(ctx context.Context, in *AddAccountMemberRequest, opts ...grpc.CallOption) (*AccountMember, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AccountMember)
+	err := c.cc.Invoke(ctx, AccountService_AddAccountMember_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) RemoveAccountMember(ctx context.Context, in *RemoveAccountMemberRequest, opts ...grpc.CallOption) (*AccountMember, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AccountMember)
+	err := c.cc.Invoke(ctx, AccountService_RemoveAccountMember_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) ListAccountMembers(ctx context.Context, in *ListAccountMembersRequest, opts ...grpc.CallOption) (*ListAccountMembersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAccountMembersResponse)
+	err := c.cc.Invoke(ctx, AccountService_ListAccountMembers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) SetLimitTier(ctx context.Context, in *SetLimitTierRequest, opts ...grpc.CallOption) (*LimitTier, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LimitTier)
+	err := c.cc.Invoke(ctx, AccountService_SetLimitTier_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) GetLimitTier(ctx context.Context, in *GetLimitTierRequest, opts ...grpc.CallOption) (*LimitTier, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LimitTier)
+	err := c.cc.Invoke(ctx, AccountService_GetLimitTier_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) SetAccountLimits(ctx context.Context, in *SetAccountLimitsRequest, opts ...grpc.CallOption) (*AccountLimits, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AccountLimits)
+	err := c.cc.Invoke(ctx, AccountService_SetAccountLimits_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) GetAccountLimits(ctx context.Context, in *GetAccountLimitsRequest, opts ...grpc.CallOption) (*AccountLimits, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AccountLimits)
+	err := c.cc.Invoke(ctx, AccountService_GetAccountLimits_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *accountServiceClient) GetAccountLimitUsage(ctx context.Context, in *GetAccountLimitUsageRequest, opts ...grpc.CallOption) (*GetAccountLimitUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAccountLimitUsageResponse)
+	err := c.cc.Invoke(ctx, AccountService_GetAccountLimitUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AccountServiceServer is the server API for AccountService service.
 // All implementations must embed UnimplementedAccountServiceServer
 // for forward compatibility
 type AccountServiceServer interface {
 	CreateAccount(context.Context, *CreateAccountRequest) (*Account, error)
+	UpdateAccount(context.Context, *UpdateAccountRequest) (*Account, error)
+	GetAccount(context.Context, *GetAccountRequest) (*Account, error)
+	ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error)
 	DepositFunds(context.Context, *DepositFundsRequest) (*Transaction, error)
 	WithdrawFunds(context.Context, *WithdrawFundsRequest) (*Transaction, error)
 	AccountTransfer(context.Context, *AccountTransferRequest) (*AccountTransferResponse, error)
 	ListTransactions(context.Context, *ListTransactionsRequest) (*ListTransactionsResponse, error)
 	GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error)
+	GetJournalEntry(context.Context, *GetJournalEntryRequest) (*JournalEntry, error)
+	AuthorizeWithdrawal(context.Context, *AuthorizeWithdrawalRequest) (*Transaction, error)
+	CaptureTransaction(context.Context, *CaptureTransactionRequest) (*Transaction, error)
+	VoidTransaction(context.Context, *VoidTransactionRequest) (*Transaction, error)
+	ReverseTransaction(context.Context, *ReverseTransactionRequest) (*JournalEntry, error)
+	WatchAccountEvents(*WatchAccountEventsRequest, AccountService_WatchAccountEventsServer) error
+	AddAccountMember(context.Context, *AddAccountMemberRequest) (*AccountMember, error)
+	RemoveAccountMember(context.Context, *RemoveAccountMemberRequest) (*AccountMember, error)
+	ListAccountMembers(context.Context, *ListAccountMembersRequest) (*ListAccountMembersResponse, error)
+	SetLimitTier(context.Context, *SetLimitTierRequest) (*LimitTier, error)
+	GetLimitTier(context.Context, *GetLimitTierRequest) (*LimitTier, error)
+	SetAccountLimits(context.Context, *SetAccountLimitsRequest) (*AccountLimits, error)
+	GetAccountLimits(context.Context, *GetAccountLimitsRequest) (*AccountLimits, error)
+	GetAccountLimitUsage(context.Context, *GetAccountLimitUsageRequest) (*GetAccountLimitUsageResponse, error)
 	mustEmbedUnimplementedAccountServiceServer()
 }
 
@@ -127,6 +371,15 @@ type UnimplementedAccountServiceServer struct {
 func (UnimplementedAccountServiceServer) CreateAccount(context.Context, *CreateAccountRequest) (*Account, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateAccount not implemented")
 }
+func (UnimplementedAccountServiceServer) UpdateAccount(context.Context, *UpdateAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAccount not implemented")
+}
+func (UnimplementedAccountServiceServer) GetAccount(context.Context, *GetAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccount not implemented")
+}
+func (UnimplementedAccountServiceServer) ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccounts not implemented")
+}
 func (UnimplementedAccountServiceServer) DepositFunds(context.Context, *DepositFundsRequest) (*Transaction, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DepositFunds not implemented")
 }
@@ -142,6 +395,48 @@ func (UnimplementedAccountServiceServer) ListTransactions(context.Context, *List
 func (UnimplementedAccountServiceServer) GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
 }
+func (UnimplementedAccountServiceServer) GetJournalEntry(context.Context, *GetJournalEntryRequest) (*JournalEntry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJournalEntry not implemented")
+}
+func (UnimplementedAccountServiceServer) AuthorizeWithdrawal(context.Context, *AuthorizeWithdrawalRequest) (*Transaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthorizeWithdrawal not implemented")
+}
+func (UnimplementedAccountServiceServer) CaptureTransaction(context.Context, *CaptureTransactionRequest) (*Transaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CaptureTransaction not implemented")
+}
+func (UnimplementedAccountServiceServer) VoidTransaction(context.Context, *VoidTransactionRequest) (*Transaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VoidTransaction not implemented")
+}
+func (UnimplementedAccountServiceServer) ReverseTransaction(context.Context, *ReverseTransactionRequest) (*JournalEntry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReverseTransaction not implemented")
+}
+func (UnimplementedAccountServiceServer) WatchAccountEvents(*WatchAccountEventsRequest, AccountService_WatchAccountEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchAccountEvents not implemented")
+}
+func (UnimplementedAccountServiceServer) AddAccountMember(context.Context, *AddAccountMemberRequest) (*AccountMember, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddAccountMember not implemented")
+}
+func (UnimplementedAccountServiceServer) RemoveAccountMember(context.Context, *RemoveAccountMemberRequest) (*AccountMember, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveAccountMember not implemented")
+}
+func (UnimplementedAccountServiceServer) ListAccountMembers(context.Context, *ListAccountMembersRequest) (*ListAccountMembersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccountMembers not implemented")
+}
+func (UnimplementedAccountServiceServer) SetLimitTier(context.Context, *SetLimitTierRequest) (*LimitTier, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLimitTier not implemented")
+}
+func (UnimplementedAccountServiceServer) GetLimitTier(context.Context, *GetLimitTierRequest) (*LimitTier, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLimitTier not implemented")
+}
+func (UnimplementedAccountServiceServer) SetAccountLimits(context.Context, *SetAccountLimitsRequest) (*AccountLimits, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAccountLimits not implemented")
+}
+func (UnimplementedAccountServiceServer) GetAccountLimits(context.Context, *GetAccountLimitsRequest) (*AccountLimits, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccountLimits not implemented")
+}
+func (UnimplementedAccountServiceServer) GetAccountLimitUsage(context.Context, *GetAccountLimitUsageRequest) (*GetAccountLimitUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccountLimitUsage not implemented")
+}
 func (UnimplementedAccountServiceServer) mustEmbedUnimplementedAccountServiceServer() {}
 
 // UnsafeAccountServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -173,6 +468,60 @@ func _AccountService_CreateAccount_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AccountService_UpdateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).UpdateAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_UpdateAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).UpdateAccount(ctx, req.(*UpdateAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_GetAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).GetAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_GetAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).GetAccount(ctx, req.(*GetAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_ListAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).ListAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_ListAccounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).ListAccounts(ctx, req.(*ListAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AccountService_DepositFunds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DepositFundsRequest)
 	if err := dec(in); err != nil {
@@ -263,6 +612,261 @@ func _AccountService_GetBalance_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AccountService_GetJournalEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJournalEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).GetJournalEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_GetJournalEntry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).GetJournalEntry(ctx, req.(*GetJournalEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_AuthorizeWithdrawal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizeWithdrawalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).AuthorizeWithdrawal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_AuthorizeWithdrawal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).AuthorizeWithdrawal(ctx, req.(*AuthorizeWithdrawalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_CaptureTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CaptureTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).CaptureTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_CaptureTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).CaptureTransaction(ctx, req.(*CaptureTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_VoidTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoidTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).VoidTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_VoidTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).VoidTransaction(ctx, req.(*VoidTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_ReverseTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReverseTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).ReverseTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_ReverseTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).ReverseTransaction(ctx, req.(*ReverseTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_WatchAccountEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchAccountEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AccountServiceServer).WatchAccountEvents(m, &accountServiceWatchAccountEventsServer{ServerStream: stream})
+}
+
+type AccountService_WatchAccountEventsServer interface {
+	Send(*AccountEvent) error
+	grpc.ServerStream
+}
+
+type accountServiceWatchAccountEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *accountServiceWatchAccountEventsServer) Send(m *AccountEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AccountService_AddAccountMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddAccountMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).AddAccountMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_AddAccountMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).AddAccountMember(ctx, req.(*AddAccountMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_RemoveAccountMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveAccountMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).RemoveAccountMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_RemoveAccountMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).RemoveAccountMember(ctx, req.(*RemoveAccountMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_ListAccountMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccountMembersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).ListAccountMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_ListAccountMembers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).ListAccountMembers(ctx, req.(*ListAccountMembersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_SetLimitTier_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLimitTierRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).SetLimitTier(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_SetLimitTier_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).SetLimitTier(ctx, req.(*SetLimitTierRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_GetLimitTier_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLimitTierRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).GetLimitTier(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_GetLimitTier_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).GetLimitTier(ctx, req.(*GetLimitTierRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_SetAccountLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAccountLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).SetAccountLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_SetAccountLimits_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).SetAccountLimits(ctx, req.(*SetAccountLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_GetAccountLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).GetAccountLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_GetAccountLimits_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).GetAccountLimits(ctx, req.(*GetAccountLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AccountService_GetAccountLimitUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountLimitUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccountServiceServer).GetAccountLimitUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccountService_GetAccountLimitUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccountServiceServer).GetAccountLimitUsage(ctx, req.(*GetAccountLimitUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AccountService_ServiceDesc is the grpc.ServiceDesc for AccountService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -274,6 +878,18 @@ var AccountService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateAccount",
 			Handler:    _AccountService_CreateAccount_Handler,
 		},
+		{
+			MethodName: "UpdateAccount",
+			Handler:    _AccountService_UpdateAccount_Handler,
+		},
+		{
+			MethodName: "GetAccount",
+			Handler:    _AccountService_GetAccount_Handler,
+		},
+		{
+			MethodName: "ListAccounts",
+			Handler:    _AccountService_ListAccounts_Handler,
+		},
 		{
 			MethodName: "DepositFunds",
 			Handler:    _AccountService_DepositFunds_Handler,
@@ -294,7 +910,65 @@ var AccountService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetBalance",
 			Handler:    _AccountService_GetBalance_Handler,
 		},
+		{
+			MethodName: "GetJournalEntry",
+			Handler:    _AccountService_GetJournalEntry_Handler,
+		},
+		{
+			MethodName: "AuthorizeWithdrawal",
+			Handler:    _AccountService_AuthorizeWithdrawal_Handler,
+		},
+		{
+			MethodName: "CaptureTransaction",
+			Handler:    _AccountService_CaptureTransaction_Handler,
+		},
+		{
+			MethodName: "VoidTransaction",
+			Handler:    _AccountService_VoidTransaction_Handler,
+		},
+		{
+			MethodName: "ReverseTransaction",
+			Handler:    _AccountService_ReverseTransaction_Handler,
+		},
+		{
+			MethodName: "AddAccountMember",
+			Handler:    _AccountService_AddAccountMember_Handler,
+		},
+		{
+			MethodName: "RemoveAccountMember",
+			Handler:    _AccountService_RemoveAccountMember_Handler,
+		},
+		{
+			MethodName: "ListAccountMembers",
+			Handler:    _AccountService_ListAccountMembers_Handler,
+		},
+		{
+			MethodName: "SetLimitTier",
+			Handler:    _AccountService_SetLimitTier_Handler,
+		},
+		{
+			MethodName: "GetLimitTier",
+			Handler:    _AccountService_GetLimitTier_Handler,
+		},
+		{
+			MethodName: "SetAccountLimits",
+			Handler:    _AccountService_SetAccountLimits_Handler,
+		},
+		{
+			MethodName: "GetAccountLimits",
+			Handler:    _AccountService_GetAccountLimits_Handler,
+		},
+		{
+			MethodName: "GetAccountLimitUsage",
+			Handler:    _AccountService_GetAccountLimitUsage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchAccountEvents",
+			Handler:       _AccountService_WatchAccountEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "accounts.proto",
 }
@@ -0,0 +1,81 @@
+package accountservice
+
+import (
+	e "chariottakehome/api/errors"
+	"chariottakehome/internal/accounts"
+	"chariottakehome/internal/auth"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// authorizeAccount checks the caller is a member of the account with the given permission
+func (s *AccountService) authorizeAccount(ctx context.Context, accountId id.Identifier, permission accounts.Permission) error {
+	principal, _ := auth.FromContext(ctx)
+
+	var member *accounts.Member
+	if principal != nil {
+		m, err := s.Repo.GetAccountMember(ctx, accountId, principal.UserId)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return e.ApiError{Err: err}
+		}
+		member = m
+	}
+
+	action := auth.Write
+	if permission == accounts.View {
+		action = auth.Read
+	}
+
+	err := auth.AuthorizeShared(principal, member != nil, member != nil && member.Can(permission), action)
+	switch {
+	// Someone with no access to the account is told it doesn't exist, the same as for an id
+	// that's never been used, so account ids can't be probed
+	case errors.Is(err, auth.ErrNotOwner):
+		return e.ApiError{Err: pgx.ErrNoRows}
+	// Operators aren't members but can access every account, so it has to be checked it exists
+	case err == nil && member == nil:
+		if _, err := s.Repo.GetAccount(ctx, accountId); err != nil {
+			return e.ApiError{Err: err}
+		}
+	}
+
+	return err
+}
+
+// authorizeTransaction checks the caller may act on the transaction's account
+func (s *AccountService) authorizeTransaction(ctx context.Context, transactionId id.Identifier, permission accounts.Permission) error {
+	transaction, err := s.Repo.GetTransaction(ctx, transactionId)
+	if err != nil {
+		return e.ApiError{Err: err}
+	}
+
+	return s.authorizeAccount(ctx, transaction.AccountId, permission)
+}
+
+// authorizeJournalEntry lets the caller read an entry if they can view any of its accounts, so
+// both sides of a transfer can see it
+func (s *AccountService) authorizeJournalEntry(ctx context.Context, entry *accounts.JournalEntry) error {
+	err := error(e.ApiError{Err: pgx.ErrNoRows})
+	for _, leg := range entry.Legs {
+		if err = s.authorizeAccount(ctx, leg.AccountId, accounts.View); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func authorizeUser(ctx context.Context, userId id.Identifier, action auth.Action) error {
+	principal, _ := auth.FromContext(ctx)
+	return auth.Authorize(principal, userId, action)
+}
+
+// authorizeOperator is for calls customers can't make on their own accounts, like changing an
+// overdraft limit or reversing a transaction
+func authorizeOperator(ctx context.Context) error {
+	principal, _ := auth.FromContext(ctx)
+	return auth.AuthorizeRole(principal, auth.Operator)
+}
@@ -0,0 +1,82 @@
+package accountservice
+
+import (
+	"chariottakehome/internal/accounts"
+	"chariottakehome/internal/auth"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// memberRepo knows one account and its members. Calls it doesn't implement panic on the nil
+// embedded repository.
+type memberRepo struct {
+	accounts.AccountRepository
+	accountId id.Identifier
+	members   map[id.Identifier]*accounts.Member
+}
+
+func (r memberRepo) GetAccount(ctx context.Context, accountId id.Identifier) (*accounts.Account, error) {
+	if accountId != r.accountId {
+		return nil, pgx.ErrNoRows
+	}
+	return &accounts.Account{Id: accountId}, nil
+}
+
+func (r memberRepo) GetAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier) (*accounts.Member, error) {
+	if m, ok := r.members[userId]; ok && accountId == r.accountId {
+		return m, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func mustId(t *testing.T, s string) id.Identifier {
+	t.Helper()
+
+	identifier, err := id.FromString(s)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %s", s, err)
+	}
+	return identifier
+}
+
+func TestAuthorizeAccountHidesAccountsFromNonMembers(t *testing.T) {
+	accountId := mustId(t, testAccountId)
+	missingId := mustId(t, "c-0000000000MISSING0")
+	memberId := mustId(t, "c-00000000000MEMBER0")
+	strangerId := mustId(t, "c-0000000000STRANGER")
+
+	s := &AccountService{Repo: memberRepo{
+		accountId: accountId,
+		members: map[id.Identifier]*accounts.Member{
+			memberId: {AccountId: accountId, UserId: memberId, Permissions: []accounts.Permission{accounts.View}},
+		},
+	}}
+	as := func(userId id.Identifier, role auth.Role) context.Context {
+		return auth.NewContext(context.Background(), &auth.Principal{UserId: userId, Role: role})
+	}
+
+	// A stranger can't tell an account they can't access from one that doesn't exist
+	for _, target := range []id.Identifier{accountId, missingId} {
+		if err := s.authorizeAccount(as(strangerId, auth.Owner), target, accounts.View); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("Expected NotFound for %s, got %v", target, err)
+		}
+	}
+
+	if err := s.authorizeAccount(as(memberId, auth.Owner), accountId, accounts.View); err != nil {
+		t.Fatalf("Expected the member to view the account, got %v", err)
+	}
+	if err := s.authorizeAccount(as(memberId, auth.Owner), accountId, accounts.Withdraw); !errors.Is(err, auth.ErrNotPermitted) {
+		t.Fatalf("Expected ErrNotPermitted, got %v", err)
+	}
+
+	if err := s.authorizeAccount(as(strangerId, auth.Operator), accountId, accounts.Admin); err != nil {
+		t.Fatalf("Expected the operator to access the account, got %v", err)
+	}
+	if err := s.authorizeAccount(as(strangerId, auth.Operator), missingId, accounts.View); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("Expected NotFound for the operator, got %v", err)
+	}
+}
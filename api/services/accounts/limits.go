@@ -0,0 +1,195 @@
+package accountservice
+
+import (
+	e "chariottakehome/api/errors"
+	"chariottakehome/internal/accounts"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"regexp"
+	"time"
+)
+
+// Tier names end up in paths, so they're kept to characters that don't need escaping. 64 matches
+// the VARCHAR(64) name column.
+var tierNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// SetLimitTier creates a tier or replaces its limits, which changes the limits of every account
+// assigned to it
+func (s *AccountService) SetLimitTier(ctx context.Context, req *SetLimitTierRequest) (*LimitTier, error) {
+	var v validator
+	v.tierName("name", req.GetName())
+	limits := v.limits("limits", req.GetLimits())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+
+	tier, err := s.Repo.SetLimitTier(ctx, req.GetName(), limits)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	return toProtoLimitTier(tier), nil
+}
+
+func (s *AccountService) GetLimitTier(ctx context.Context, req *GetLimitTierRequest) (*LimitTier, error) {
+	var v validator
+	v.tierName("name", req.GetName())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+
+	tier, err := s.Repo.GetLimitTier(ctx, req.GetName())
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	return toProtoLimitTier(tier), nil
+}
+
+// SetAccountLimits assigns the account to a tier and replaces the limits set on it. Limits are
+// for the risk team, so account admins can read them but not change them.
+func (s *AccountService) SetAccountLimits(ctx context.Context, req *SetAccountLimitsRequest) (*AccountLimits, error) {
+	var v validator
+	accountId := v.identifier("account_id", req.GetAccountId())
+	var tier *string
+	if name := req.GetTier(); name != "" {
+		v.tierName("tier", name)
+		tier = &name
+	}
+	limits := v.limits("limits", req.GetLimits())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+
+	accountLimits, err := s.Repo.SetAccountLimits(ctx, accountId, tier, limits)
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
+	return toProtoAccountLimits(accountLimits), nil
+}
+
+func (s *AccountService) GetAccountLimits(ctx context.Context, req *GetAccountLimitsRequest) (*AccountLimits, error) {
+	accountId, err := id.FromString(req.GetAccountId())
+	if err != nil {
+		return nil, e.FieldError("account_id", err)
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.View); err != nil {
+		return nil, err
+	}
+
+	accountLimits, err := s.Repo.GetAccountLimits(ctx, accountId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	return toProtoAccountLimits(accountLimits), nil
+}
+
+// GetAccountLimitUsage reports how much of each of its limits the account has left in the
+// current window
+func (s *AccountService) GetAccountLimitUsage(ctx context.Context, req *GetAccountLimitUsageRequest) (*GetAccountLimitUsageResponse, error) {
+	accountId, err := id.FromString(req.GetAccountId())
+	if err != nil {
+		return nil, e.FieldError("account_id", err)
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.View); err != nil {
+		return nil, err
+	}
+
+	usage, err := s.Repo.GetLimitUsage(ctx, accountId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	protoUsage := make([]*LimitUsage, 0, len(usage))
+	for _, u := range usage {
+		protoUsage = append(protoUsage, &LimitUsage{
+			Limit:     u.Limit,
+			Max:       u.Max,
+			Used:      u.Used,
+			Remaining: u.Remaining(),
+			ResetsAt:  u.ResetsAt.Format(time.RFC3339),
+		})
+	}
+
+	return &GetAccountLimitUsageResponse{Usage: protoUsage}, nil
+}
+
+func (v *validator) tierName(field string, name string) {
+	if !tierNamePattern.MatchString(name) {
+		v.addViolation(field, errors.New("tier name must be 1 to 64 lowercase letters, digits, hyphens, or underscores"))
+	}
+}
+
+// limits checks each cap that's set isn't negative. A cap of zero blocks that kind of movement.
+func (v *validator) limits(field string, limits *Limits) accounts.Limits {
+	if limits == nil {
+		return accounts.Limits{}
+	}
+
+	caps := []struct {
+		name  string
+		value *int64
+	}{
+		{"daily_withdrawal_amount", limits.DailyWithdrawalAmount},
+		{"hourly_transfer_count", limits.HourlyTransferCount},
+		{"monthly_outbound_amount", limits.MonthlyOutboundAmount},
+	}
+	for _, c := range caps {
+		if c.value != nil && *c.value < 0 {
+			v.addViolation(field+"."+c.name, errors.New("limit cannot be negative"))
+		}
+	}
+
+	return accounts.Limits{
+		DailyWithdrawalAmount: limits.DailyWithdrawalAmount,
+		HourlyTransferCount:   limits.HourlyTransferCount,
+		MonthlyOutboundAmount: limits.MonthlyOutboundAmount,
+	}
+}
+
+func toProtoLimits(limits accounts.Limits) *Limits {
+	return &Limits{
+		DailyWithdrawalAmount: limits.DailyWithdrawalAmount,
+		HourlyTransferCount:   limits.HourlyTransferCount,
+		MonthlyOutboundAmount: limits.MonthlyOutboundAmount,
+	}
+}
+
+func toProtoLimitTier(tier *accounts.LimitTier) *LimitTier {
+	return &LimitTier{
+		Name:      tier.Name,
+		Limits:    toProtoLimits(tier.Limits),
+		CreatedAt: tier.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: tier.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toProtoAccountLimits(limits *accounts.AccountLimits) *AccountLimits {
+	protoLimits := &AccountLimits{
+		AccountId: limits.AccountId.String(),
+		Limits:    toProtoLimits(limits.Limits),
+		Effective: toProtoLimits(limits.Effective),
+	}
+	if limits.Tier != nil {
+		protoLimits.Tier = *limits.Tier
+	}
+
+	return protoLimits
+}
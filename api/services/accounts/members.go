@@ -0,0 +1,112 @@
+package accountservice
+
+import (
+	e "chariottakehome/api/errors"
+	"chariottakehome/internal/accounts"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// AddAccountMember gives a user access to the account, or changes an existing member's permissions
+func (s *AccountService) AddAccountMember(ctx context.Context, req *AddAccountMemberRequest) (*AccountMember, error) {
+	accountId, userId, permissions, err := validateAddMember(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.Admin); err != nil {
+		return nil, err
+	}
+
+	member, err := s.Repo.AddAccountMember(ctx, accountId, userId, permissions)
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
+	return toProtoAccountMember(member), nil
+}
+
+func (s *AccountService) RemoveAccountMember(ctx context.Context, req *RemoveAccountMemberRequest) (*AccountMember, error) {
+	var v validator
+	accountId := v.identifier("account_id", req.GetAccountId())
+	userId := v.identifier("user_id", req.GetUserId())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.Admin); err != nil {
+		return nil, err
+	}
+
+	member, err := s.Repo.RemoveAccountMember(ctx, accountId, userId)
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
+	return toProtoAccountMember(member), nil
+}
+
+func (s *AccountService) ListAccountMembers(ctx context.Context, req *ListAccountMembersRequest) (*ListAccountMembersResponse, error) {
+	accountId, err := id.FromString(req.GetAccountId())
+	if err != nil {
+		return nil, e.FieldError("account_id", err)
+	}
+
+	if err := s.authorizeAccount(ctx, accountId, accounts.View); err != nil {
+		return nil, err
+	}
+
+	members, err := s.Repo.ListAccountMembers(ctx, accountId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	protoMembers := make([]*AccountMember, 0, len(members))
+	for i := range members {
+		protoMembers = append(protoMembers, toProtoAccountMember(&members[i]))
+	}
+
+	return &ListAccountMembersResponse{Members: protoMembers}, nil
+}
+
+func validateAddMember(req *AddAccountMemberRequest) (id.Identifier, id.Identifier, []accounts.Permission, error) {
+	var v validator
+	accountId := v.identifier("account_id", req.GetAccountId())
+	userId := v.identifier("user_id", req.GetUserId())
+
+	permissions := make([]accounts.Permission, 0, len(req.GetPermissions()))
+	for _, p := range req.GetPermissions() {
+		var permission accounts.Permission
+		if err := permission.Scan(p); err != nil {
+			v.addViolation("permissions", fmt.Errorf("unknown permission %q", p))
+			continue
+		}
+		if !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	if len(req.GetPermissions()) == 0 {
+		v.addViolation("permissions", errors.New("at least one permission is required"))
+	}
+
+	return accountId, userId, permissions, v.err()
+}
+
+func toProtoAccountMember(member *accounts.Member) *AccountMember {
+	permissions := make([]string, 0, len(member.Permissions))
+	for _, permission := range member.Permissions {
+		permissions = append(permissions, permission.String())
+	}
+
+	return &AccountMember{
+		AccountId:   member.AccountId.String(),
+		UserId:      member.UserId.String(),
+		Permissions: permissions,
+		CreatedAt:   member.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   member.UpdatedAt.Format(time.RFC3339),
+	}
+}
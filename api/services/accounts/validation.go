@@ -0,0 +1,264 @@
+package accountservice
+
+import (
+	e "chariottakehome/api/errors"
+	"chariottakehome/internal/accounts"
+	"chariottakehome/internal/cursor"
+	id "chariottakehome/internal/identifier"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxTransactionAmount caps a single deposit, withdrawal, or transfer when the service
+	// isn't configured with its own limit. Amounts are in the account currency's minor unit.
+	DefaultMaxTransactionAmount int64 = 1_000_000_000
+
+	defaultPageSize int = 15
+	// maxPageSize bounds how many rows a list call reads at once
+	maxPageSize int = 100
+
+	// Matches the VARCHAR(255) description columns
+	maxDescriptionLength int = 255
+)
+
+var errInvalidFields = errors.New("request has invalid fields")
+
+// validator collects every field violation in a request so the client can fix them in one go
+type validator struct {
+	violations []e.FieldViolation
+}
+
+func (v *validator) addViolation(field string, err error) {
+	v.violations = append(v.violations, e.FieldViolation{Field: field, Description: err.Error()})
+}
+
+func (v *validator) identifier(field string, value string) id.Identifier {
+	identifier, err := id.FromString(value)
+	if err != nil {
+		v.addViolation(field, err)
+	}
+
+	return identifier
+}
+
+func (v *validator) amount(field string, amount int64, max int64) {
+	switch {
+	case amount <= 0:
+		v.addViolation(field, errors.New("amount must be greater than zero"))
+	case amount > max:
+		v.addViolation(field, fmt.Errorf("amount cannot exceed %d", max))
+	}
+}
+
+func (v *validator) optionalAmount(field string, amount *int64) {
+	if amount != nil && *amount <= 0 {
+		v.addViolation(field, errors.New("amount must be greater than zero"))
+	}
+}
+
+func (v *validator) description(field string, description string) {
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		v.addViolation(field, fmt.Errorf("description cannot be longer than %d characters", maxDescriptionLength))
+	}
+}
+
+// err returns nil when the request is valid, or a RequestError listing all violations
+func (v *validator) err() error {
+	switch len(v.violations) {
+	case 0:
+		return nil
+	case 1:
+		return e.RequestError{Err: errors.New(v.violations[0].Description), Violations: v.violations}
+	default:
+		return e.RequestError{Err: errInvalidFields, Violations: v.violations}
+	}
+}
+
+func (s *AccountService) maxTransactionAmount() int64 {
+	if s.MaxTransactionAmount > 0 {
+		return s.MaxTransactionAmount
+	}
+	return DefaultMaxTransactionAmount
+}
+
+func (s *AccountService) validateDeposit(req *DepositFundsRequest) (id.Identifier, int64, error) {
+	var v validator
+	accountId := v.identifier("account_id", req.GetAccountId())
+	amount := requestAmount(req.GetAmountMinor(), req.GetAmount())
+	v.amount(amountField(req.GetAmountMinor()), amount, s.maxTransactionAmount())
+	v.description("description", req.GetDescription())
+
+	return accountId, amount, v.err()
+}
+
+func (s *AccountService) validateWithdrawal(req *WithdrawFundsRequest) (id.Identifier, int64, error) {
+	var v validator
+	accountId := v.identifier("account_id", req.GetAccountId())
+	amount := requestAmount(req.GetAmountMinor(), req.GetAmount())
+	v.amount(amountField(req.GetAmountMinor()), amount, s.maxTransactionAmount())
+	v.description("description", req.GetDescription())
+
+	return accountId, amount, v.err()
+}
+
+func (s *AccountService) validateTransfer(req *AccountTransferRequest) (id.Identifier, id.Identifier, int64, error) {
+	var v validator
+	sourceAccountId := v.identifier("source_account_id", req.GetSourceAccountId())
+	destAccountId := v.identifier("destination_account_id", req.GetDestinationAccountId())
+	if len(v.violations) == 0 && sourceAccountId == destAccountId {
+		v.addViolation("destination_account_id", errors.New("cannot transfer to the source account"))
+	}
+	amount := requestAmount(req.GetAmountMinor(), req.GetAmount())
+	v.amount(amountField(req.GetAmountMinor()), amount, s.maxTransactionAmount())
+	v.description("description", req.GetDescription())
+
+	return sourceAccountId, destAccountId, amount, v.err()
+}
+
+// amountField names the field the amount was read from so violations point at what the client sent
+func amountField(amountMinor int64) string {
+	if amountMinor != 0 {
+		return "amount_minor"
+	}
+	return "amount"
+}
+
+func (s *AccountService) validateAuthorization(req *AuthorizeWithdrawalRequest) (id.Identifier, error) {
+	var v validator
+	accountId := v.identifier("account_id", req.GetAccountId())
+	v.amount("amount", req.GetAmount(), s.maxTransactionAmount())
+	v.description("description", req.GetDescription())
+
+	return accountId, v.err()
+}
+
+// validateCapture only checks the amount is positive, the hold itself caps it
+func validateCapture(req *CaptureTransactionRequest) (id.Identifier, error) {
+	var v validator
+	transactionId := v.identifier("transaction_id", req.GetTransactionId())
+	v.optionalAmount("amount", req.Amount)
+
+	return transactionId, v.err()
+}
+
+// validateReversal only checks the amount is positive, the original transaction caps it
+func validateReversal(req *ReverseTransactionRequest) (id.Identifier, error) {
+	var v validator
+	transactionId := v.identifier("transaction_id", req.GetTransactionId())
+	v.optionalAmount("amount", req.Amount)
+	v.description("description", req.GetDescription())
+
+	return transactionId, v.err()
+}
+
+func (s *AccountService) validateListTransactions(req *ListTransactionsRequest) (id.Identifier, accounts.Page, accounts.TransactionFilter, error) {
+	var (
+		v      validator
+		filter accounts.TransactionFilter
+	)
+	accountId := v.identifier("account_id", req.GetAccountId())
+	filter.From = v.timestamp("from_date", req.GetFromDate())
+	filter.To = v.timestamp("to_date", req.GetToDate())
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		v.addViolation("to_date", errors.New("to_date cannot be before from_date"))
+	}
+
+	if req.GetTransactionType() != "" {
+		var transactionType accounts.TransactionType
+		if err := transactionType.Scan(req.GetTransactionType()); err != nil {
+			v.addViolation("transaction_type", fmt.Errorf("unknown transaction type %q", req.GetTransactionType()))
+		}
+		filter.TransactionType = &transactionType
+	}
+
+	if req.GetStatus() != "" {
+		var status accounts.TransactionStatus
+		if err := status.Scan(req.GetStatus()); err != nil {
+			v.addViolation("status", fmt.Errorf("unknown status %q", req.GetStatus()))
+		}
+		filter.Status = &status
+	}
+
+	filter.MinAmount = req.MinAmount
+	filter.MaxAmount = req.MaxAmount
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		v.addViolation("max_amount", errors.New("max_amount cannot be less than min_amount"))
+	}
+
+	size, err := parsePageSize(req.GetPageSize())
+	if err != nil {
+		v.addViolation("page_size", err)
+	}
+	page := accounts.Page{Size: size}
+
+	// The cursor is signed over the account and filters, so only check it once they're valid
+	if req.GetStartCursor() != "" && len(v.violations) == 0 {
+		c, err := s.Cursors.Decode(req.GetStartCursor(), transactionsScope(accountId, filter))
+		if err != nil {
+			v.addViolation("start_cursor", err)
+		}
+
+		page.Cursor = &c.Position
+		if c.Direction == cursor.Backward {
+			page.Direction = accounts.Backward
+		}
+	}
+
+	return accountId, page, filter, v.err()
+}
+
+func (v *validator) timestamp(field string, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+
+	timestamp, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		v.addViolation(field, err)
+	}
+
+	return &timestamp
+}
+
+// transactionsScope ties a cursor to the account and filters of the request that produced it
+func transactionsScope(accountId id.Identifier, filter accounts.TransactionFilter) string {
+	var b strings.Builder
+	b.WriteString(accountId.String())
+
+	if filter.From != nil {
+		fmt.Fprintf(&b, "|from=%d", filter.From.UnixNano())
+	}
+	if filter.To != nil {
+		fmt.Fprintf(&b, "|to=%d", filter.To.UnixNano())
+	}
+	if filter.TransactionType != nil {
+		fmt.Fprintf(&b, "|type=%s", filter.TransactionType)
+	}
+	if filter.Status != nil {
+		fmt.Fprintf(&b, "|status=%s", filter.Status)
+	}
+	if filter.MinAmount != nil {
+		fmt.Fprintf(&b, "|min=%d", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		fmt.Fprintf(&b, "|max=%d", *filter.MaxAmount)
+	}
+
+	return b.String()
+}
+
+// parsePageSize returns the requested page size, or the default if it isn't set
+func parsePageSize(requested int32) (int, error) {
+	if requested > int32(maxPageSize) {
+		return 0, fmt.Errorf("page size cannot be more than %d", maxPageSize)
+	}
+	if requested <= 0 {
+		return defaultPageSize, nil
+	}
+
+	return int(requested), nil
+}
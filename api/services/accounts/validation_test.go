@@ -0,0 +1,108 @@
+package accountservice
+
+import (
+	e "chariottakehome/api/errors"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testAccountId = "c-0000000000TESTACCT"
+
+func violationFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	var requestErr e.RequestError
+	if !errors.As(err, &requestErr) {
+		t.Fatalf("Expected a RequestError, got %v", err)
+	}
+
+	fields := make([]string, 0, len(requestErr.Violations))
+	for _, v := range requestErr.Violations {
+		fields = append(fields, v.Field)
+	}
+	return fields
+}
+
+func TestValidateDepositRejectsNonPositiveAmounts(t *testing.T) {
+	s := &AccountService{}
+
+	for _, amount := range []int64{0, -500} {
+		_, _, err := s.validateDeposit(&DepositFundsRequest{AccountId: testAccountId, AmountMinor: amount})
+		fields := violationFields(t, err)
+		if len(fields) != 1 || !strings.HasPrefix(fields[0], "amount") {
+			t.Fatalf("Expected an amount violation for %d, got %v", amount, fields)
+		}
+	}
+}
+
+func TestValidateDepositMaxAmount(t *testing.T) {
+	s := &AccountService{MaxTransactionAmount: 100}
+
+	if _, _, err := s.validateDeposit(&DepositFundsRequest{AccountId: testAccountId, AmountMinor: 100}); err != nil {
+		t.Fatalf("Expected the maximum amount to be accepted, got %v", err)
+	}
+
+	_, _, err := s.validateDeposit(&DepositFundsRequest{AccountId: testAccountId, AmountMinor: 101})
+	if fields := violationFields(t, err); len(fields) != 1 || fields[0] != "amount_minor" {
+		t.Fatalf("Expected an amount_minor violation, got %v", fields)
+	}
+}
+
+func TestValidateTransferCollectsViolations(t *testing.T) {
+	s := &AccountService{}
+
+	_, _, _, err := s.validateTransfer(&AccountTransferRequest{
+		SourceAccountId:      testAccountId,
+		DestinationAccountId: testAccountId,
+		Amount:               -1,
+		Description:          strings.Repeat("x", maxDescriptionLength+1),
+	})
+
+	fields := violationFields(t, err)
+	expected := []string{"destination_account_id", "amount", "description"}
+	if len(fields) != len(expected) {
+		t.Fatalf("Expected violations %v, got %v", expected, fields)
+	}
+	for i := range expected {
+		if fields[i] != expected[i] {
+			t.Fatalf("Expected violations %v, got %v", expected, fields)
+		}
+	}
+}
+
+func TestValidateListTransactionsPageSize(t *testing.T) {
+	s := &AccountService{}
+
+	for requested, want := range map[int32]int{0: defaultPageSize, -5: defaultPageSize, 40: 40, int32(maxPageSize): maxPageSize} {
+		_, page, _, err := s.validateListTransactions(&ListTransactionsRequest{AccountId: testAccountId, PageSize: requested})
+		if err != nil || page.Size != want {
+			t.Fatalf("Expected a page size of %d for %d, got %d and %v", want, requested, page.Size, err)
+		}
+	}
+
+	_, _, _, err := s.validateListTransactions(&ListTransactionsRequest{AccountId: testAccountId, PageSize: int32(maxPageSize) + 1})
+	if fields := violationFields(t, err); len(fields) != 1 || fields[0] != "page_size" {
+		t.Fatalf("Expected a page_size violation, got %v", fields)
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	var v validator
+	negative, zero := int64(-1), int64(0)
+	limits := v.limits("limits", &Limits{HourlyTransferCount: &zero, MonthlyOutboundAmount: &negative})
+
+	if fields := violationFields(t, v.err()); len(fields) != 1 || fields[0] != "limits.monthly_outbound_amount" {
+		t.Fatalf("Expected a limits.monthly_outbound_amount violation, got %v", fields)
+	}
+	if limits.DailyWithdrawalAmount != nil || *limits.HourlyTransferCount != 0 {
+		t.Fatalf("Expected unset caps to stay off and zero caps to be kept, got %+v", limits)
+	}
+
+	v = validator{}
+	v.tierName("tier", "Gold Tier")
+	v.tierName("tier", "gold_2")
+	if fields := violationFields(t, v.err()); len(fields) != 1 {
+		t.Fatalf("Expected only the tier name with spaces and capitals to be rejected, got %v", fields)
+	}
+}
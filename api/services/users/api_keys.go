@@ -0,0 +1,123 @@
+package userservice
+
+import (
+	e "chariottakehome/api/errors"
+	"chariottakehome/internal/apikeys"
+	"chariottakehome/internal/auth"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// Matches the VARCHAR(255) name column
+const maxApiKeyNameLength int = 255
+
+// CreateApiKey issues a key for the user. The key itself is only ever returned here, and it can't
+// be given a role above the caller's own.
+func (s *UserService) CreateApiKey(ctx context.Context, req *CreateApiKeyRequest) (*ApiKey, error) {
+	userId, err := id.FromString(req.GetUserId())
+	if err != nil {
+		return nil, e.FieldError("user_id", err)
+	}
+
+	name := req.GetName()
+	if name == "" {
+		return nil, e.FieldError("name", errors.New("name is required"))
+	}
+	if utf8.RuneCountInString(name) > maxApiKeyNameLength {
+		return nil, e.FieldError("name", fmt.Errorf("name cannot be longer than %d characters", maxApiKeyNameLength))
+	}
+
+	role := auth.Owner
+	if req.GetRole() != "" {
+		if err := role.Scan(req.GetRole()); err != nil {
+			return nil, e.FieldError("role", fmt.Errorf("unknown role %q", req.GetRole()))
+		}
+	}
+
+	if err := authorizeUser(ctx, userId, auth.Write); err != nil {
+		return nil, err
+	}
+	principal, _ := auth.FromContext(ctx)
+	if err := auth.AuthorizeRole(principal, role); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Repo.GetUser(ctx, userId); err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	apiKey, key, err := s.ApiKeys.CreateApiKey(ctx, userId, name, role)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	protoKey := toProtoApiKey(apiKey)
+	protoKey.Key = key
+
+	return protoKey, nil
+}
+
+func (s *UserService) ListApiKeys(ctx context.Context, req *ListApiKeysRequest) (*ListApiKeysResponse, error) {
+	userId, err := id.FromString(req.GetUserId())
+	if err != nil {
+		return nil, e.FieldError("user_id", err)
+	}
+
+	if err := authorizeUser(ctx, userId, auth.Read); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.ApiKeys.ListApiKeys(ctx, userId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	protoKeys := make([]*ApiKey, 0, len(keys))
+	for i := range keys {
+		protoKeys = append(protoKeys, toProtoApiKey(&keys[i]))
+	}
+
+	return &ListApiKeysResponse{ApiKeys: protoKeys}, nil
+}
+
+func (s *UserService) RevokeApiKey(ctx context.Context, req *RevokeApiKeyRequest) (*ApiKey, error) {
+	keyId, err := id.FromString(req.GetApiKeyId())
+	if err != nil {
+		return nil, e.FieldError("api_key_id", err)
+	}
+
+	apiKey, err := s.ApiKeys.GetApiKey(ctx, keyId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+	if err := authorizeUser(ctx, apiKey.UserId, auth.Write); err != nil {
+		return nil, err
+	}
+
+	apiKey, err = s.ApiKeys.RevokeApiKey(ctx, keyId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	return toProtoApiKey(apiKey), nil
+}
+
+func toProtoApiKey(apiKey *apikeys.ApiKey) *ApiKey {
+	revokedAt := ""
+	if apiKey.RevokedAt != nil {
+		revokedAt = apiKey.RevokedAt.Format(time.RFC3339)
+	}
+
+	return &ApiKey{
+		Id:        apiKey.Id.String(),
+		UserId:    apiKey.UserId.String(),
+		Name:      apiKey.Name,
+		Role:      apiKey.Role.String(),
+		CreatedAt: apiKey.CreatedAt.Format(time.RFC3339),
+		RevokedAt: revokedAt,
+	}
+}
@@ -2,12 +2,24 @@ package userservice
 
 import (
 	e "chariottakehome/api/errors"
+	"chariottakehome/internal/apikeys"
+	"chariottakehome/internal/auth"
+	id "chariottakehome/internal/identifier"
 	"chariottakehome/internal/users"
+	"chariottakehome/internal/webhooks"
 	"context"
 	"errors"
 	"fmt"
 	"regexp"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultPageSize int = 15
+	// maxPageSize bounds how many rows a list call reads at once
+	maxPageSize int = 100
 )
 
 // not tested exhaustively, but catches most cases
@@ -21,27 +33,132 @@ func init() {
 
 type UserService struct {
 	UnimplementedUserServiceServer
-	Repo users.UserRepository
+	Repo     users.UserRepository
+	Webhooks webhooks.WebhookRepository
+	ApiKeys  apikeys.ApiKeyRepository
+	// AllowHttpWebhooks accepts plain http webhook urls, for development
+	AllowHttpWebhooks bool
 }
 
+// CreateUser is only open to operators. Its AlreadyExists error would tell anyone else whether
+// an email is registered, which GetUserByEmail hides.
 func (s *UserService) CreateUser(ctx context.Context, req *CreateUserRequest) (*User, error) {
+	principal, _ := auth.FromContext(ctx)
+	if err := auth.AuthorizeRole(principal, auth.Operator); err != nil {
+		return nil, err
+	}
+
 	reqEmail := req.GetEmail()
 
 	if !emailRegex.MatchString(reqEmail) {
-		return nil, e.RequestError{Err: errors.New("invalid email address")}
+		return nil, e.FieldError("email", errors.New("invalid email address"))
 	}
 
 	user, err := s.Repo.CreateUser(ctx, req.Email)
 	if err != nil {
-		fmt.Println(err)
-		// TODO: Better error handling and returning more useful error messages to the client.
-		// Example: duplicate emails.
-		return nil, e.ApiError{Err: e.Internal}
+		// Duplicate emails are reported to the client as AlreadyExists
+		return nil, e.ApiError{Err: err}
+	}
+
+	return toProtoUser(user), nil
+}
+
+func (s *UserService) GetUser(ctx context.Context, req *GetUserRequest) (*User, error) {
+	userId, err := id.FromString(req.GetUserId())
+	if err != nil {
+		return nil, e.FieldError("user_id", err)
+	}
+
+	if err := authorizeUser(ctx, userId, auth.Read); err != nil {
+		return nil, err
+	}
+
+	user, err := s.Repo.GetUser(ctx, userId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
 	}
 
 	return toProtoUser(user), nil
 }
 
+func (s *UserService) GetUserByEmail(ctx context.Context, req *GetUserByEmailRequest) (*User, error) {
+	reqEmail := req.GetEmail()
+
+	if !emailRegex.MatchString(reqEmail) {
+		return nil, e.FieldError("email", errors.New("invalid email address"))
+	}
+
+	principal, _ := auth.FromContext(ctx)
+	if err := auth.AuthorizeRole(principal, auth.Viewer); err != nil {
+		return nil, err
+	}
+
+	user, err := s.Repo.GetUserByEmail(ctx, reqEmail)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	// Someone else's user is reported as not found, so callers can't find out which emails
+	// are registered
+	if err := authorizeUser(ctx, user.Id, auth.Read); err != nil {
+		if errors.Is(err, auth.ErrNotOwner) {
+			return nil, e.ApiError{Err: pgx.ErrNoRows}
+		}
+		return nil, err
+	}
+
+	return toProtoUser(user), nil
+}
+
+func (s *UserService) ListUsers(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error) {
+	principal, _ := auth.FromContext(ctx)
+	if err := auth.AuthorizeRole(principal, auth.Operator); err != nil {
+		return nil, err
+	}
+
+	var startCursor *id.Identifier
+	startCursorStr := req.GetStartCursor()
+	if startCursorStr != "" {
+		id, err := id.FromString(startCursorStr)
+		if err != nil {
+			return nil, e.FieldError("start_cursor", err)
+		}
+
+		startCursor = &id
+	}
+
+	pageSize, err := parsePageSize(req.GetPageSize())
+	if err != nil {
+		return nil, e.FieldError("page_size", err)
+	}
+
+	resp, err := s.Repo.ListUsers(ctx, startCursor, pageSize)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	protoUsers := make([]*User, 0)
+	for i := 0; i < len(resp.Users); i++ {
+		protoUsers = append(protoUsers, toProtoUser(&resp.Users[i]))
+	}
+
+	nextCursor := ""
+	if resp.NextCursor != nil {
+		nextCursor = resp.NextCursor.String()
+	}
+
+	return &ListUsersResponse{
+		Users:      protoUsers,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// authorizeUser checks the caller may take action on the user's resources
+func authorizeUser(ctx context.Context, userId id.Identifier, action auth.Action) error {
+	principal, _ := auth.FromContext(ctx)
+	return auth.Authorize(principal, userId, action)
+}
+
 func toProtoUser(user *users.User) *User {
 	return &User{
 		Id:        user.Id.String(),
@@ -50,3 +167,15 @@ func toProtoUser(user *users.User) *User {
 		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
 	}
 }
+
+// parsePageSize returns the requested page size, or the default if it isn't set
+func parsePageSize(requested int32) (int, error) {
+	if requested > int32(maxPageSize) {
+		return 0, fmt.Errorf("page size cannot be more than %d", maxPageSize)
+	}
+	if requested <= 0 {
+		return defaultPageSize, nil
+	}
+
+	return int(requested), nil
+}
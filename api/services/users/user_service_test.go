@@ -0,0 +1,29 @@
+package userservice
+
+import (
+	"chariottakehome/internal/auth"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCreateUserIsOperatorOnly(t *testing.T) {
+	userId, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+
+	// The repository is never reached, so a registered email can't be told from a new one
+	s := &UserService{}
+	req := &CreateUserRequest{Email: "someone@example.com"}
+
+	if _, err := s.CreateUser(context.Background(), req); !errors.Is(err, auth.ErrNotOwner) {
+		t.Fatalf("Expected an unauthenticated call to be refused, got %v", err)
+	}
+
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: userId, Role: auth.Owner})
+	if _, err := s.CreateUser(ctx, req); !errors.Is(err, auth.ErrOperatorOnly) {
+		t.Fatalf("Expected an owner to be refused, got %v", err)
+	}
+}
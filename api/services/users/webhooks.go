@@ -0,0 +1,237 @@
+package userservice
+
+import (
+	e "chariottakehome/api/errors"
+	"chariottakehome/internal/auth"
+	id "chariottakehome/internal/identifier"
+	"chariottakehome/internal/webhooks"
+	"chariottakehome/internal/webhooks/delivery"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Matches the VARCHAR(2048) url column
+const maxWebhookUrlLength int = 2048
+
+// CreateWebhookEndpoint registers a url to receive events for all of the user's accounts. The
+// signing secret is only ever returned here.
+func (s *UserService) CreateWebhookEndpoint(ctx context.Context, req *CreateWebhookEndpointRequest) (*WebhookEndpoint, error) {
+	userId, err := id.FromString(req.GetUserId())
+	if err != nil {
+		return nil, e.FieldError("user_id", err)
+	}
+
+	if err := s.validateWebhookUrl(ctx, req.GetUrl()); err != nil {
+		return nil, e.FieldError("url", err)
+	}
+
+	if err := authorizeUser(ctx, userId, auth.Write); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Repo.GetUser(ctx, userId); err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	endpoint, err := s.Webhooks.CreateEndpoint(ctx, userId, req.GetUrl())
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	protoEndpoint := toProtoWebhookEndpoint(endpoint)
+	protoEndpoint.Secret = endpoint.Secret
+
+	return protoEndpoint, nil
+}
+
+func (s *UserService) ListWebhookEndpoints(ctx context.Context, req *ListWebhookEndpointsRequest) (*ListWebhookEndpointsResponse, error) {
+	userId, err := id.FromString(req.GetUserId())
+	if err != nil {
+		return nil, e.FieldError("user_id", err)
+	}
+
+	if err := authorizeUser(ctx, userId, auth.Read); err != nil {
+		return nil, err
+	}
+
+	endpoints, err := s.Webhooks.ListEndpoints(ctx, userId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	protoEndpoints := make([]*WebhookEndpoint, 0, len(endpoints))
+	for i := range endpoints {
+		protoEndpoints = append(protoEndpoints, toProtoWebhookEndpoint(&endpoints[i]))
+	}
+
+	return &ListWebhookEndpointsResponse{Endpoints: protoEndpoints}, nil
+}
+
+// DeleteWebhookEndpoint disables the endpoint. It's kept, along with its delivery log, but
+// nothing more is sent to it.
+func (s *UserService) DeleteWebhookEndpoint(ctx context.Context, req *DeleteWebhookEndpointRequest) (*WebhookEndpoint, error) {
+	endpointId, err := id.FromString(req.GetEndpointId())
+	if err != nil {
+		return nil, e.FieldError("endpoint_id", err)
+	}
+
+	if err := s.authorizeEndpoint(ctx, endpointId, auth.Write); err != nil {
+		return nil, err
+	}
+
+	endpoint, err := s.Webhooks.DisableEndpoint(ctx, endpointId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	return toProtoWebhookEndpoint(endpoint), nil
+}
+
+func (s *UserService) ListWebhookDeliveries(ctx context.Context, req *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error) {
+	endpointId, err := id.FromString(req.GetEndpointId())
+	if err != nil {
+		return nil, e.FieldError("endpoint_id", err)
+	}
+
+	var status *webhooks.DeliveryStatus
+	if req.GetStatus() != "" {
+		var deliveryStatus webhooks.DeliveryStatus
+		if err := deliveryStatus.Scan(req.GetStatus()); err != nil {
+			return nil, e.FieldError("status", fmt.Errorf("unknown status %q", req.GetStatus()))
+		}
+		status = &deliveryStatus
+	}
+
+	var startCursor *id.Identifier
+	startCursorStr := req.GetStartCursor()
+	if startCursorStr != "" {
+		id, err := id.FromString(startCursorStr)
+		if err != nil {
+			return nil, e.FieldError("start_cursor", err)
+		}
+
+		startCursor = &id
+	}
+
+	pageSize, err := parsePageSize(req.GetPageSize())
+	if err != nil {
+		return nil, e.FieldError("page_size", err)
+	}
+
+	// An unknown endpoint is NotFound rather than an empty log
+	if err := s.authorizeEndpoint(ctx, endpointId, auth.Read); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Webhooks.ListDeliveries(ctx, endpointId, status, startCursor, pageSize)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	protoDeliveries := make([]*WebhookDelivery, 0, len(resp.Deliveries))
+	for i := range resp.Deliveries {
+		protoDeliveries = append(protoDeliveries, toProtoWebhookDelivery(&resp.Deliveries[i]))
+	}
+
+	nextCursor := ""
+	if resp.NextCursor != nil {
+		nextCursor = resp.NextCursor.String()
+	}
+
+	return &ListWebhookDeliveriesResponse{
+		Deliveries: protoDeliveries,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// ReplayWebhookDelivery sends a delivery again, whether it succeeded or was dead-lettered
+func (s *UserService) ReplayWebhookDelivery(ctx context.Context, req *ReplayWebhookDeliveryRequest) (*WebhookDelivery, error) {
+	deliveryId, err := id.FromString(req.GetDeliveryId())
+	if err != nil {
+		return nil, e.FieldError("delivery_id", err)
+	}
+
+	delivery, err := s.Webhooks.GetDelivery(ctx, deliveryId)
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+	if err := s.authorizeEndpoint(ctx, delivery.EndpointId, auth.Write); err != nil {
+		return nil, err
+	}
+
+	delivery, err = s.Webhooks.ReplayDelivery(ctx, deliveryId)
+	if errors.Is(err, webhooks.ErrEndpointDisabled) {
+		return nil, e.PreconditionError{Err: err}
+	}
+	if err != nil {
+		return nil, e.ApiError{Err: err}
+	}
+
+	return toProtoWebhookDelivery(delivery), nil
+}
+
+// authorizeEndpoint checks the caller may take action on the endpoint's user's webhooks
+func (s *UserService) authorizeEndpoint(ctx context.Context, endpointId id.Identifier, action auth.Action) error {
+	endpoint, err := s.Webhooks.GetEndpoint(ctx, endpointId)
+	if err != nil {
+		return e.ApiError{Err: err}
+	}
+
+	return authorizeUser(ctx, endpoint.UserId, action)
+}
+
+// validateWebhookUrl only accepts https urls, or http ones in development, whose host is outside
+// our own network
+func (s *UserService) validateWebhookUrl(ctx context.Context, rawUrl string) error {
+	if len(rawUrl) > maxWebhookUrlLength {
+		return fmt.Errorf("url cannot be longer than %d characters", maxWebhookUrlLength)
+	}
+
+	return delivery.CheckUrl(ctx, rawUrl, s.AllowHttpWebhooks)
+}
+
+func toProtoWebhookEndpoint(endpoint *webhooks.Endpoint) *WebhookEndpoint {
+	disabledAt := ""
+	if endpoint.DisabledAt != nil {
+		disabledAt = endpoint.DisabledAt.Format(time.RFC3339)
+	}
+
+	return &WebhookEndpoint{
+		Id:         endpoint.Id.String(),
+		UserId:     endpoint.UserId.String(),
+		Url:        endpoint.Url,
+		CreatedAt:  endpoint.CreatedAt.Format(time.RFC3339),
+		DisabledAt: disabledAt,
+	}
+}
+
+func toProtoWebhookDelivery(delivery *webhooks.Delivery) *WebhookDelivery {
+	lastAttemptAt := ""
+	if delivery.LastAttemptAt != nil {
+		lastAttemptAt = delivery.LastAttemptAt.Format(time.RFC3339)
+	}
+	var lastStatusCode int32
+	if delivery.LastStatusCode != nil {
+		lastStatusCode = int32(*delivery.LastStatusCode)
+	}
+	lastError := ""
+	if delivery.LastError != nil {
+		lastError = *delivery.LastError
+	}
+
+	return &WebhookDelivery{
+		Id:             delivery.Id.String(),
+		EndpointId:     delivery.EndpointId.String(),
+		EventId:        delivery.EventId.String(),
+		EventType:      delivery.EventType,
+		Status:         delivery.Status.String(),
+		Attempts:       int32(delivery.Attempts),
+		NextAttemptAt:  delivery.NextAttemptAt.Format(time.RFC3339),
+		LastAttemptAt:  lastAttemptAt,
+		LastStatusCode: lastStatusCode,
+		LastError:      lastError,
+		CreatedAt:      delivery.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,63 @@
+package accounts
+
+type errReason string
+
+const (
+	IdempotencyConflict errReason = "Idempotency key was already used for a different request."
+	InsufficientFunds   errReason = "Insufficient funds."
+	UnbalancedEntry     errReason = "Journal entry legs don't sum to zero."
+	SystemAccount       errReason = "System accounts can't be used directly."
+	CurrencyMismatch    errReason = "Accounts are held in different currencies."
+	NotAHold            errReason = "Transaction isn't an authorization hold."
+	HoldNotPending      errReason = "Hold has already been captured, voided, or expired."
+	HoldExpired         errReason = "Hold has expired."
+	CaptureExceedsHold  errReason = "Capture amount exceeds the authorized amount."
+	NotReversible       errReason = "Only completed transactions that aren't reversals can be reversed."
+	AlreadyReversed     errReason = "Transaction has already been fully reversed."
+	ReversalExceeds     errReason = "Reversal amount exceeds what's left of the original amount."
+	DailyWithdrawal     errReason = "Daily withdrawal limit exceeded."
+	HourlyTransfers     errReason = "Hourly transfer limit exceeded."
+	MonthlyOutbound     errReason = "Monthly outbound limit exceeded."
+)
+
+type TransactionError struct {
+	reason errReason
+}
+
+func (e TransactionError) Error() string {
+	return "Transaction rejected: " + string(e.reason)
+}
+
+var (
+	ErrIdempotencyConflict     = TransactionError{reason: IdempotencyConflict}
+	ErrInsufficientFunds       = TransactionError{reason: InsufficientFunds}
+	ErrUnbalancedJournalEntry  = TransactionError{reason: UnbalancedEntry}
+	ErrSystemAccount           = TransactionError{reason: SystemAccount}
+	ErrCurrencyMismatch        = TransactionError{reason: CurrencyMismatch}
+	ErrNotAHold                = TransactionError{reason: NotAHold}
+	ErrHoldNotPending          = TransactionError{reason: HoldNotPending}
+	ErrHoldExpired             = TransactionError{reason: HoldExpired}
+	ErrCaptureExceedsHold      = TransactionError{reason: CaptureExceedsHold}
+	ErrNotReversible           = TransactionError{reason: NotReversible}
+	ErrAlreadyReversed         = TransactionError{reason: AlreadyReversed}
+	ErrReversalExceedsOriginal = TransactionError{reason: ReversalExceeds}
+	ErrDailyWithdrawalLimit    = TransactionError{reason: DailyWithdrawal}
+	ErrHourlyTransferLimit     = TransactionError{reason: HourlyTransfers}
+	ErrMonthlyOutboundLimit    = TransactionError{reason: MonthlyOutbound}
+)
+
+const (
+	LastAdmin errReason = "Accounts must keep at least one admin member."
+)
+
+type MemberError struct {
+	reason errReason
+}
+
+func (e MemberError) Error() string {
+	return "Membership change rejected: " + string(e.reason)
+}
+
+var (
+	ErrLastAdmin = MemberError{reason: LastAdmin}
+)
@@ -0,0 +1,102 @@
+package accounts
+
+import (
+	"chariottakehome/internal/events"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type accountPayload struct {
+	Id             string    `json:"id"`
+	UserId         string    `json:"user_id"`
+	Name           string    `json:"name"`
+	Currency       string    `json:"currency"`
+	OverdraftLimit int64     `json:"overdraft_limit"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type transactionPayload struct {
+	Id              string    `json:"id"`
+	AccountId       string    `json:"account_id"`
+	Amount          int64     `json:"amount"`
+	Currency        string    `json:"currency"`
+	TransactionType string    `json:"transaction_type"`
+	TransactionDate time.Time `json:"transaction_date"`
+	Description     *string   `json:"description,omitempty"`
+	JournalEntryId  string    `json:"journal_entry_id,omitempty"`
+	ReversalOf      string    `json:"reversal_of,omitempty"`
+}
+
+type transferPayload struct {
+	Source      transactionPayload `json:"source"`
+	Destination transactionPayload `json:"destination"`
+}
+
+type reversalPayload struct {
+	JournalEntryId string               `json:"journal_entry_id"`
+	Legs           []transactionPayload `json:"legs"`
+}
+
+func newTransactionPayload(t Transaction) transactionPayload {
+	payload := transactionPayload{
+		Id:              t.Id.String(),
+		AccountId:       t.AccountId.String(),
+		Amount:          t.Amount,
+		Currency:        t.Currency.Code(),
+		TransactionType: t.TransactionType.String(),
+		TransactionDate: t.TransactionDate,
+		Description:     t.Description,
+	}
+	if t.JournalEntryId != nil {
+		payload.JournalEntryId = t.JournalEntryId.String()
+	}
+	if t.ReversalOf != nil {
+		payload.ReversalOf = t.ReversalOf.String()
+	}
+
+	return payload
+}
+
+func txAppendAccountCreated(ctx context.Context, tx pgx.Tx, a Account) error {
+	return events.TxAppend(ctx, tx, events.AccountCreated, []id.Identifier{a.Id}, accountPayload{
+		Id:             a.Id.String(),
+		UserId:         a.UserId.String(),
+		Name:           a.Name,
+		Currency:       a.Currency.Code(),
+		OverdraftLimit: a.OverdraftLimit,
+		CreatedAt:      a.CreatedAt,
+	})
+}
+
+// txAppendTransactionEvent records a deposit or withdrawal from its customer leg
+func txAppendTransactionEvent(ctx context.Context, tx pgx.Tx, eventType events.EventType, t Transaction) error {
+	return events.TxAppend(ctx, tx, eventType, []id.Identifier{t.AccountId}, newTransactionPayload(t))
+}
+
+func txAppendTransferCompleted(ctx context.Context, tx pgx.Tx, source, dest Transaction) error {
+	return events.TxAppend(ctx, tx, events.TransferCompleted, []id.Identifier{source.AccountId, dest.AccountId}, transferPayload{
+		Source:      newTransactionPayload(source),
+		Destination: newTransactionPayload(dest),
+	})
+}
+
+// txAppendTransactionReversed records a reversal against every customer account it touched.
+// System account legs are included in the payload so it still balances.
+func txAppendTransactionReversed(ctx context.Context, tx pgx.Tx, entry JournalEntry) error {
+	accountIds := make([]id.Identifier, 0, len(entry.Legs))
+	legs := make([]transactionPayload, 0, len(entry.Legs))
+	for _, leg := range entry.Legs {
+		if !IsSystemAccount(leg.AccountId) {
+			accountIds = append(accountIds, leg.AccountId)
+		}
+		legs = append(legs, newTransactionPayload(leg))
+	}
+
+	return events.TxAppend(ctx, tx, events.TransactionReversed, accountIds, reversalPayload{
+		JournalEntryId: entry.Id.String(),
+		Legs:           legs,
+	})
+}
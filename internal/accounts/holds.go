@@ -0,0 +1,268 @@
+package accounts
+
+import (
+	"chariottakehome/internal/database"
+	"chariottakehome/internal/events"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AuthorizeWithdrawal places a hold on amount of the account's available balance until expiresAt.
+// The hold is recorded as a pending debit and doesn't touch the ledger until it's captured.
+func (r *accountRepository) AuthorizeWithdrawal(ctx context.Context, accountId id.Identifier, amount int64, description string, idempotencyKey string, expiresAt time.Time) (*Transaction, error) {
+	db := r.database
+	key := resolveIdempotencyKey(idempotencyKey, accountId, amount, Debit)
+	replayed, err := replayHold(ctx, db, key, accountId, amount, description)
+	if err != nil || replayed != nil {
+		return replayed, err
+	}
+
+	currency, err := accountCurrency(ctx, db, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := newLeg(key, accountId, amount, currency, Debit, description)
+	if err != nil {
+		return nil, err
+	}
+	hold.Status = Pending
+	hold.TransactionDate = time.Now().UTC()
+	hold.AuthorizedAmount = &amount
+	expiresAt = expiresAt.UTC()
+	hold.ExpiresAt = &expiresAt
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = txPlaceHold(ctx, tx, accountId, currency, amount)
+	if errors.Is(err, ErrInsufficientFunds) {
+		tx.Rollback(ctx)
+		return nil, recordFailedTransaction(ctx, db, err, accountId, amount, currency, Debit, description)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sql, args := prepareInsertTransaction(hold)
+	_, err = tx.Exec(ctx, sql, args...)
+	if isUniqueViolation(err) {
+		return requireReplay(replayHold(ctx, db, key, accountId, amount, description))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert hold: %w", err)
+	}
+
+	// Holds count towards the withdrawal limits when they're placed, so capturing one is never
+	// refused for going over
+	err = txCheckLimits(ctx, tx, accountId, hold.TransactionDate)
+	if isDeclined(err) {
+		tx.Rollback(ctx)
+		return nil, recordFailedTransaction(ctx, db, err, accountId, amount, currency, Debit, description)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &hold, nil
+}
+
+// CaptureTransaction settles a hold, debiting amount (or the full authorized amount if nil) from
+// the account and releasing the rest. Capturing an already captured hold for the same amount
+// returns it unchanged.
+func (r *accountRepository) CaptureTransaction(ctx context.Context, transactionId id.Identifier, amount *int64) (*Transaction, error) {
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := txLockHold(ctx, tx, transactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	authorized := *hold.AuthorizedAmount
+	captured := authorized
+	if amount != nil {
+		captured = *amount
+	}
+
+	replay, err := checkCapture(hold, captured)
+	if err != nil {
+		return nil, err
+	}
+	if replay {
+		return hold, nil
+	}
+
+	hold.Amount = captured
+	hold.Status = Complete
+
+	cashOutAccountId := systemAccountId(cashOut, hold.Currency)
+	cashOut, err := newLeg(systemLegKey(*hold, cashOutAccountId), cashOutAccountId, captured, hold.Currency, Credit, *hold.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := newJournalEntry(*hold.Description, *hold, cashOut)
+	if err != nil {
+		return nil, err
+	}
+
+	err = txPostCapture(ctx, tx, entry, authorized)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = txAppendTransactionEvent(ctx, tx, events.FundsWithdrawn, entry.Legs[0]); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	recordMovement(captureMovement, entry.Legs[0])
+	return &entry.Legs[0], nil
+}
+
+// VoidTransaction releases a hold without moving any money. Voiding an already voided or expired
+// hold returns it unchanged.
+func (r *accountRepository) VoidTransaction(ctx context.Context, transactionId id.Identifier) (*Transaction, error) {
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := txLockHold(ctx, tx, transactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	replay, err := checkVoid(hold)
+	if err != nil {
+		return nil, err
+	}
+	if replay {
+		return hold, nil
+	}
+
+	err = txSettleHold(ctx, tx, hold.AccountId, hold.Currency, *hold.AuthorizedAmount, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	hold.Status = Failed
+	_, err = tx.Exec(ctx, `UPDATE transactions SET status = $1 WHERE id = $2`, hold.Status.String(), hold.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update hold: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return hold, nil
+}
+
+// ExpireHolds fails every pending hold that lapsed before now and releases it from its account,
+// returning how many accounts had holds released
+func (r *accountRepository) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := r.database.Exec(ctx, expiredHoldsRelease, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func txLockHold(ctx context.Context, tx pgx.Tx, transactionId id.Identifier) (*Transaction, error) {
+	var t Transaction
+	row := tx.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE id = $1 FOR UPDATE`, transactionId)
+	if err := scanTransaction(row, &t); err != nil {
+		return nil, err
+	}
+
+	if !t.isHold() {
+		return nil, ErrNotAHold
+	}
+
+	return &t, nil
+}
+
+// checkHoldPending rejects holds that have been settled, or lapsed even if not yet swept
+func checkHoldPending(hold *Transaction) error {
+	if hold.Status != Pending {
+		return ErrHoldNotPending
+	}
+	if !hold.ExpiresAt.After(time.Now().UTC()) {
+		return ErrHoldExpired
+	}
+
+	return nil
+}
+
+// checkCapture reports whether capturing captured from hold repeats a capture that already
+// happened, or why the hold can't be captured
+func checkCapture(hold *Transaction, captured int64) (bool, error) {
+	if hold.Status == Complete && hold.Amount == captured {
+		return true, nil
+	}
+	if err := checkHoldPending(hold); err != nil {
+		return false, err
+	}
+	if captured > *hold.AuthorizedAmount {
+		return false, ErrCaptureExceedsHold
+	}
+
+	return false, nil
+}
+
+// checkVoid reports whether voiding hold repeats a void that already happened, or why the hold
+// can't be voided
+func checkVoid(hold *Transaction) (bool, error) {
+	// A lapsed hold that hasn't been swept yet can still be voided, with the same result
+	if hold.Status == Failed {
+		return true, nil
+	}
+	if hold.Status != Pending {
+		return false, ErrHoldNotPending
+	}
+
+	return false, nil
+}
+
+// replayHold returns the hold stored under key if it was authorized with the same payload
+func replayHold(ctx context.Context, db *database.DatabasePool, key string, accountId id.Identifier, amount int64, description string) (*Transaction, error) {
+	existing, err := findTransactionByIdempotencyKey(ctx, db, accountId, key)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+
+	if !existing.isHold() {
+		return nil, ErrIdempotencyConflict
+	}
+
+	// A captured hold's amount may have changed, so compare what was authorized
+	authorized := *existing
+	authorized.Amount = *existing.AuthorizedAmount
+	if !authorized.matches(accountId, amount, Debit, description) {
+		return nil, ErrIdempotencyConflict
+	}
+
+	return existing, nil
+}
@@ -0,0 +1,86 @@
+package accounts
+
+import (
+	id "chariottakehome/internal/identifier"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithdrawalDoesNotReplayHold(t *testing.T) {
+	accountId, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+
+	description := "rent"
+	amount := int64(500)
+	withdrawal := Transaction{AccountId: accountId, Amount: amount, TransactionType: Debit, Status: Complete, Description: &description}
+	if !withdrawal.replays(accountId, amount, Debit, description) {
+		t.Fatal("Expected a withdrawal with the same payload to be replayed")
+	}
+
+	// A hold under the same key and payload must not come back as a completed withdrawal
+	hold := withdrawal
+	hold.Status = Pending
+	hold.AuthorizedAmount = &amount
+	if hold.replays(accountId, amount, Debit, description) {
+		t.Fatal("Expected a withdrawal reusing a hold's key to conflict")
+	}
+}
+
+func TestCheckCapture(t *testing.T) {
+	authorized := int64(500)
+	future := time.Now().UTC().Add(time.Hour)
+	past := time.Now().UTC().Add(-time.Minute)
+
+	cases := []struct {
+		status    TransactionStatus
+		amount    int64
+		expiresAt time.Time
+		captured  int64
+		replay    bool
+		err       error
+	}{
+		{Pending, authorized, future, 500, false, nil},
+		// A hold can be captured for less than was authorized, but not more
+		{Pending, authorized, future, 300, false, nil},
+		{Pending, authorized, future, 501, false, ErrCaptureExceedsHold},
+		// A lapsed hold can't be captured even before it's swept
+		{Pending, authorized, past, 500, false, ErrHoldExpired},
+		// Capturing again for the same amount returns the capture, any other amount is refused
+		{Complete, 300, future, 300, true, nil},
+		{Complete, 300, future, 500, false, ErrHoldNotPending},
+		{Failed, authorized, future, 500, false, ErrHoldNotPending},
+	}
+
+	for _, c := range cases {
+		hold := Transaction{Amount: c.amount, TransactionType: Debit, Status: c.status, AuthorizedAmount: &authorized, ExpiresAt: &c.expiresAt}
+		replay, err := checkCapture(&hold, c.captured)
+		if replay != c.replay || !errors.Is(err, c.err) {
+			t.Fatalf("Expected (%t, %v) capturing %+v, got (%t, %v)", c.replay, c.err, c, replay, err)
+		}
+	}
+}
+
+func TestCheckVoid(t *testing.T) {
+	cases := []struct {
+		status TransactionStatus
+		replay bool
+		err    error
+	}{
+		{Pending, false, nil},
+		// Voided and expired holds both end up failed, so voiding them again is a replay
+		{Failed, true, nil},
+		{Complete, false, ErrHoldNotPending},
+	}
+
+	for _, c := range cases {
+		authorized := int64(500)
+		hold := Transaction{Amount: authorized, TransactionType: Debit, Status: c.status, AuthorizedAmount: &authorized}
+		replay, err := checkVoid(&hold)
+		if replay != c.replay || !errors.Is(err, c.err) {
+			t.Fatalf("Expected (%t, %v) voiding a %s hold, got (%t, %v)", c.replay, c.err, c.status, replay, err)
+		}
+	}
+}
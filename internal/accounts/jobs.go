@@ -0,0 +1,63 @@
+package accounts
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	snapshotPeriod time.Duration = 24 * time.Hour
+
+	// Transactions are dated before they commit, so a period is only checkpointed once any
+	// transaction dated inside it has had time to land
+	snapshotGracePeriod time.Duration = time.Hour
+)
+
+// snapshotCutoff is the end of the latest daily period that's safe to checkpoint at now
+func snapshotCutoff(now time.Time) time.Time {
+	return now.UTC().Add(-snapshotGracePeriod).Truncate(snapshotPeriod)
+}
+
+// RunBalanceSnapshots takes daily balance snapshots, checking every interval until ctx is done.
+// Snapshots are keyed by account and time, so it's safe to run on several instances.
+func RunBalanceSnapshots(ctx context.Context, repo AccountRepository, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		cutoff := snapshotCutoff(time.Now())
+		count, err := repo.SnapshotBalances(ctx, cutoff)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to snapshot balances", "cutoff", cutoff.Format(time.RFC3339), "error", err)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "snapshotted balances", "count", count, "cutoff", cutoff.Format(time.RFC3339))
+		}
+	})
+}
+
+// RunHoldExpiry releases lapsed holds every interval until ctx is done. Captures already reject
+// expired holds, so this only has to keep available balances from lagging too far behind.
+func RunHoldExpiry(ctx context.Context, repo AccountRepository, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		count, err := repo.ExpireHolds(ctx, time.Now())
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to expire holds", "error", err)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "released expired holds", "accounts", count)
+		}
+	})
+}
+
+// runEvery runs job straight away and then every interval until ctx is done
+func runEvery(ctx context.Context, interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
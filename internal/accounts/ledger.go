@@ -0,0 +1,218 @@
+package accounts
+
+import (
+	id "chariottakehome/internal/identifier"
+	"chariottakehome/internal/money"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// System accounts mirror money entering and leaving the ledger, so deposits and withdrawals
+// balance like any other journal entry. Each currency has its own pair: the USD pair is created
+// by the journal entries migration and the rest are created the first time they're posted to.
+var (
+	SystemUserId     = mustIdentifier("c-0000000000SYSTEM00")
+	CashInAccountId  = mustIdentifier("c-00000000CASHIN0000")
+	CashOutAccountId = mustIdentifier("c-0000000CASHOUT0000")
+)
+
+type systemAccountKind string
+
+const (
+	cashIn  systemAccountKind = "CASHIN"
+	cashOut systemAccountKind = "CASHOUT"
+)
+
+var systemAccountNames = map[systemAccountKind]string{
+	cashIn:  "Cash in",
+	cashOut: "Cash out",
+}
+
+// systemAccountId returns the system account of the given kind for a currency. Apart from the
+// original USD pair the ids embed the currency code, e.g. c-00000CASHINEUR0000
+func systemAccountId(kind systemAccountKind, currency money.Currency) id.Identifier {
+	if currency == money.USD {
+		if kind == cashIn {
+			return CashInAccountId
+		}
+		return CashOutAccountId
+	}
+
+	body := string(kind) + currency.Code() + "0000"
+	return mustIdentifier("c-" + strings.Repeat("0", 18-len(body)) + body)
+}
+
+// parseSystemAccount reverses systemAccountId
+func parseSystemAccount(accountId id.Identifier) (systemAccountKind, money.Currency, bool) {
+	switch accountId {
+	case CashInAccountId:
+		return cashIn, money.USD, true
+	case CashOutAccountId:
+		return cashOut, money.USD, true
+	}
+
+	body := strings.TrimLeft(strings.TrimPrefix(accountId.String(), "c-"), "0")
+	for kind := range systemAccountNames {
+		code, found := strings.CutPrefix(body, string(kind))
+		if !found || len(code) != 7 {
+			continue
+		}
+
+		currency, err := money.ParseCurrency(code[:3])
+		if err == nil && systemAccountId(kind, currency) == accountId {
+			return kind, currency, true
+		}
+	}
+
+	return "", money.Currency{}, false
+}
+
+func mustIdentifier(s string) id.Identifier {
+	identifier, err := id.FromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return identifier
+}
+
+func IsSystemAccount(accountId id.Identifier) bool {
+	_, _, ok := parseSystemAccount(accountId)
+	return ok
+}
+
+func systemAccountName(accountId id.Identifier) string {
+	kind, currency, _ := parseSystemAccount(accountId)
+	return systemAccountNames[kind] + " (" + currency.Code() + ")"
+}
+
+func newLeg(idempotencyKey string, accountId id.Identifier, amount int64, currency money.Currency, transType TransactionType, description string) (Transaction, error) {
+	id, err := id.New()
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	return Transaction{
+		Id:              id,
+		IdempotencyKey:  idempotencyKey,
+		AccountId:       accountId,
+		Amount:          amount,
+		Currency:        currency,
+		TransactionType: transType,
+		Status:          Complete,
+		Description:     &description,
+	}, nil
+}
+
+// systemLegKey derives the idempotency key of a system account leg from the customer leg it
+// balances. Customers' keys are only unique within their account, so the account is part of it.
+func systemLegKey(leg Transaction, systemAccountId id.Identifier) string {
+	return deriveIdempotencyKey(leg.IdempotencyKey, leg.AccountId.String(), systemAccountId.String())
+}
+
+func newJournalEntry(description string, legs ...Transaction) (JournalEntry, error) {
+	entryId, err := id.New()
+	if err != nil {
+		return JournalEntry{}, err
+	}
+
+	now := time.Now().UTC()
+	for i := range legs {
+		legs[i].JournalEntryId = &entryId
+		legs[i].TransactionDate = now
+	}
+
+	return JournalEntry{
+		Id:          entryId,
+		Description: &description,
+		CreatedAt:   now,
+		Legs:        legs,
+	}, nil
+}
+
+// txPostJournalEntry records the entry and applies each of its legs to the account balances.
+// Unbalanced entries are rejected before anything is written. The customer accounts are locked
+// up front in id order, so transfers between the same accounts in opposite directions queue
+// behind each other rather than deadlocking.
+func txPostJournalEntry(ctx context.Context, tx pgx.Tx, entry JournalEntry) error {
+	if !entry.balanced() {
+		return ErrUnbalancedJournalEntry
+	}
+
+	err := txLockAccounts(ctx, tx, entry.customerAccountIds())
+	if err != nil {
+		return err
+	}
+
+	sql, args := prepareInsertJournalEntry(entry)
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+
+	for _, leg := range entry.Legs {
+		if err := txPostLeg(ctx, tx, leg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// txPostCapture records the entry settling a hold of authorized. Its first leg is the hold
+// itself, which is updated in place to the captured amount rather than inserted.
+func txPostCapture(ctx context.Context, tx pgx.Tx, entry JournalEntry, authorized int64) error {
+	if !entry.balanced() {
+		return ErrUnbalancedJournalEntry
+	}
+
+	sql, args := prepareInsertJournalEntry(entry)
+	_, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+
+	hold := entry.Legs[0]
+	err = txSettleHold(ctx, tx, hold.AccountId, hold.Currency, authorized, hold.Amount)
+	if err != nil {
+		return err
+	}
+
+	sql, args = prepareCaptureHold(hold)
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update hold: %w", err)
+	}
+
+	for _, leg := range entry.Legs[1:] {
+		if err := txPostLeg(ctx, tx, leg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// txPostLeg applies a leg to its account's balance and inserts it
+func txPostLeg(ctx context.Context, tx pgx.Tx, leg Transaction) error {
+	var err error
+	if IsSystemAccount(leg.AccountId) {
+		err = txSystemBalanceUpdate(ctx, tx, leg.AccountId, leg.Currency, leg.TransactionType, leg.Amount)
+	} else {
+		err = txAccountBalanceUpdate(ctx, tx, leg.AccountId, leg.Currency, leg.TransactionType, leg.Amount)
+	}
+	if err != nil {
+		return err
+	}
+
+	sql, args := prepareInsertTransaction(leg)
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package accounts
+
+import (
+	id "chariottakehome/internal/identifier"
+	"chariottakehome/internal/money"
+	"testing"
+	"time"
+)
+
+func TestSystemAccountIds(t *testing.T) {
+	for _, currency := range []money.Currency{money.USD, money.EUR, money.JPY, money.BHD} {
+		for kind := range systemAccountNames {
+			accountId := systemAccountId(kind, currency)
+
+			parsedKind, parsedCurrency, ok := parseSystemAccount(accountId)
+			if !ok || parsedKind != kind || parsedCurrency != currency {
+				t.Fatalf("Failed to round trip system account '%s'", accountId)
+			}
+		}
+	}
+}
+
+func TestGeneratedIdsAreNotSystemAccounts(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		accountId, err := id.New()
+		if err != nil {
+			t.Fatal("Failed to generate identifier")
+		}
+
+		if IsSystemAccount(accountId) {
+			t.Fatalf("Generated identifier '%s' mistaken for a system account", accountId)
+		}
+	}
+}
+
+func TestJournalEntryBalanced(t *testing.T) {
+	debit := Transaction{Amount: 500, TransactionType: Debit}
+	credit := Transaction{Amount: 500, TransactionType: Credit}
+
+	if !(JournalEntry{Legs: []Transaction{debit, credit}}).balanced() {
+		t.Fatal("Failed to accept a balanced entry")
+	}
+
+	if (JournalEntry{Legs: []Transaction{debit, credit, credit}}).balanced() {
+		t.Fatal("Failed to reject an unbalanced entry")
+	}
+
+	if (JournalEntry{Legs: []Transaction{credit}}).balanced() {
+		t.Fatal("Failed to reject a single leg entry")
+	}
+}
+
+func TestJournalEntryCustomerAccountIds(t *testing.T) {
+	source, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+	dest, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+
+	entry := JournalEntry{Legs: []Transaction{
+		{AccountId: dest, TransactionType: Credit},
+		{AccountId: systemAccountId(cashIn, money.USD), TransactionType: Debit},
+		{AccountId: source, TransactionType: Debit},
+		{AccountId: dest, TransactionType: Debit},
+	}}
+
+	accountIds := entry.customerAccountIds()
+	if len(accountIds) != 2 || accountIds[0] != dest.String() || accountIds[1] != source.String() {
+		t.Fatalf("Expected only the distinct customer accounts to be locked, got %v", accountIds)
+	}
+}
+
+func TestSnapshotCutoff(t *testing.T) {
+	cases := []struct {
+		now    time.Time
+		cutoff time.Time
+	}{
+		// Inside the grace period the previous day isn't closed yet
+		{time.Date(2024, 3, 2, 0, 30, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 3, 2, 1, 30, 0, 0, time.UTC), time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 3, 2, 23, 59, 0, 0, time.UTC), time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		if got := snapshotCutoff(c.now); !got.Equal(c.cutoff) {
+			t.Fatalf("Expected cutoff %s at %s, got %s", c.cutoff, c.now, got)
+		}
+	}
+}
+
+func TestDerivedIdempotencyKeys(t *testing.T) {
+	source, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+	dest, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+
+	sourceKey, destKey := resolveTransferIdempotencyKeys("order-1", source, dest, 500)
+	if sourceKey != resolveIdempotencyKey("order-1", source, 500, Debit) {
+		t.Fatalf("Expected the debit leg to use the client's key")
+	}
+	// A client can't pick a key that lands on the credit leg of someone else's transfer
+	for _, clientKey := range []string{"order-1", "order-1-credit", sourceKey + "-" + source.String() + "-credit"} {
+		if destKey == resolveIdempotencyKey(clientKey, dest, 500, Credit) {
+			t.Fatalf("Expected the credit leg's key not to match client key %q", clientKey)
+		}
+	}
+
+	// The same client key on two accounts balances against the same system account
+	cashInAccountId := systemAccountId(cashIn, money.USD)
+	first := systemLegKey(Transaction{AccountId: source, IdempotencyKey: sourceKey}, cashInAccountId)
+	second := systemLegKey(Transaction{AccountId: dest, IdempotencyKey: sourceKey}, cashInAccountId)
+	if first == second {
+		t.Fatalf("Expected system legs of different accounts to have different keys")
+	}
+	if len(first) != 32 || len(destKey) != 32 {
+		t.Fatalf("Expected keys to fit the idempotency_key column, got %q", first)
+	}
+}
@@ -0,0 +1,197 @@
+package accounts
+
+import (
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Names of the limits reported by GetLimitUsage
+const (
+	dailyWithdrawalAmount string = "daily_withdrawal_amount"
+	hourlyTransferCount   string = "hourly_transfer_count"
+	monthlyOutboundAmount string = "monthly_outbound_amount"
+)
+
+// outbound is what an account has moved out in the windows containing a moment
+type outbound struct {
+	// Withdrawn in the day
+	withdrawn int64
+	// Transfers out in the hour
+	transfers int64
+	// Withdrawn and transferred out in the month
+	volume int64
+}
+
+// windows are the starts of the UTC hour, day, and month containing a moment
+type windows struct {
+	hour  time.Time
+	day   time.Time
+	month time.Time
+}
+
+func windowsAt(at time.Time) windows {
+	at = at.UTC()
+	return windows{
+		hour:  at.Truncate(time.Hour),
+		day:   time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC),
+		month: time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// usage pairs each cap in l with what's been used of it in the windows containing at
+func (l Limits) usage(used outbound, at time.Time) []LimitUsage {
+	w := windowsAt(at)
+
+	usage := make([]LimitUsage, 0, 3)
+	if l.DailyWithdrawalAmount != nil {
+		usage = append(usage, LimitUsage{dailyWithdrawalAmount, *l.DailyWithdrawalAmount, used.withdrawn, w.day.AddDate(0, 0, 1)})
+	}
+	if l.HourlyTransferCount != nil {
+		usage = append(usage, LimitUsage{hourlyTransferCount, *l.HourlyTransferCount, used.transfers, w.hour.Add(time.Hour)})
+	}
+	if l.MonthlyOutboundAmount != nil {
+		usage = append(usage, LimitUsage{monthlyOutboundAmount, *l.MonthlyOutboundAmount, used.volume, w.month.AddDate(0, 1, 0)})
+	}
+
+	return usage
+}
+
+// check returns the error for the first cap in l that used is over
+func (l Limits) check(used outbound) error {
+	switch {
+	case l.DailyWithdrawalAmount != nil && used.withdrawn > *l.DailyWithdrawalAmount:
+		return ErrDailyWithdrawalLimit
+	case l.HourlyTransferCount != nil && used.transfers > *l.HourlyTransferCount:
+		return ErrHourlyTransferLimit
+	case l.MonthlyOutboundAmount != nil && used.volume > *l.MonthlyOutboundAmount:
+		return ErrMonthlyOutboundLimit
+	default:
+		return nil
+	}
+}
+
+// isDeclined reports whether a debit was turned down because of the account's balance or limits,
+// which is recorded as a failed transaction
+func isDeclined(err error) bool {
+	return errors.Is(err, ErrInsufficientFunds) ||
+		errors.Is(err, ErrDailyWithdrawalLimit) ||
+		errors.Is(err, ErrHourlyTransferLimit) ||
+		errors.Is(err, ErrMonthlyOutboundLimit)
+}
+
+// txCheckLimits rejects a debit that takes the account past one of its limits. It runs after the
+// debit is written, so the debit counts towards its own limits, and while the account row is
+// locked, so concurrent debits are counted one after the other rather than both slipping under.
+func txCheckLimits(ctx context.Context, tx pgx.Tx, accountId id.Identifier, at time.Time) error {
+	var limits AccountLimits
+	if err := scanAccountLimits(tx.QueryRow(ctx, accountLimitsSelect, accountId), &limits); err != nil {
+		return fmt.Errorf("failed to read limits: %w", err)
+	}
+	if limits.Effective.empty() {
+		return nil
+	}
+
+	var used outbound
+	sql, args := prepareSelectOutbound(accountId, at)
+	if err := scanOutbound(tx.QueryRow(ctx, sql, args...), &used); err != nil {
+		return fmt.Errorf("failed to sum outbound transactions: %w", err)
+	}
+
+	return limits.Effective.check(used)
+}
+
+// SetLimitTier creates the tier, or replaces its limits if it exists
+func (r *accountRepository) SetLimitTier(ctx context.Context, name string, limits Limits) (*LimitTier, error) {
+	var tier LimitTier
+	sql, args := prepareUpsertLimitTier(name, limits, time.Now().UTC())
+	if err := scanLimitTier(r.database.QueryRow(ctx, sql, args...), &tier); err != nil {
+		return nil, err
+	}
+
+	return &tier, nil
+}
+
+func (r *accountRepository) GetLimitTier(ctx context.Context, name string) (*LimitTier, error) {
+	var tier LimitTier
+	row := r.database.QueryRow(ctx, `SELECT `+limitTierColumns+` FROM limit_tiers WHERE name = $1`, name)
+	if err := scanLimitTier(row, &tier); err != nil {
+		return nil, err
+	}
+
+	return &tier, nil
+}
+
+// SetAccountLimits assigns the account to a tier, or to none if tier is nil, and replaces the
+// limits set on the account itself
+func (r *accountRepository) SetAccountLimits(ctx context.Context, accountId id.Identifier, tier *string, limits Limits) (*AccountLimits, error) {
+	if IsSystemAccount(accountId) {
+		return nil, ErrSystemAccount
+	}
+
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Reports an unknown account or tier as NotFound rather than a foreign key violation
+	var exists int
+	if err := tx.QueryRow(ctx, `SELECT 1 FROM accounts WHERE id = $1`, accountId).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if tier != nil {
+		if err := tx.QueryRow(ctx, `SELECT 1 FROM limit_tiers WHERE name = $1`, *tier).Scan(&exists); err != nil {
+			return nil, err
+		}
+	}
+
+	sql, args := prepareUpsertAccountLimits(accountId, tier, limits, time.Now().UTC())
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return nil, fmt.Errorf("failed to upsert limits: %w", err)
+	}
+
+	var accountLimits AccountLimits
+	if err := scanAccountLimits(tx.QueryRow(ctx, accountLimitsSelect, accountId), &accountLimits); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &accountLimits, nil
+}
+
+func (r *accountRepository) GetAccountLimits(ctx context.Context, accountId id.Identifier) (*AccountLimits, error) {
+	var limits AccountLimits
+	if err := scanAccountLimits(r.database.QueryRow(ctx, accountLimitsSelect, accountId), &limits); err != nil {
+		return nil, err
+	}
+
+	return &limits, nil
+}
+
+// GetLimitUsage reports how much of each limit in effect on the account has been used in its
+// current window. Accounts without limits have nothing to report.
+func (r *accountRepository) GetLimitUsage(ctx context.Context, accountId id.Identifier) ([]LimitUsage, error) {
+	limits, err := r.GetAccountLimits(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var used outbound
+	if !limits.Effective.empty() {
+		sql, args := prepareSelectOutbound(accountId, now)
+		if err := scanOutbound(r.database.QueryRow(ctx, sql, args...), &used); err != nil {
+			return nil, err
+		}
+	}
+
+	return limits.Effective.usage(used, now), nil
+}
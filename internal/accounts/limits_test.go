@@ -0,0 +1,91 @@
+package accounts
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func limitOf(limit int64) *int64 {
+	return &limit
+}
+
+func TestLimitsOrElse(t *testing.T) {
+	account := Limits{DailyWithdrawalAmount: limitOf(100)}
+	tier := Limits{DailyWithdrawalAmount: limitOf(500), HourlyTransferCount: limitOf(3)}
+
+	effective := account.orElse(tier)
+	if *effective.DailyWithdrawalAmount != 100 {
+		t.Fatalf("Expected the account's cap to override the tier's, got %d", *effective.DailyWithdrawalAmount)
+	}
+	if effective.HourlyTransferCount == nil || *effective.HourlyTransferCount != 3 {
+		t.Fatalf("Expected the tier to fill in caps the account doesn't set")
+	}
+	if effective.MonthlyOutboundAmount != nil {
+		t.Fatalf("Expected caps neither sets to stay off")
+	}
+}
+
+func TestLimitsCheck(t *testing.T) {
+	limits := Limits{DailyWithdrawalAmount: limitOf(100), HourlyTransferCount: limitOf(0), MonthlyOutboundAmount: limitOf(1000)}
+
+	cases := []struct {
+		used outbound
+		want error
+	}{
+		{outbound{withdrawn: 100, volume: 100}, nil},
+		{outbound{withdrawn: 101, volume: 101}, ErrDailyWithdrawalLimit},
+		// A cap of zero blocks the movement outright
+		{outbound{transfers: 1, volume: 5}, ErrHourlyTransferLimit},
+		{outbound{withdrawn: 50, volume: 1001}, ErrMonthlyOutboundLimit},
+	}
+	for _, c := range cases {
+		if err := limits.check(c.used); !errors.Is(err, c.want) {
+			t.Fatalf("Expected %v for %+v, got %v", c.want, c.used, err)
+		}
+	}
+
+	if err := (Limits{}).check(outbound{withdrawn: 1 << 40, transfers: 1 << 20, volume: 1 << 40}); err != nil {
+		t.Fatalf("Expected no caps to allow anything, got %v", err)
+	}
+}
+
+func TestLimitsUsage(t *testing.T) {
+	at := time.Date(2024, 1, 31, 23, 45, 0, 0, time.UTC)
+	limits := Limits{DailyWithdrawalAmount: limitOf(100), MonthlyOutboundAmount: limitOf(1000)}
+
+	usage := limits.usage(outbound{withdrawn: 150, transfers: 2, volume: 400}, at)
+	if len(usage) != 2 {
+		t.Fatalf("Expected usage for the 2 caps that are set, got %+v", usage)
+	}
+
+	daily := usage[0]
+	if daily.Limit != dailyWithdrawalAmount || daily.Used != 150 || daily.Remaining() != 0 {
+		t.Fatalf("Expected the daily limit to be used up, got %+v", daily)
+	}
+	if !daily.ResetsAt.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the daily limit to reset at midnight, got %s", daily.ResetsAt)
+	}
+
+	monthly := usage[1]
+	if monthly.Limit != monthlyOutboundAmount || monthly.Remaining() != 600 {
+		t.Fatalf("Expected 600 left of the monthly limit, got %+v", monthly)
+	}
+	if !monthly.ResetsAt.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the monthly limit to reset on the 1st, got %s", monthly.ResetsAt)
+	}
+}
+
+func TestWindowsAt(t *testing.T) {
+	w := windowsAt(time.Date(2024, 3, 15, 10, 30, 15, 0, time.FixedZone("EST", -5*60*60)))
+
+	if !w.hour.Equal(time.Date(2024, 3, 15, 15, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the UTC hour, got %s", w.hour)
+	}
+	if !w.day.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the UTC day, got %s", w.day)
+	}
+	if !w.month.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the UTC month, got %s", w.month)
+	}
+}
@@ -0,0 +1,131 @@
+package accounts
+
+import (
+	id "chariottakehome/internal/identifier"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AddAccountMember gives the user the permissions on the account, replacing any they already
+// had. An account's last admin can't give up the admin permission.
+func (r *accountRepository) AddAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier, permissions []Permission) (*Member, error) {
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := txLockMembership(ctx, tx, accountId); err != nil {
+		return nil, err
+	}
+
+	// Reports an unknown user as NotFound rather than a foreign key violation
+	var exists int
+	if err := tx.QueryRow(ctx, `SELECT 1 FROM users WHERE id = $1`, userId).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	if !(Member{Permissions: permissions}).Can(Admin) {
+		if err := txCheckOtherAdmins(ctx, tx, accountId, userId); err != nil {
+			return nil, err
+		}
+	}
+
+	member, err := txUpsertMember(ctx, tx, accountId, userId, permissions, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return member, nil
+}
+
+// RemoveAccountMember takes away all of the user's access to the account, returning the
+// membership that was removed. The last admin can't be removed.
+func (r *accountRepository) RemoveAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier) (*Member, error) {
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := txLockMembership(ctx, tx, accountId); err != nil {
+		return nil, err
+	}
+
+	if err := txCheckOtherAdmins(ctx, tx, accountId, userId); err != nil {
+		return nil, err
+	}
+
+	var member Member
+	row := tx.QueryRow(ctx, `DELETE FROM account_members WHERE account_id = $1 AND user_id = $2 RETURNING `+memberColumns, accountId, userId)
+	if err := scanMember(row, &member); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &member, nil
+}
+
+func (r *accountRepository) GetAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier) (*Member, error) {
+	var member Member
+	row := r.database.QueryRow(ctx, `SELECT `+memberColumns+` FROM account_members WHERE account_id = $1 AND user_id = $2`, accountId, userId)
+	if err := scanMember(row, &member); err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func (r *accountRepository) ListAccountMembers(ctx context.Context, accountId id.Identifier) ([]Member, error) {
+	rows, err := r.database.Query(ctx, `SELECT `+memberColumns+`
+	FROM account_members
+	WHERE account_id = $1
+	ORDER BY created_at, user_id`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]Member, 0)
+	for rows.Next() {
+		var m Member
+		if err := scanMember(rows, &m); err != nil {
+			return nil, err
+		}
+
+		results = append(results, m)
+	}
+
+	return results, rows.Err()
+}
+
+// txLockMembership serializes membership changes on an account, so two admins can't each remove
+// the other
+func txLockMembership(ctx context.Context, tx pgx.Tx, accountId id.Identifier) error {
+	var locked id.Identifier
+	return tx.QueryRow(ctx, `SELECT id FROM accounts WHERE id = $1 FOR NO KEY UPDATE`, accountId).Scan(&locked)
+}
+
+// txCheckOtherAdmins fails unless the account has an admin besides userId
+func txCheckOtherAdmins(ctx context.Context, tx pgx.Tx, accountId id.Identifier, userId id.Identifier) error {
+	var admins int
+	if err := tx.QueryRow(ctx, otherAdminCount, accountId, userId).Scan(&admins); err != nil {
+		return err
+	}
+
+	if admins == 0 {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package accounts
+
+import "testing"
+
+func TestPermissionRoundTrip(t *testing.T) {
+	for _, permission := range AllPermissions {
+		var scanned Permission
+		if err := scanned.Scan(permission.String()); err != nil {
+			t.Fatalf("Failed to scan %s: %s", permission, err)
+		}
+		if scanned != permission {
+			t.Fatalf("Expected %s, got %s", permission, scanned)
+		}
+	}
+
+	var p Permission
+	if err := p.Scan("superuser"); err == nil {
+		t.Fatalf("Expected an unknown permission to be rejected")
+	}
+}
+
+func TestMemberCan(t *testing.T) {
+	member := Member{Permissions: []Permission{View, Deposit}}
+
+	if !member.Can(View) || !member.Can(Deposit) {
+		t.Fatalf("Expected granted permissions to be allowed")
+	}
+	// Permissions don't imply each other, not even admin
+	if member.Can(Withdraw) || (Member{Permissions: []Permission{Admin}}).Can(Transfer) {
+		t.Fatalf("Expected permissions that weren't granted to be refused")
+	}
+}
@@ -0,0 +1,34 @@
+package accounts
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Kinds of money movement counted by the business metrics
+const (
+	depositMovement    string = "deposit"
+	withdrawalMovement string = "withdrawal"
+	transferMovement   string = "transfer"
+	captureMovement    string = "capture"
+	reversalMovement   string = "reversal"
+)
+
+var (
+	postedMovements = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ledger_movements_total",
+		Help: "Money movements posted to the ledger, by kind and currency. Idempotent replays aren't counted.",
+	}, []string{"kind", "currency"})
+
+	postedVolume = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ledger_movement_volume_minor_total",
+		Help: "Amount of money moved, in the currency's minor unit, by kind and currency.",
+	}, []string{"kind", "currency"})
+)
+
+// recordMovement counts a movement once it's committed
+func recordMovement(kind string, leg Transaction) {
+	currency := leg.Currency.Code()
+	postedMovements.WithLabelValues(kind, currency).Inc()
+	postedVolume.WithLabelValues(kind, currency).Add(float64(leg.Amount))
+}
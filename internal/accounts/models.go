@@ -2,28 +2,126 @@ package accounts
 
 import (
 	id "chariottakehome/internal/identifier"
+	"chariottakehome/internal/money"
 	"errors"
+	"slices"
 	"time"
 )
 
 type Account struct {
-	Id        id.Identifier
-	UserId    id.Identifier
-	Name      string
-	Balance   int
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Id             id.Identifier
+	UserId         id.Identifier
+	Name           string
+	Balance        int64
+	HeldBalance    int64
+	Currency       money.Currency
+	OverdraftLimit int64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// AvailableBalance is the ledger balance less the amount reserved by active holds
+func (a Account) AvailableBalance() int64 {
+	return a.Balance - a.HeldBalance
 }
 
 type Transaction struct {
 	Id              id.Identifier
 	IdempotencyKey  string
 	AccountId       id.Identifier
-	Amount          int
+	Amount          int64
+	Currency        money.Currency
 	TransactionType TransactionType
 	TransactionDate time.Time
 	Status          TransactionStatus
 	Description     *string
+	JournalEntryId  *id.Identifier
+
+	// Only set on holds placed by AuthorizeWithdrawal
+	AuthorizedAmount *int64
+	ExpiresAt        *time.Time
+
+	// Set on the legs of a reversal to the leg they compensate
+	ReversalOf *id.Identifier
+}
+
+func (t Transaction) isHold() bool {
+	return t.AuthorizedAmount != nil
+}
+
+type JournalEntry struct {
+	Id          id.Identifier
+	Description *string
+	CreatedAt   time.Time
+	Legs        []Transaction
+}
+
+// balanced reports whether the entry's legs sum to zero
+func (j JournalEntry) balanced() bool {
+	if len(j.Legs) < 2 {
+		return false
+	}
+
+	var sum int64
+	for _, leg := range j.Legs {
+		sum += leg.signedAmount()
+	}
+
+	return sum == 0
+}
+
+// customerAccountIds are the distinct customer accounts the entry's legs post to
+func (j JournalEntry) customerAccountIds() []string {
+	accountIds := make([]string, 0, len(j.Legs))
+	for _, leg := range j.Legs {
+		accountId := leg.AccountId.String()
+		if !IsSystemAccount(leg.AccountId) && !slices.Contains(accountIds, accountId) {
+			accountIds = append(accountIds, accountId)
+		}
+	}
+
+	return accountIds
+}
+
+// signedAmount is positive for credits and negative for debits
+func (t Transaction) signedAmount() int64 {
+	if t.TransactionType == Debit {
+		return -t.Amount
+	}
+	return t.Amount
+}
+
+// matches reports whether a stored transaction was created from the same request payload
+func (t Transaction) matches(accountId id.Identifier, amount int64, transType TransactionType, description string) bool {
+	storedDescription := ""
+	if t.Description != nil {
+		storedDescription = *t.Description
+	}
+
+	return t.AccountId == accountId &&
+		t.Amount == amount &&
+		t.TransactionType == transType &&
+		storedDescription == description
+}
+
+// replays reports whether a stored transaction is the result of retrying a deposit, withdrawal, or
+// reversal with the same payload. Holds share the withdrawal key space but only replay through
+// AuthorizeWithdrawal, since a hold returned as a withdrawal never moved any money.
+func (t Transaction) replays(accountId id.Identifier, amount int64, transType TransactionType, description string) bool {
+	return !t.isHold() && t.matches(accountId, amount, transType, description)
+}
+
+// replaysReversal reports whether a stored leg was posted by the request that would post
+// reversal. Unless matchAmount is set the amounts aren't compared, since a reversal that didn't
+// name an amount refunded whatever was left of the original at the time.
+func (t Transaction) replaysReversal(reversal Transaction, matchAmount bool) bool {
+	amount := reversal.Amount
+	if !matchAmount {
+		amount = t.Amount
+	}
+
+	return t.ReversalOf != nil && *t.ReversalOf == *reversal.ReversalOf &&
+		t.matches(reversal.AccountId, amount, reversal.TransactionType, *reversal.Description)
 }
 
 type TransactionType int
@@ -33,6 +131,14 @@ const (
 	Debit
 )
 
+// opposite is the type of the leg that undoes one of type t
+func (t TransactionType) opposite() TransactionType {
+	if t == Credit {
+		return Debit
+	}
+	return Credit
+}
+
 func (t TransactionType) String() string {
 	switch t {
 	case Credit:
@@ -130,3 +236,141 @@ func (t *TransactionStatus) Scan(value interface{}) error {
 
 	return nil
 }
+
+// Member is a user who has been given access to an account
+type Member struct {
+	AccountId   id.Identifier
+	UserId      id.Identifier
+	Permissions []Permission
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (m Member) Can(permission Permission) bool {
+	return slices.Contains(m.Permissions, permission)
+}
+
+// Permission is one thing a member can do with an account. None of them imply another.
+type Permission int
+
+const (
+	View Permission = iota
+	Deposit
+	Withdraw
+	Transfer
+	// Admin allows managing the account's members and details
+	Admin
+)
+
+// AllPermissions is what an account's creator is given
+var AllPermissions = []Permission{View, Deposit, Withdraw, Transfer, Admin}
+
+func (p Permission) String() string {
+	switch p {
+	case View:
+		return "view"
+	case Deposit:
+		return "deposit"
+	case Withdraw:
+		return "withdraw"
+	case Transfer:
+		return "transfer"
+	case Admin:
+		return "admin"
+	default:
+		return ""
+	}
+}
+
+func (p *Permission) Scan(value interface{}) error {
+	if value == nil {
+		return errors.New("nil value")
+	}
+
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return errors.New("unsupported data type")
+	}
+
+	switch str {
+	case "view":
+		*p = View
+	case "deposit":
+		*p = Deposit
+	case "withdraw":
+		*p = Withdraw
+	case "transfer":
+		*p = Transfer
+	case "admin":
+		*p = Admin
+	default:
+		return errors.New("unsupported string value")
+	}
+
+	return nil
+}
+
+// Limits cap what an account can move out in a UTC hour, day, or month. Amounts are in the
+// account currency's minor unit and nil caps aren't enforced.
+type Limits struct {
+	// Withdrawn in a day, counting pending holds
+	DailyWithdrawalAmount *int64
+	// Transfers out in an hour
+	HourlyTransferCount *int64
+	// Withdrawn and transferred out in a month
+	MonthlyOutboundAmount *int64
+}
+
+// orElse fills the caps l leaves off from fallback
+func (l Limits) orElse(fallback Limits) Limits {
+	if l.DailyWithdrawalAmount == nil {
+		l.DailyWithdrawalAmount = fallback.DailyWithdrawalAmount
+	}
+	if l.HourlyTransferCount == nil {
+		l.HourlyTransferCount = fallback.HourlyTransferCount
+	}
+	if l.MonthlyOutboundAmount == nil {
+		l.MonthlyOutboundAmount = fallback.MonthlyOutboundAmount
+	}
+
+	return l
+}
+
+func (l Limits) empty() bool {
+	return l.DailyWithdrawalAmount == nil && l.HourlyTransferCount == nil && l.MonthlyOutboundAmount == nil
+}
+
+// LimitTier is a named set of limits shared by the accounts assigned to it
+type LimitTier struct {
+	Name      string
+	Limits    Limits
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AccountLimits are the limits set on an account, and those in effect once its tier fills in the
+// ones it doesn't set
+type AccountLimits struct {
+	AccountId id.Identifier
+	Tier      *string
+	Limits    Limits
+	Effective Limits
+}
+
+// LimitUsage is how much of one of its limits an account has used in the current window
+type LimitUsage struct {
+	// daily_withdrawal_amount, hourly_transfer_count, or monthly_outbound_amount
+	Limit    string
+	Max      int64
+	Used     int64
+	ResetsAt time.Time
+}
+
+func (u LimitUsage) Remaining() int64 {
+	return max(0, u.Max-u.Used)
+}
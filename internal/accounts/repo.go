@@ -2,13 +2,20 @@ package accounts
 
 import (
 	"chariottakehome/internal/database"
+	"chariottakehome/internal/events"
 	id "chariottakehome/internal/identifier"
+	"chariottakehome/internal/money"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type AccountTransferResp struct {
@@ -16,18 +23,70 @@ type AccountTransferResp struct {
 	DestinationTransaction Transaction
 }
 
+type PageDirection int
+
+const (
+	Forward PageDirection = iota
+	Backward
+)
+
+// Page is a keyset page of up to Size rows starting just past Cursor in Direction, or from the
+// first row if Cursor is nil
+type Page struct {
+	Cursor    *id.Identifier
+	Direction PageDirection
+	Size      int
+}
+
+// TransactionFilter narrows ListTransactions. Nil fields don't filter and ranges are inclusive.
+type TransactionFilter struct {
+	From            *time.Time
+	To              *time.Time
+	TransactionType *TransactionType
+	Status          *TransactionStatus
+	MinAmount       *int64
+	MaxAmount       *int64
+}
+
+// ListTransactionsResp holds a page in ascending id order. HasMore reports whether there are
+// further rows in the direction the page was read.
 type ListTransactionsResp struct {
 	Transactions []Transaction
-	NextCursor   *id.Identifier
+	HasMore      bool
+}
+
+type ListAccountsResp struct {
+	Accounts   []Account
+	NextCursor *id.Identifier
 }
 
 type AccountRepository interface {
-	CreateAccount(ctx context.Context, userId id.Identifier, name string) (*Account, error)
-	DepositFunds(ctx context.Context, accountId id.Identifier, amount int, description string) (*Transaction, error)
-	WithdrawFunds(ctx context.Context, accountId id.Identifier, amount int, description string) (*Transaction, error)
-	AccountTransfer(ctx context.Context, sourceAccountId, destAccountId id.Identifier, amount int, description string) (*AccountTransferResp, error)
-	ListTransactions(ctx context.Context, accountId id.Identifier, startCursor *id.Identifier, pageSize int) (*ListTransactionsResp, error)
-	GetBalance(ctx context.Context, accountId id.Identifier, timestamp time.Time) (int, error)
+	CreateAccount(ctx context.Context, userId id.Identifier, name string, currency money.Currency, overdraftLimit int64) (*Account, error)
+	UpdateAccount(ctx context.Context, accountId id.Identifier, name *string, overdraftLimit *int64) (*Account, error)
+	GetAccount(ctx context.Context, accountId id.Identifier) (*Account, error)
+	ListAccounts(ctx context.Context, userId id.Identifier, startCursor *id.Identifier, pageSize int) (*ListAccountsResp, error)
+	DepositFunds(ctx context.Context, accountId id.Identifier, amount int64, description string, idempotencyKey string) (*Transaction, error)
+	WithdrawFunds(ctx context.Context, accountId id.Identifier, amount int64, description string, idempotencyKey string) (*Transaction, error)
+	AccountTransfer(ctx context.Context, sourceAccountId, destAccountId id.Identifier, amount int64, description string, idempotencyKey string) (*AccountTransferResp, error)
+	GetTransaction(ctx context.Context, transactionId id.Identifier) (*Transaction, error)
+	ListTransactions(ctx context.Context, accountId id.Identifier, page Page, filter TransactionFilter) (*ListTransactionsResp, error)
+	AuthorizeWithdrawal(ctx context.Context, accountId id.Identifier, amount int64, description string, idempotencyKey string, expiresAt time.Time) (*Transaction, error)
+	CaptureTransaction(ctx context.Context, transactionId id.Identifier, amount *int64) (*Transaction, error)
+	VoidTransaction(ctx context.Context, transactionId id.Identifier) (*Transaction, error)
+	ReverseTransaction(ctx context.Context, transactionId id.Identifier, amount *int64, description string, idempotencyKey string) (*JournalEntry, error)
+	ExpireHolds(ctx context.Context, now time.Time) (int64, error)
+	GetBalance(ctx context.Context, accountId id.Identifier, timestamp *time.Time) (money.Money, error)
+	SnapshotBalances(ctx context.Context, cutoff time.Time) (int64, error)
+	GetJournalEntry(ctx context.Context, journalEntryId id.Identifier) (*JournalEntry, error)
+	AddAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier, permissions []Permission) (*Member, error)
+	RemoveAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier) (*Member, error)
+	GetAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier) (*Member, error)
+	ListAccountMembers(ctx context.Context, accountId id.Identifier) ([]Member, error)
+	SetLimitTier(ctx context.Context, name string, limits Limits) (*LimitTier, error)
+	GetLimitTier(ctx context.Context, name string) (*LimitTier, error)
+	SetAccountLimits(ctx context.Context, accountId id.Identifier, tier *string, limits Limits) (*AccountLimits, error)
+	GetAccountLimits(ctx context.Context, accountId id.Identifier) (*AccountLimits, error)
+	GetLimitUsage(ctx context.Context, accountId id.Identifier) ([]LimitUsage, error)
 }
 
 type accountRepository struct {
@@ -35,10 +94,10 @@ type accountRepository struct {
 }
 
 func NewRepo(database *database.DatabasePool) AccountRepository {
-	return &accountRepository{database}
+	return &tracedRepository{&accountRepository{database}}
 }
 
-func (r *accountRepository) CreateAccount(ctx context.Context, userId id.Identifier, name string) (*Account, error) {
+func (r *accountRepository) CreateAccount(ctx context.Context, userId id.Identifier, name string, currency money.Currency, overdraftLimit int64) (*Account, error) {
 	id, err := id.New()
 	if err != nil {
 		return nil, err
@@ -46,17 +105,49 @@ func (r *accountRepository) CreateAccount(ctx context.Context, userId id.Identif
 
 	now := time.Now().UTC()
 	account := Account{
-		Id:        id,
-		UserId:    userId,
-		Name:      name,
-		Balance:   0,
-		CreatedAt: now,
-		UpdatedAt: now,
+		Id:             id,
+		UserId:         userId,
+		Name:           name,
+		Balance:        0,
+		Currency:       currency,
+		OverdraftLimit: overdraftLimit,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 
-	db := r.database
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
 	sql, args := prepareInsertAccount(account)
-	_, err = db.Exec(ctx, sql, args...)
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account's creator is its first admin
+	if _, err = txUpsertMember(ctx, tx, account.Id, userId, AllPermissions, now); err != nil {
+		return nil, err
+	}
+
+	if err = txAppendAccountCreated(ctx, tx, account); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &account, nil
+}
+
+func (r *accountRepository) UpdateAccount(ctx context.Context, accountId id.Identifier, name *string, overdraftLimit *int64) (*Account, error) {
+	var account Account
+
+	sql, args := prepareUpdateAccount(accountId, name, overdraftLimit)
+	err := scanAccount(r.database.QueryRow(ctx, sql, args...), &account)
 	if err != nil {
 		return nil, err
 	}
@@ -64,45 +155,120 @@ func (r *accountRepository) CreateAccount(ctx context.Context, userId id.Identif
 	return &account, nil
 }
 
-func (r *accountRepository) DepositFunds(ctx context.Context, accountId id.Identifier, amount int, description string) (*Transaction, error) {
-	idempotencyKey := generateIdempotencyKey(accountId, amount, Credit)
+func (r *accountRepository) GetAccount(ctx context.Context, accountId id.Identifier) (*Account, error) {
+	var account Account
+
+	row := r.database.QueryRow(ctx, `SELECT `+accountColumns+` FROM accounts WHERE id = $1`, accountId)
+	if err := scanAccount(row, &account); err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (r *accountRepository) GetTransaction(ctx context.Context, transactionId id.Identifier) (*Transaction, error) {
+	var transaction Transaction
+
+	row := r.database.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE id = $1`, transactionId)
+	if err := scanTransaction(row, &transaction); err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// ListAccounts pages through every account the user is a member of
+func (r *accountRepository) ListAccounts(ctx context.Context, userId id.Identifier, startCursor *id.Identifier, pageSize int) (*ListAccountsResp, error) {
+	start := "00000000000000000000"
+	if startCursor != nil {
+		start = startCursor.String()
+	}
+
+	rows, err := r.database.Query(ctx, `SELECT `+accountColumns+`
+	FROM accounts
+	WHERE id IN (SELECT account_id FROM account_members WHERE user_id = $1)
+		AND id >= $2
+	ORDER BY id
+	LIMIT $3
+	`, userId, start, pageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]Account, 0)
+	for rows.Next() {
+		var a Account
+		if err := scanAccount(rows, &a); err != nil {
+			return nil, err
+		}
+
+		results = append(results, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var nextCursor *id.Identifier
+	// the extra row is the first result of the next page
+	if len(results) > pageSize {
+		nextCursor = &results[pageSize].Id
+		results = results[:pageSize]
+	}
+
+	return &ListAccountsResp{
+		Accounts:   results,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+func (r *accountRepository) DepositFunds(ctx context.Context, accountId id.Identifier, amount int64, description string, idempotencyKey string) (*Transaction, error) {
+	key := resolveIdempotencyKey(idempotencyKey, accountId, amount, Credit)
 
 	db := r.database
-	if !transactionIsUnique(db, ctx, idempotencyKey) {
-		return nil, errors.New("transaction already exists")
+	replayed, err := replayTransaction(ctx, db, key, accountId, amount, Credit, description)
+	if err != nil || replayed != nil {
+		return replayed, err
 	}
 
-	tx, err := db.Begin(ctx)
+	currency, err := accountCurrency(ctx, db, accountId)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback(ctx)
 
-	err = txAccountBalanceUpdate(ctx, tx, accountId, Credit, amount)
+	deposit, err := newLeg(key, accountId, amount, currency, Credit, description)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := id.New()
+	cashInAccountId := systemAccountId(cashIn, currency)
+	cashIn, err := newLeg(systemLegKey(deposit, cashInAccountId), cashInAccountId, amount, currency, Debit, description)
 	if err != nil {
 		return nil, err
 	}
 
-	transaction := Transaction{
-		Id:              id,
-		IdempotencyKey:  idempotencyKey,
-		AccountId:       accountId,
-		Amount:          amount,
-		TransactionType: Credit,
-		TransactionDate: time.Now().UTC(),
-		Status:          Complete,
-		Description:     &description,
+	entry, err := newJournalEntry(description, deposit, cashIn)
+	if err != nil {
+		return nil, err
 	}
 
-	sql, args := prepareInsertTransaction(transaction)
-	_, err = tx.Exec(ctx, sql, args...)
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = txPostJournalEntry(ctx, tx, entry)
+	if isUniqueViolation(err) {
+		// A concurrent retry with the same key won the race, so replay its result
+		return requireReplay(replayTransaction(ctx, db, key, accountId, amount, Credit, description))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+		return nil, err
+	}
+
+	if err = txAppendTransactionEvent(ctx, tx, events.FundsDeposited, entry.Legs[0]); err != nil {
+		return nil, err
 	}
 
 	err = tx.Commit(ctx)
@@ -110,154 +276,188 @@ func (r *accountRepository) DepositFunds(ctx context.Context, accountId id.Ident
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return &transaction, nil
+	recordMovement(depositMovement, entry.Legs[0])
+	return &entry.Legs[0], nil
 }
 
-func (r *accountRepository) WithdrawFunds(ctx context.Context, accountId id.Identifier, amount int, description string) (*Transaction, error) {
+func (r *accountRepository) WithdrawFunds(ctx context.Context, accountId id.Identifier, amount int64, description string, idempotencyKey string) (*Transaction, error) {
 	db := r.database
-	idempotencyKey := generateIdempotencyKey(accountId, amount, Debit)
-	if !transactionIsUnique(db, ctx, idempotencyKey) {
-		return nil, errors.New("transaction already exists")
+	key := resolveIdempotencyKey(idempotencyKey, accountId, amount, Debit)
+	replayed, err := replayTransaction(ctx, db, key, accountId, amount, Debit, description)
+	if err != nil || replayed != nil {
+		return replayed, err
 	}
 
-	tx, err := db.Begin(ctx)
+	currency, err := accountCurrency(ctx, db, accountId)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback(ctx)
 
-	err = txAccountBalanceUpdate(ctx, tx, accountId, Debit, amount)
+	withdrawal, err := newLeg(key, accountId, amount, currency, Debit, description)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := id.New()
+	cashOutAccountId := systemAccountId(cashOut, currency)
+	cashOut, err := newLeg(systemLegKey(withdrawal, cashOutAccountId), cashOutAccountId, amount, currency, Credit, description)
 	if err != nil {
 		return nil, err
 	}
 
-	transaction := Transaction{
-		Id:              id,
-		IdempotencyKey:  idempotencyKey,
-		AccountId:       accountId,
-		Amount:          amount,
-		TransactionType: Debit,
-		TransactionDate: time.Now().UTC(),
-		Status:          Complete,
-		Description:     &description,
+	entry, err := newJournalEntry(description, withdrawal, cashOut)
+	if err != nil {
+		return nil, err
 	}
 
-	sql, args := prepareInsertTransaction(transaction)
-	_, err = tx.Exec(ctx, sql, args...)
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = txPostJournalEntry(ctx, tx, entry)
+	if err == nil {
+		err = txCheckLimits(ctx, tx, accountId, entry.CreatedAt)
+	}
+	if isDeclined(err) {
+		tx.Rollback(ctx)
+		return nil, recordFailedTransaction(ctx, db, err, accountId, amount, currency, Debit, description)
+	}
+	if isUniqueViolation(err) {
+		return requireReplay(replayTransaction(ctx, db, key, accountId, amount, Debit, description))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+		return nil, err
+	}
+
+	if err = txAppendTransactionEvent(ctx, tx, events.FundsWithdrawn, entry.Legs[0]); err != nil {
+		return nil, err
 	}
 
 	if err = tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return &transaction, nil
+	recordMovement(withdrawalMovement, entry.Legs[0])
+	return &entry.Legs[0], nil
 }
 
-func (r *accountRepository) AccountTransfer(ctx context.Context, sourceAccountId, destAccountId id.Identifier, amount int, description string) (*AccountTransferResp, error) {
+func (r *accountRepository) AccountTransfer(ctx context.Context, sourceAccountId, destAccountId id.Identifier, amount int64, description string, idempotencyKey string) (*AccountTransferResp, error) {
 	db := r.database
-	sourceIdempotencyKey := generateIdempotencyKey(sourceAccountId, amount, Debit)
-	destIdempotencyKey := generateIdempotencyKey(destAccountId, amount, Credit)
+	sourceIdempotencyKey, destIdempotencyKey := resolveTransferIdempotencyKeys(idempotencyKey, sourceAccountId, destAccountId, amount)
 
-	if !transactionIsUnique(db, ctx, sourceIdempotencyKey) || !transactionIsUnique(db, ctx, destIdempotencyKey) {
-		return nil, errors.New("transaction already exists")
+	replayed, err := replayTransfer(ctx, db, sourceIdempotencyKey, destIdempotencyKey, sourceAccountId, destAccountId, amount, description)
+	if err != nil || replayed != nil {
+		return replayed, err
 	}
 
-	tx, err := db.Begin(ctx)
+	currency, err := accountCurrency(ctx, db, sourceAccountId)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback(ctx)
 
-	err = txAccountBalanceUpdate(ctx, tx, sourceAccountId, Debit, amount)
+	destCurrency, err := accountCurrency(ctx, db, destAccountId)
 	if err != nil {
 		return nil, err
 	}
 
-	err = txAccountBalanceUpdate(ctx, tx, destAccountId, Credit, amount)
+	if currency != destCurrency {
+		return nil, ErrCurrencyMismatch
+	}
+
+	sourceTransaction, err := newLeg(sourceIdempotencyKey, sourceAccountId, amount, currency, Debit, description)
 	if err != nil {
 		return nil, err
 	}
 
-	sourceTransactionId, err := id.New()
+	destTransaction, err := newLeg(destIdempotencyKey, destAccountId, amount, currency, Credit, description)
 	if err != nil {
 		return nil, err
 	}
-	sourceTransaction := Transaction{
-		Id:              sourceTransactionId,
-		IdempotencyKey:  sourceIdempotencyKey,
-		AccountId:       sourceAccountId,
-		Amount:          amount,
-		TransactionType: Debit,
-		TransactionDate: time.Now().UTC(),
-		Status:          Complete,
-		Description:     &description,
-	}
 
-	destTransactionId, err := id.New()
+	entry, err := newJournalEntry(description, sourceTransaction, destTransaction)
 	if err != nil {
 		return nil, err
 	}
-	destTransaction := Transaction{
-		Id:              destTransactionId,
-		IdempotencyKey:  destIdempotencyKey,
-		AccountId:       destAccountId,
-		Amount:          amount,
-		TransactionType: Credit,
-		TransactionDate: time.Now().UTC(),
-		Status:          Complete,
-		Description:     &description,
-	}
 
-	sql, args := prepareInsertTransaction(sourceTransaction)
-	_, err = tx.Exec(ctx, sql, args...)
+	tx, err := db.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+		return nil, err
 	}
+	defer tx.Rollback(ctx)
 
-	sql, args = prepareInsertTransaction(destTransaction)
-	_, err = tx.Exec(ctx, sql, args...)
+	err = txPostJournalEntry(ctx, tx, entry)
+	if err == nil {
+		err = txCheckLimits(ctx, tx, sourceAccountId, entry.CreatedAt)
+	}
+	if isDeclined(err) {
+		tx.Rollback(ctx)
+		return nil, recordFailedTransaction(ctx, db, err, sourceAccountId, amount, currency, Debit, description)
+	}
+	if isUniqueViolation(err) {
+		return requireReplay(replayTransfer(ctx, db, sourceIdempotencyKey, destIdempotencyKey, sourceAccountId, destAccountId, amount, description))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+		return nil, err
+	}
+
+	if err = txAppendTransferCompleted(ctx, tx, entry.Legs[0], entry.Legs[1]); err != nil {
+		return nil, err
 	}
 
 	if err = tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	recordMovement(transferMovement, entry.Legs[0])
 	return &AccountTransferResp{
-		SourceTransaction:      sourceTransaction,
-		DestinationTransaction: destTransaction,
+		SourceTransaction:      entry.Legs[0],
+		DestinationTransaction: entry.Legs[1],
 	}, nil
 }
 
-func (r *accountRepository) ListTransactions(ctx context.Context, accountId id.Identifier, startCursor *id.Identifier, pageSize int) (*ListTransactionsResp, error) {
-	db := r.database
+func (r *accountRepository) GetJournalEntry(ctx context.Context, journalEntryId id.Identifier) (*JournalEntry, error) {
+	return findJournalEntry(ctx, r.database, journalEntryId)
+}
 
-	start := "00000000000000000000"
-	if startCursor != nil {
-		start = startCursor.String()
+func findJournalEntry(ctx context.Context, db *database.DatabasePool, journalEntryId id.Identifier) (*JournalEntry, error) {
+	entry := JournalEntry{Id: journalEntryId}
+	err := db.QueryRow(ctx, `SELECT description, created_at FROM journal_entries WHERE id = $1`, journalEntryId).Scan(
+		&entry.Description,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	rows, err := db.Query(ctx, `SELECT
-	id,
-	account_id,
-	amount,
-	transaction_type,
-	transaction_date,
-	status,
-	description
+	rows, err := db.Query(ctx, `SELECT `+transactionColumns+`
 	FROM transactions
-	WHERE account_id = $1
-		AND id >= $2
-	LIMIT $3
-	`, accountId, start, pageSize+1)
+	WHERE journal_entry_id = $1
+	ORDER BY id`, journalEntryId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entry.Legs = make([]Transaction, 0)
+	for rows.Next() {
+		var t Transaction
+		err := scanTransaction(rows, &t)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Legs = append(entry.Legs, t)
+	}
+
+	return &entry, rows.Err()
+}
+
+func (r *accountRepository) ListTransactions(ctx context.Context, accountId id.Identifier, page Page, filter TransactionFilter) (*ListTransactionsResp, error) {
+	db := r.database
+
+	sql, args := prepareListTransactions(accountId, page, filter)
+	rows, err := db.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -266,70 +466,119 @@ func (r *accountRepository) ListTransactions(ctx context.Context, accountId id.I
 	results := make([]Transaction, 0)
 	for rows.Next() {
 		var t Transaction
-		err := rows.Scan(
-			&t.Id,
-			&t.AccountId,
-			&t.Amount,
-			&t.TransactionType,
-			&t.TransactionDate,
-			&t.Status,
-			&t.Description,
-		)
+		err := scanTransaction(rows, &t)
 		if err != nil {
 			return nil, err
 		}
 
 		results = append(results, t)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	var nextCursor *id.Identifier
-	// results have the next cursor
-	if len(results) > pageSize {
-		nextCursor = &results[pageSize-1].Id
-		results = results[:pageSize]
+	// The extra row only tells us there's another page
+	hasMore := len(results) > page.Size
+	if hasMore {
+		results = results[:page.Size]
+	}
+
+	// Backward pages are read newest first
+	if page.Direction == Backward {
+		slices.Reverse(results)
 	}
 
 	return &ListTransactionsResp{
 		Transactions: results,
-		NextCursor:   nextCursor,
+		HasMore:      hasMore,
 	}, nil
 }
 
-func (r *accountRepository) GetBalance(ctx context.Context, accountId id.Identifier, timestamp time.Time) (int, error) {
+// GetBalance returns the account's current balance when timestamp is nil. Otherwise it returns the
+// balance from complete transactions up to timestamp, starting from the nearest snapshot.
+func (r *accountRepository) GetBalance(ctx context.Context, accountId id.Identifier, timestamp *time.Time) (money.Money, error) {
 	db := r.database
 
-	rows, err := db.Query(ctx, `SELECT amount, transaction_type FROM transactions 
-	WHERE account_id = $1
-	AND transaction_date <= $2`, accountId, timestamp)
+	if timestamp == nil {
+		var balance money.Money
+		err := db.QueryRow(ctx, `SELECT balance, currency FROM accounts WHERE id = $1`, accountId).Scan(&balance.Amount, &balance.Currency)
+		return balance, err
+	}
+
+	currency, err := accountCurrency(ctx, db, accountId)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	// Without a snapshot the delta covers every transaction
+	var (
+		snapshotAt time.Time
+		balance    int64
+	)
+	err = db.QueryRow(ctx, latestBalanceSnapshot, accountId, *timestamp).Scan(&snapshotAt, &balance)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return money.Money{}, err
+	}
+
+	var delta int64
+	err = db.QueryRow(ctx, balanceDelta, accountId, snapshotAt, *timestamp).Scan(&delta)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return money.New(balance, currency).Add(money.New(delta, currency))
+}
+
+// SnapshotBalances checkpoints the balance of every account with new complete transactions as of
+// cutoff, returning how many snapshots were taken
+func (r *accountRepository) SnapshotBalances(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.database.Exec(ctx, balanceSnapshotInsert, cutoff)
 	if err != nil {
 		return 0, err
 	}
 
-	result := 0
-	for rows.Next() {
-		var (
-			amount    int
-			transType TransactionType
-		)
-		err := rows.Scan(&amount, &transType)
-		if err != nil {
-			return 0, err
-		}
+	return tag.RowsAffected(), nil
+}
 
-		switch transType {
-		case Credit:
-			result += amount
-		case Debit:
-			result -= amount
-		}
+// resolveIdempotencyKey returns the column value for a client-supplied key, falling back
+// to a generated key when the client didn't send one. Keys are unique per account, so clients
+// can't collide with each other's keys.
+func resolveIdempotencyKey(clientKey string, accountId id.Identifier, amount int64, transType TransactionType) string {
+	if clientKey == "" {
+		return generateIdempotencyKey(accountId, amount, transType)
 	}
 
-	return result, nil
+	return hashIdempotencyKey(clientKey)
 }
 
-func generateIdempotencyKey(accountId id.Identifier, amount int, transType TransactionType) string {
+// Both legs of a transfer share the client's key. The credit leg's key is derived from it, so it
+// can't be mistaken for a deposit to the destination made with the same key.
+func resolveTransferIdempotencyKeys(clientKey string, sourceAccountId, destAccountId id.Identifier, amount int64) (string, string) {
+	if clientKey == "" {
+		return generateIdempotencyKey(sourceAccountId, amount, Debit), generateIdempotencyKey(destAccountId, amount, Credit)
+	}
+
+	sourceKey := hashIdempotencyKey(clientKey)
+	return sourceKey, deriveIdempotencyKey(sourceKey, sourceAccountId.String(), Credit.String())
+}
+
+func generateIdempotencyKey(accountId id.Identifier, amount int64, transType TransactionType) string {
 	timestamp := time.Now().UTC().Format(time.RFC3339Nano)
-	key := fmt.Sprintf("%s-%d-%s-%s", accountId, amount, timestamp, transType)
+	return deriveIdempotencyKey(accountId.String(), fmt.Sprint(amount), timestamp, transType.String())
+}
+
+// derivedKeyPrefix starts the input of every key the server makes up, rather than takes from a
+// client. It's invalid UTF-8, which proto strings can't hold, so no client key hashes the same.
+const derivedKeyPrefix = "\xff"
+
+// deriveIdempotencyKey makes a key for a row the client didn't name, from whatever identifies it
+func deriveIdempotencyKey(parts ...string) string {
+	return hashIdempotencyKey(append([]string{derivedKeyPrefix}, parts...)...)
+}
+
+// Hashing keeps arbitrary client keys within the fixed width of the idempotency_key column
+func hashIdempotencyKey(parts ...string) string {
+	key := strings.Join(parts, "-")
 	hash := sha256.Sum256([]byte(key))
 	encoded := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(hash[:])
 	if len(encoded) > 32 {
@@ -338,10 +587,103 @@ func generateIdempotencyKey(accountId id.Identifier, amount int, transType Trans
 	return encoded
 }
 
-func transactionIsUnique(db *database.DatabasePool, ctx context.Context, key string) bool {
-	row := db.QueryRow(ctx, "SELECT count(1) from transactions where idempotency_key = $1", key)
-	var num int
-	row.Scan(&num)
+// accountCurrency looks up the currency an account is held in. Currencies can't change once
+// the account is created, so this doesn't need to happen inside the posting transaction.
+func accountCurrency(ctx context.Context, db *database.DatabasePool, accountId id.Identifier) (money.Currency, error) {
+	var currency money.Currency
+	err := db.QueryRow(ctx, `SELECT currency FROM accounts WHERE id = $1`, accountId).Scan(&currency)
+	return currency, err
+}
+
+func findTransactionByIdempotencyKey(ctx context.Context, db *database.DatabasePool, accountId id.Identifier, key string) (*Transaction, error) {
+	var t Transaction
+	row := db.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE account_id = $1 AND idempotency_key = $2`, accountId, key)
+	err := scanTransaction(row, &t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// replayTransaction returns the transaction previously stored on the account under key, or nil if
+// there isn't one. A stored transaction that doesn't match the request is a conflict.
+func replayTransaction(ctx context.Context, db *database.DatabasePool, key string, accountId id.Identifier, amount int64, transType TransactionType, description string) (*Transaction, error) {
+	existing, err := findTransactionByIdempotencyKey(ctx, db, accountId, key)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+
+	if !existing.replays(accountId, amount, transType, description) {
+		return nil, ErrIdempotencyConflict
+	}
+
+	return existing, nil
+}
+
+func replayTransfer(ctx context.Context, db *database.DatabasePool, sourceKey, destKey string, sourceAccountId, destAccountId id.Identifier, amount int64, description string) (*AccountTransferResp, error) {
+	source, err := replayTransaction(ctx, db, sourceKey, sourceAccountId, amount, Debit, description)
+	if err != nil || source == nil {
+		return nil, err
+	}
+
+	dest, err := replayTransaction(ctx, db, destKey, destAccountId, amount, Credit, description)
+	if err != nil {
+		return nil, err
+	}
+	if dest == nil {
+		return nil, ErrIdempotencyConflict
+	}
+
+	return &AccountTransferResp{
+		SourceTransaction:      *source,
+		DestinationTransaction: *dest,
+	}, nil
+}
+
+// recordFailedTransaction keeps an audit trail of a debit declined for reason, which it returns.
+// The row is stored under a generated key rather than the client's, so a retry is evaluated again
+// once the account can cover it.
+func recordFailedTransaction(ctx context.Context, db *database.DatabasePool, reason error, accountId id.Identifier, amount int64, currency money.Currency, transType TransactionType, description string) error {
+	id, err := id.New()
+	if err != nil {
+		return err
+	}
+
+	transaction := Transaction{
+		Id:              id,
+		IdempotencyKey:  generateIdempotencyKey(accountId, amount, transType),
+		AccountId:       accountId,
+		Amount:          amount,
+		Currency:        currency,
+		TransactionType: transType,
+		TransactionDate: time.Now().UTC(),
+		Status:          Failed,
+		Description:     &description,
+	}
+
+	sql, args := prepareInsertTransaction(transaction)
+	_, err = db.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to record failed transaction: %w", err)
+	}
+
+	return reason
+}
+
+// requireReplay is used after a unique violation, where a missing stored transaction means the
+// key collided with an unrelated request
+func requireReplay[T any](replayed *T, err error) (*T, error) {
+	if err == nil && replayed == nil {
+		return nil, ErrIdempotencyConflict
+	}
+	return replayed, err
+}
 
-	return num == 0
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
 }
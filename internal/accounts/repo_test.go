@@ -0,0 +1,95 @@
+package accounts
+
+import (
+	id "chariottakehome/internal/identifier"
+	"testing"
+)
+
+func TestTransactionMatches(t *testing.T) {
+	accountId, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+	otherId, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+
+	description := "rent"
+	stored := Transaction{AccountId: accountId, Amount: 500, TransactionType: Debit, Description: &description}
+
+	if !stored.matches(accountId, 500, Debit, "rent") {
+		t.Fatal("Expected a retry with the same payload to match")
+	}
+
+	cases := []struct {
+		accountId   id.Identifier
+		amount      int64
+		transType   TransactionType
+		description string
+	}{
+		{otherId, 500, Debit, "rent"},
+		{accountId, 501, Debit, "rent"},
+		{accountId, 500, Credit, "rent"},
+		{accountId, 500, Debit, "groceries"},
+	}
+	for _, c := range cases {
+		if stored.matches(c.accountId, c.amount, c.transType, c.description) {
+			t.Fatalf("Expected %+v not to match the stored transaction", c)
+		}
+	}
+
+	// A transaction stored without a description matches a request without one
+	if !(Transaction{AccountId: accountId, Amount: 500, TransactionType: Credit}).matches(accountId, 500, Credit, "") {
+		t.Fatal("Expected a missing description to match an empty one")
+	}
+}
+
+func TestResolveIdempotencyKey(t *testing.T) {
+	accountId, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+
+	// A retry with the client's key lands on the same row
+	key := resolveIdempotencyKey("order-1", accountId, 500, Debit)
+	if key != resolveIdempotencyKey("order-1", accountId, 500, Debit) {
+		t.Fatal("Expected a client key to resolve the same way every time")
+	}
+	if key == resolveIdempotencyKey("order-2", accountId, 500, Debit) {
+		t.Fatal("Expected different client keys to resolve differently")
+	}
+	if len(key) != 32 {
+		t.Fatalf("Expected keys to fit the idempotency_key column, got %q", key)
+	}
+
+	// Without a key every request is new
+	if resolveIdempotencyKey("", accountId, 500, Debit) == resolveIdempotencyKey("", accountId, 500, Debit) {
+		t.Fatal("Expected generated keys to differ")
+	}
+}
+
+func TestResolveTransferIdempotencyKeys(t *testing.T) {
+	source, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+	dest, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+
+	sourceKey, destKey := resolveTransferIdempotencyKeys("order-1", source, dest, 500)
+	retrySource, retryDest := resolveTransferIdempotencyKeys("order-1", source, dest, 500)
+	if sourceKey != retrySource || destKey != retryDest {
+		t.Fatal("Expected a retried transfer to resolve to the same keys")
+	}
+	if sourceKey == destKey {
+		t.Fatal("Expected the legs of a transfer to have different keys")
+	}
+
+	generatedSource, generatedDest := resolveTransferIdempotencyKeys("", source, dest, 500)
+	if generatedSource == sourceKey || generatedDest == destKey || generatedSource == generatedDest {
+		t.Fatal("Expected a transfer without a key to get keys of its own")
+	}
+}
@@ -0,0 +1,190 @@
+package accounts
+
+import (
+	"chariottakehome/internal/database"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReverseTransaction posts a journal entry compensating every leg of the entry the transaction
+// belongs to, so reversing either side of a transfer undoes both. A partial reversal refunds
+// amount on each leg, and without one whatever hasn't been reversed yet is refunded. A
+// transaction can be reversed in several parts, up to its original amount.
+func (r *accountRepository) ReverseTransaction(ctx context.Context, transactionId id.Identifier, amount *int64, description string, idempotencyKey string) (*JournalEntry, error) {
+	db := r.database
+
+	var journalEntryId *id.Identifier
+	err := db.QueryRow(ctx, `SELECT journal_entry_id FROM transactions WHERE id = $1`, transactionId).Scan(&journalEntryId)
+	if err != nil {
+		return nil, err
+	}
+	// Pending holds aren't part of an entry until they're captured
+	if journalEntryId == nil {
+		return nil, ErrNotReversible
+	}
+
+	if description == "" {
+		description = "Reversal of " + transactionId.String()
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Concurrent reversals of the entry queue here, so they can't add up to more than the original
+	originals, err := txLockEntryLegs(ctx, tx, *journalEntryId)
+	if err != nil {
+		return nil, err
+	}
+
+	reversals := make([]Transaction, 0, len(originals))
+	for _, original := range originals {
+		reversal, err := newReversalLeg(original.Transaction, original.remaining(), amount, description, idempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		reversals = append(reversals, reversal)
+	}
+
+	// A retry is answered before the checks, which the reversal it made may now fail
+	replayed, err := replayReversal(ctx, db, reversals[0], amount != nil)
+	if err != nil || replayed != nil {
+		return replayed, err
+	}
+
+	for i, original := range originals {
+		if err := checkReversal(original.Transaction, original.reversed, reversals[i].Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := newJournalEntry(description, reversals...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = txPostJournalEntry(ctx, tx, entry)
+	if isUniqueViolation(err) {
+		tx.Rollback(ctx)
+		return requireReplay(replayReversal(ctx, db, reversals[0], amount != nil))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = txAppendTransactionReversed(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Counted once per reversal rather than once per leg
+	recordMovement(reversalMovement, entry.Legs[0])
+	return &entry, nil
+}
+
+// reversedLeg is a leg of an entry being reversed, with how much of it earlier reversals refunded
+type reversedLeg struct {
+	Transaction
+	reversed int64
+}
+
+func (l reversedLeg) remaining() int64 {
+	return l.Amount - l.reversed
+}
+
+// txLockEntryLegs locks every leg of the journal entry in id order, along with how much of each
+// has already been reversed
+func txLockEntryLegs(ctx context.Context, tx pgx.Tx, journalEntryId id.Identifier) ([]reversedLeg, error) {
+	rows, err := tx.Query(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE journal_entry_id = $1 ORDER BY id FOR UPDATE`, journalEntryId)
+	if err != nil {
+		return nil, err
+	}
+
+	legs := make([]reversedLeg, 0)
+	for rows.Next() {
+		var leg reversedLeg
+		if err := scanTransaction(rows, &leg.Transaction); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		legs = append(legs, leg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range legs {
+		err := tx.QueryRow(ctx, reversedAmount, legs[i].Id).Scan(&legs[i].reversed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return legs, nil
+}
+
+// checkReversal refuses to refund amount on original when it isn't a completed transaction, or
+// earlier reversals leave less than amount of it to refund
+func checkReversal(original Transaction, reversed int64, amount int64) error {
+	if original.Status != Complete || original.ReversalOf != nil {
+		return ErrNotReversible
+	}
+
+	remaining := original.Amount - reversed
+	if remaining <= 0 {
+		return ErrAlreadyReversed
+	}
+	if amount > remaining {
+		return ErrReversalExceedsOriginal
+	}
+
+	return nil
+}
+
+// newReversalLeg builds the leg refunding amount, or remaining if amount is nil, of original.
+// Its idempotency key is derived from the client's key and the original leg, so a retry produces
+// the same legs.
+func newReversalLeg(original Transaction, remaining int64, amount *int64, description string, idempotencyKey string) (Transaction, error) {
+	reversalAmount := remaining
+	if amount != nil {
+		reversalAmount = *amount
+	}
+
+	transType := original.TransactionType.opposite()
+	key := resolveIdempotencyKey(idempotencyKey, original.AccountId, reversalAmount, transType)
+
+	reversal, err := newLeg(deriveIdempotencyKey(key, original.Id.String()), original.AccountId, reversalAmount, original.Currency, transType, description)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	originalId := original.Id
+	reversal.ReversalOf = &originalId
+
+	return reversal, nil
+}
+
+// replayReversal returns the entry posted by an earlier request with the same idempotency key as
+// reversal, or nil if there isn't one. A reversal that didn't name an amount refunded whatever was
+// left at the time, so its retries only need to match on the rest.
+func replayReversal(ctx context.Context, db *database.DatabasePool, reversal Transaction, matchAmount bool) (*JournalEntry, error) {
+	existing, err := findTransactionByIdempotencyKey(ctx, db, reversal.AccountId, reversal.IdempotencyKey)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+
+	if !existing.replaysReversal(reversal, matchAmount) {
+		return nil, ErrIdempotencyConflict
+	}
+
+	return findJournalEntry(ctx, db, *existing.JournalEntryId)
+}
@@ -0,0 +1,84 @@
+package accounts
+
+import (
+	id "chariottakehome/internal/identifier"
+	"errors"
+	"testing"
+)
+
+func TestCheckReversal(t *testing.T) {
+	original := Transaction{Amount: 1000, TransactionType: Credit, Status: Complete}
+
+	cases := []struct {
+		reversed int64
+		amount   int64
+		err      error
+	}{
+		{0, 1000, nil},
+		{0, 400, nil},
+		// What's left after a partial refund can still be refunded, in one go or in parts
+		{400, 600, nil},
+		{400, 100, nil},
+		{400, 601, ErrReversalExceedsOriginal},
+		{1000, 1, ErrAlreadyReversed},
+	}
+
+	for _, c := range cases {
+		if err := checkReversal(original, c.reversed, c.amount); !errors.Is(err, c.err) {
+			t.Fatalf("Expected %v refunding %d after %d, got %v", c.err, c.amount, c.reversed, err)
+		}
+	}
+
+	pending := original
+	pending.Status = Pending
+	if err := checkReversal(pending, 0, 100); !errors.Is(err, ErrNotReversible) {
+		t.Fatalf("Expected a pending transaction not to be reversible, got %v", err)
+	}
+
+	reversal := original
+	reversal.ReversalOf = &original.Id
+	if err := checkReversal(reversal, 0, 100); !errors.Is(err, ErrNotReversible) {
+		t.Fatalf("Expected a reversal not to be reversible, got %v", err)
+	}
+}
+
+func TestNewReversalLeg(t *testing.T) {
+	originalId, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+	accountId, err := id.New()
+	if err != nil {
+		t.Fatal("Failed to generate identifier")
+	}
+	original := Transaction{Id: originalId, AccountId: accountId, Amount: 1000, TransactionType: Debit, Status: Complete}
+
+	// Without an amount the remainder is refunded
+	rest, err := newReversalLeg(original, 600, nil, "refund", "refund-1")
+	if err != nil {
+		t.Fatalf("Failed to build reversal: %s", err)
+	}
+	if rest.Amount != 600 || rest.TransactionType != Credit || *rest.ReversalOf != originalId {
+		t.Fatalf("Expected a 600 credit reversing the original, got %+v", rest)
+	}
+
+	amount := int64(250)
+	partial, err := newReversalLeg(original, 600, &amount, "refund", "refund-1")
+	if err != nil {
+		t.Fatalf("Failed to build reversal: %s", err)
+	}
+	if partial.Amount != amount {
+		t.Fatalf("Expected a partial reversal of %d, got %d", amount, partial.Amount)
+	}
+	// The key only depends on the client's key and the original leg, so a retry finds the reversal
+	if partial.IdempotencyKey != rest.IdempotencyKey {
+		t.Fatalf("Expected retries of a reversal to share a key")
+	}
+
+	if !rest.replaysReversal(rest, true) || !rest.replaysReversal(partial, false) {
+		t.Fatalf("Expected a retry to replay the stored reversal")
+	}
+	if rest.replaysReversal(partial, true) {
+		t.Fatalf("Expected a retry for a different amount to conflict")
+	}
+}
@@ -2,7 +2,10 @@ package accounts
 
 import (
 	id "chariottakehome/internal/identifier"
+	"chariottakehome/internal/money"
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -10,12 +13,118 @@ import (
 
 const (
 	transactionInsert string = `INSERT INTO transactions (
-		id, idempotency_key, account_id, amount, transaction_type, transaction_date, status, description
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+		id, idempotency_key, account_id, amount, currency, transaction_type, transaction_date, status, description, journal_entry_id, authorized_amount, expires_at, reversal_of
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	transactionColumns string = `id, idempotency_key, account_id, amount, currency, transaction_type, transaction_date, status, description, journal_entry_id, authorized_amount, expires_at, reversal_of`
+
+	// The hold is re-dated to the capture, when the balance changes, and keeps when it was
+	// authorized for the spend limits
+	transactionCaptureHold string = `UPDATE transactions SET
+	amount = $2,
+	status = $3,
+	authorized_at = transaction_date,
+	transaction_date = $4,
+	journal_entry_id = $5
+	WHERE id = $1`
+
+	// Release the holds that lapsed before $1 from their accounts' held balances
+	expiredHoldsRelease string = `WITH expired AS (
+		UPDATE transactions SET status = 'failed'
+		WHERE status = 'pending'
+			AND expires_at <= $1
+		RETURNING account_id, authorized_amount
+	), released AS (
+		SELECT account_id, SUM(authorized_amount) AS amount FROM expired GROUP BY account_id
+	)
+	UPDATE accounts SET held_balance = accounts.held_balance - released.amount
+	FROM released
+	WHERE accounts.id = released.account_id`
+
+	// How much of a leg its reversals have refunded so far
+	reversedAmount string = `SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE reversal_of = $1`
+
+	journalEntryInsert string = `INSERT INTO journal_entries (
+	id, description, created_at
+	) VALUES ($1, $2, $3)`
+
+	// Postgres error code raised when a UNIQUE constraint is violated
+	uniqueViolationCode string = "23505"
 
 	accountInsert string = `INSERT INTO accounts (
-	id, user_id, name, balance, created_at, updated_at
-	) VALUES ($1, $2, $3, $4, $5, $6)`
+	id, user_id, name, balance, currency, overdraft_limit, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	accountColumns string = `id, user_id, name, balance, held_balance, currency, overdraft_limit, created_at, updated_at`
+
+	// System accounts for a currency are created the first time they're posted to
+	systemAccountUpsert string = `INSERT INTO accounts (
+	id, user_id, name, balance, currency, overdraft_limit, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, 0, $6, $6)
+	ON CONFLICT (id) DO UPDATE SET
+	balance = accounts.balance + EXCLUDED.balance,
+	updated_at = EXCLUDED.updated_at`
+
+	memberColumns string = `account_id, user_id, permissions, created_at, updated_at`
+
+	// Adding an existing member replaces their permissions
+	memberUpsert string = `INSERT INTO account_members (
+	account_id, user_id, permissions, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $4)
+	ON CONFLICT (account_id, user_id) DO UPDATE SET
+	permissions = EXCLUDED.permissions,
+	updated_at = EXCLUDED.updated_at
+	RETURNING ` + memberColumns
+
+	// The admins left on the account if the given user weren't one
+	otherAdminCount string = `SELECT COUNT(*)
+	FROM account_members
+	WHERE account_id = $1 AND user_id <> $2 AND 'admin' = ANY(permissions)`
+
+	// NULL arguments leave the existing value in place
+	accountUpdate string = `UPDATE accounts SET
+	name = COALESCE($2, name),
+	overdraft_limit = COALESCE($3, overdraft_limit),
+	updated_at = $4
+	WHERE id = $1
+	RETURNING ` + accountColumns
+)
+
+const (
+	// Transaction.signedAmount summed in SQL
+	signedAmountSum string = `COALESCE(SUM(CASE WHEN transaction_type = 'credit' THEN amount ELSE -amount END), 0)`
+
+	// Checkpoints every account with complete transactions since its latest snapshot, carrying
+	// that snapshot's balance forward. Accounts with no new activity keep their older snapshot.
+	balanceSnapshotInsert string = `INSERT INTO balance_snapshots (account_id, snapshot_at, balance)
+	SELECT a.id, $1, COALESCE(s.balance, 0) + ` + signedAmountSum + `
+	FROM accounts a
+	LEFT JOIN LATERAL (
+		SELECT snapshot_at, balance FROM balance_snapshots
+		WHERE account_id = a.id
+		ORDER BY snapshot_at DESC
+		LIMIT 1
+	) s ON true
+	JOIN transactions t ON t.account_id = a.id
+		AND t.status = 'complete'
+		AND t.transaction_date <= $1
+		AND (s.snapshot_at IS NULL OR t.transaction_date > s.snapshot_at)
+	WHERE s.snapshot_at IS NULL OR s.snapshot_at < $1
+	GROUP BY a.id, s.balance
+	ON CONFLICT DO NOTHING`
+
+	latestBalanceSnapshot string = `SELECT snapshot_at, balance FROM balance_snapshots
+	WHERE account_id = $1
+		AND snapshot_at <= $2
+	ORDER BY snapshot_at DESC
+	LIMIT 1`
+
+	// Sum of complete transactions in (from, to]
+	balanceDelta string = `SELECT ` + signedAmountSum + ` FROM transactions
+	WHERE account_id = $1
+		AND status = 'complete'
+		AND transaction_date > $2
+		AND transaction_date <= $3`
 )
 
 func prepareInsertTransaction(t Transaction) (string, []any) {
@@ -24,21 +133,69 @@ func prepareInsertTransaction(t Transaction) (string, []any) {
 		t.IdempotencyKey,
 		t.AccountId,
 		t.Amount,
+		t.Currency,
 		t.TransactionType,
 		t.TransactionDate,
 		t.Status,
 		t.Description,
+		t.JournalEntryId,
+		t.AuthorizedAmount,
+		t.ExpiresAt,
+		t.ReversalOf,
 	}
 
 	return transactionInsert, args
 }
 
+func scanTransaction(row pgx.Row, t *Transaction) error {
+	return row.Scan(
+		&t.Id,
+		&t.IdempotencyKey,
+		&t.AccountId,
+		&t.Amount,
+		&t.Currency,
+		&t.TransactionType,
+		&t.TransactionDate,
+		&t.Status,
+		&t.Description,
+		&t.JournalEntryId,
+		&t.AuthorizedAmount,
+		&t.ExpiresAt,
+		&t.ReversalOf,
+	)
+}
+
+// prepareCaptureHold turns a hold into the completed debit leg of the journal entry capturing it
+func prepareCaptureHold(t Transaction) (string, []any) {
+	args := []any{
+		t.Id,
+		t.Amount,
+		t.Status.String(),
+		t.TransactionDate,
+		t.JournalEntryId,
+	}
+
+	return transactionCaptureHold, args
+}
+
+func prepareInsertJournalEntry(j JournalEntry) (string, []any) {
+	args := []any{
+		j.Id,
+		j.Description,
+		j.CreatedAt,
+	}
+
+	return journalEntryInsert, args
+}
+
 func prepareInsertAccount(a Account) (string, []any) {
 	args := []any{
 		a.Id,
 		a.UserId,
 		a.Name,
 		a.Balance,
+		a.Currency,
+		a.OverdraftLimit,
 		a.CreatedAt,
 		a.UpdatedAt,
 	}
@@ -46,24 +203,363 @@ func prepareInsertAccount(a Account) (string, []any) {
 	return accountInsert, args
 }
 
-func txAccountBalanceUpdate(ctx context.Context, tx pgx.Tx, accountId id.Identifier, changeType TransactionType, amount int) error {
-	var balance int
-	err := tx.QueryRow(ctx, "SELECT balance FROM accounts WHERE id = $1 FOR UPDATE", accountId).Scan(&balance)
+func scanAccount(row pgx.Row, a *Account) error {
+	return row.Scan(
+		&a.Id,
+		&a.UserId,
+		&a.Name,
+		&a.Balance,
+		&a.HeldBalance,
+		&a.Currency,
+		&a.OverdraftLimit,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+}
+
+func prepareUpdateAccount(accountId id.Identifier, name *string, overdraftLimit *int64) (string, []any) {
+	args := []any{
+		accountId,
+		name,
+		overdraftLimit,
+		time.Now().UTC(),
+	}
+
+	return accountUpdate, args
+}
+
+// prepareListTransactions builds a keyset query for a page of an account's transactions. It
+// fetches one row more than the page size so the caller can tell whether another page follows.
+func prepareListTransactions(accountId id.Identifier, page Page, filter TransactionFilter) (string, []any) {
+	args := []any{accountId}
+	conditions := []string{"account_id = $1"}
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	order, seek := "ASC", "id > $%d"
+	if page.Direction == Backward {
+		order, seek = "DESC", "id < $%d"
+	}
+	if page.Cursor != nil {
+		where(seek, *page.Cursor)
+	}
+
+	if filter.From != nil {
+		where("transaction_date >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		where("transaction_date <= $%d", *filter.To)
+	}
+	if filter.TransactionType != nil {
+		where("transaction_type = $%d", filter.TransactionType.String())
+	}
+	if filter.Status != nil {
+		where("status = $%d", filter.Status.String())
+	}
+	if filter.MinAmount != nil {
+		where("amount >= $%d", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		where("amount <= $%d", *filter.MaxAmount)
+	}
+
+	args = append(args, page.Size+1)
+	sql := fmt.Sprintf(`SELECT %s
+	FROM transactions
+	WHERE %s
+	ORDER BY id %s
+	LIMIT $%d`, transactionColumns, strings.Join(conditions, "\n\t\tAND "), order, len(args))
+
+	return sql, args
+}
+
+type lockedAccount struct {
+	balance        money.Money
+	heldBalance    int64
+	overdraftLimit int64
+}
+
+// available is the balance left once active holds are set aside
+func (a lockedAccount) available() (money.Money, error) {
+	return a.balance.Sub(money.New(a.heldBalance, a.balance.Currency))
+}
+
+// checkOverdraft rejects a change that left the available balance below the overdraft limit
+func (a lockedAccount) checkOverdraft() error {
+	available, err := a.available()
+	if err != nil {
+		return err
+	}
+	if available.Amount < -a.overdraftLimit {
+		return ErrInsufficientFunds
+	}
+
+	return nil
+}
+
+// txLockAccount locks a customer account's row for the rest of the transaction. Postings in a
+// different currency to the account are rejected with ErrCurrencyMismatch.
+func txLockAccount(ctx context.Context, tx pgx.Tx, accountId id.Identifier, currency money.Currency) (lockedAccount, error) {
+	if IsSystemAccount(accountId) {
+		return lockedAccount{}, ErrSystemAccount
+	}
+
+	var a lockedAccount
+	err := tx.QueryRow(ctx, "SELECT balance, currency, held_balance, overdraft_limit FROM accounts WHERE id = $1 FOR UPDATE", accountId).Scan(
+		&a.balance.Amount,
+		&a.balance.Currency,
+		&a.heldBalance,
+		&a.overdraftLimit,
+	)
+	if err != nil {
+		return lockedAccount{}, err
+	}
+
+	if a.balance.Currency != currency {
+		return lockedAccount{}, ErrCurrencyMismatch
+	}
+
+	return a, nil
+}
+
+// txLockAccounts locks several accounts at once. Rows are locked in the order they're returned,
+// so every transaction taking more than one account lock takes them in the same order.
+func txLockAccounts(ctx context.Context, tx pgx.Tx, accountIds []string) error {
+	if len(accountIds) < 2 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `SELECT id FROM accounts WHERE id = ANY($1) ORDER BY id COLLATE "C" FOR UPDATE`, accountIds)
+	return err
+}
+
+// txAccountBalanceUpdate applies a change to the locked account row. Debits that would take the
+// available balance below the account's overdraft limit are rejected with ErrInsufficientFunds,
+// and changes that would overflow the balance with money.ErrOverflow.
+func txAccountBalanceUpdate(ctx context.Context, tx pgx.Tx, accountId id.Identifier, currency money.Currency, changeType TransactionType, amount int64) error {
+	account, err := txLockAccount(ctx, tx, accountId, currency)
 	if err != nil {
 		return err
 	}
 
+	change := money.New(amount, currency)
 	switch changeType {
 	case Credit:
-		balance += amount
+		account.balance, err = account.balance.Add(change)
 	case Debit:
-		balance -= amount
+		account.balance, err = account.balance.Sub(change)
+	}
+	if err != nil {
+		return err
+	}
+
+	if changeType == Debit {
+		if err := account.checkOverdraft(); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE accounts SET balance = $1, updated_at = $2 WHERE id = $3`, account.balance.Amount, time.Now().UTC(), accountId)
+	return err
+}
+
+// txPlaceHold reserves amount of the account's available balance without changing its ledger
+// balance, rejecting holds the available balance and overdraft limit can't cover
+func txPlaceHold(ctx context.Context, tx pgx.Tx, accountId id.Identifier, currency money.Currency, amount int64) error {
+	account, err := txLockAccount(ctx, tx, accountId, currency)
+	if err != nil {
+		return err
+	}
+
+	held, err := money.New(account.heldBalance, currency).Add(money.New(amount, currency))
+	if err != nil {
+		return err
+	}
+	account.heldBalance = held.Amount
+
+	if err := account.checkOverdraft(); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE accounts SET held_balance = $1, updated_at = $2 WHERE id = $3`, account.heldBalance, time.Now().UTC(), accountId)
+	return err
+}
+
+// txSettleHold releases a hold of authorized and debits captured from the ledger balance in its
+// place. The funds were reserved when the hold was placed so the overdraft limit isn't checked
+// again. Voiding a hold settles it with nothing captured.
+func txSettleHold(ctx context.Context, tx pgx.Tx, accountId id.Identifier, currency money.Currency, authorized int64, captured int64) error {
+	account, err := txLockAccount(ctx, tx, accountId, currency)
+	if err != nil {
+		return err
+	}
+
+	account.balance, err = account.balance.Sub(money.New(captured, currency))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE accounts SET balance = $1, held_balance = held_balance - $2, updated_at = $3 WHERE id = $4`,
+		account.balance.Amount, authorized, time.Now().UTC(), accountId)
+	return err
+}
+
+// txSystemBalanceUpdate applies a change to a system account, creating it if needed. System
+// accounts mirror money outside the ledger so they have no overdraft limit.
+func txSystemBalanceUpdate(ctx context.Context, tx pgx.Tx, accountId id.Identifier, currency money.Currency, changeType TransactionType, amount int64) error {
+	if changeType == Debit {
+		amount = -amount
 	}
 
-	_, err = tx.Exec(ctx, `UPDATE accounts SET balance = $1, updated_at = $2 WHERE id = $3`, balance, time.Now().UTC(), accountId)
+	_, err := tx.Exec(ctx, systemAccountUpsert, accountId, SystemUserId, systemAccountName(accountId), amount, currency, time.Now().UTC())
+	return err
+}
+
+func scanMember(row pgx.Row, m *Member) error {
+	var permissions []string
+	err := row.Scan(&m.AccountId, &m.UserId, &permissions, &m.CreatedAt, &m.UpdatedAt)
 	if err != nil {
 		return err
 	}
 
+	m.Permissions = make([]Permission, len(permissions))
+	for i, permission := range permissions {
+		if err := m.Permissions[i].Scan(permission); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+func permissionStrings(permissions []Permission) []string {
+	results := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		results = append(results, permission.String())
+	}
+
+	return results
+}
+
+func txUpsertMember(ctx context.Context, tx pgx.Tx, accountId id.Identifier, userId id.Identifier, permissions []Permission, now time.Time) (*Member, error) {
+	var member Member
+	row := tx.QueryRow(ctx, memberUpsert, accountId, userId, permissionStrings(permissions), now)
+	if err := scanMember(row, &member); err != nil {
+		return nil, fmt.Errorf("failed to upsert member: %w", err)
+	}
+
+	return &member, nil
+}
+
+const (
+	limitTierColumns string = `name, daily_withdrawal_amount, hourly_transfer_count, monthly_outbound_amount, created_at, updated_at`
+
+	// Setting an existing tier replaces its limits
+	limitTierUpsert string = `INSERT INTO limit_tiers (
+	name, daily_withdrawal_amount, hourly_transfer_count, monthly_outbound_amount, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $5)
+	ON CONFLICT (name) DO UPDATE SET
+	daily_withdrawal_amount = EXCLUDED.daily_withdrawal_amount,
+	hourly_transfer_count = EXCLUDED.hourly_transfer_count,
+	monthly_outbound_amount = EXCLUDED.monthly_outbound_amount,
+	updated_at = EXCLUDED.updated_at
+	RETURNING ` + limitTierColumns
+
+	accountLimitsUpsert string = `INSERT INTO account_limits (
+	account_id, tier, daily_withdrawal_amount, hourly_transfer_count, monthly_outbound_amount, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $6)
+	ON CONFLICT (account_id) DO UPDATE SET
+	tier = EXCLUDED.tier,
+	daily_withdrawal_amount = EXCLUDED.daily_withdrawal_amount,
+	hourly_transfer_count = EXCLUDED.hourly_transfer_count,
+	monthly_outbound_amount = EXCLUDED.monthly_outbound_amount,
+	updated_at = EXCLUDED.updated_at`
+
+	// Accounts without limits of their own still get a row, with every column NULL
+	accountLimitsSelect string = `SELECT a.id, l.tier,
+		l.daily_withdrawal_amount, l.hourly_transfer_count, l.monthly_outbound_amount,
+		t.daily_withdrawal_amount, t.hourly_transfer_count, t.monthly_outbound_amount
+	FROM accounts a
+	LEFT JOIN account_limits l ON l.account_id = a.id
+	LEFT JOIN limit_tiers t ON t.name = l.tier
+	WHERE a.id = $1`
+
+	// What account $1 has withdrawn since $3, transferred out since $4, and moved out in total
+	// since $2. A debit is a transfer when another customer account is on the other side of its
+	// journal entry. Holds count as withdrawals until they lapse at $5 and reversals don't count.
+	// Captured holds count from when they were authorized, which is never after transaction_date,
+	// so the transaction_date condition only narrows the rows read.
+	outboundSelect string = `SELECT
+		COALESCE(SUM(t.amount) FILTER (WHERE NOT c.transfer AND s.spent_at >= $3), 0),
+		COUNT(*) FILTER (WHERE c.transfer AND s.spent_at >= $4),
+		COALESCE(SUM(t.amount), 0)
+	FROM transactions t
+	CROSS JOIN LATERAL (
+		SELECT COALESCE(t.authorized_at, t.transaction_date) AS spent_at
+	) s
+	CROSS JOIN LATERAL (
+		SELECT EXISTS (
+			SELECT 1 FROM transactions o
+			JOIN accounts a ON a.id = o.account_id
+			WHERE o.journal_entry_id = t.journal_entry_id
+				AND o.id <> t.id
+				AND a.user_id <> $6
+		) AS transfer
+	) c
+	WHERE t.account_id = $1
+		AND t.transaction_type = 'debit'
+		AND t.reversal_of IS NULL
+		AND t.transaction_date >= $2
+		AND s.spent_at >= $2
+		AND (t.status = 'complete' OR (t.status = 'pending' AND t.expires_at > $5))`
+)
+
+func prepareUpsertLimitTier(name string, limits Limits, now time.Time) (string, []any) {
+	return limitTierUpsert, []any{name, limits.DailyWithdrawalAmount, limits.HourlyTransferCount, limits.MonthlyOutboundAmount, now}
+}
+
+func scanLimitTier(row pgx.Row, t *LimitTier) error {
+	return row.Scan(
+		&t.Name,
+		&t.Limits.DailyWithdrawalAmount,
+		&t.Limits.HourlyTransferCount,
+		&t.Limits.MonthlyOutboundAmount,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+}
+
+func prepareUpsertAccountLimits(accountId id.Identifier, tier *string, limits Limits, now time.Time) (string, []any) {
+	return accountLimitsUpsert, []any{accountId, tier, limits.DailyWithdrawalAmount, limits.HourlyTransferCount, limits.MonthlyOutboundAmount, now}
+}
+
+func scanAccountLimits(row pgx.Row, l *AccountLimits) error {
+	var tierLimits Limits
+	err := row.Scan(
+		&l.AccountId,
+		&l.Tier,
+		&l.Limits.DailyWithdrawalAmount,
+		&l.Limits.HourlyTransferCount,
+		&l.Limits.MonthlyOutboundAmount,
+		&tierLimits.DailyWithdrawalAmount,
+		&tierLimits.HourlyTransferCount,
+		&tierLimits.MonthlyOutboundAmount,
+	)
+	if err != nil {
+		return err
+	}
+
+	l.Effective = l.Limits.orElse(tierLimits)
+	return nil
+}
+
+func prepareSelectOutbound(accountId id.Identifier, at time.Time) (string, []any) {
+	w := windowsAt(at)
+	return outboundSelect, []any{accountId, w.month, w.day, w.hour, at, SystemUserId}
+}
+
+func scanOutbound(row pgx.Row, o *outbound) error {
+	return row.Scan(&o.withdrawn, &o.transfers, &o.volume)
+}
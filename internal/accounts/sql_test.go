@@ -0,0 +1,34 @@
+package accounts
+
+import (
+	"chariottakehome/internal/money"
+	"errors"
+	"testing"
+)
+
+func TestCheckOverdraft(t *testing.T) {
+	cases := []struct {
+		balance        int64
+		held           int64
+		overdraftLimit int64
+		err            error
+	}{
+		{0, 0, 0, nil},
+		{-1, 0, 0, ErrInsufficientFunds},
+		// The overdraft limit is how far below zero the available balance may go
+		{-500, 0, 500, nil},
+		{-501, 0, 500, ErrInsufficientFunds},
+		// Held funds aren't available, even though they're still in the ledger balance
+		{300, 300, 0, nil},
+		{300, 301, 0, ErrInsufficientFunds},
+		{300, 800, 500, nil},
+		{300, 801, 500, ErrInsufficientFunds},
+	}
+
+	for _, c := range cases {
+		account := lockedAccount{balance: money.New(c.balance, money.USD), heldBalance: c.held, overdraftLimit: c.overdraftLimit}
+		if err := account.checkOverdraft(); !errors.Is(err, c.err) {
+			t.Fatalf("Expected %v for %+v, got %v", c.err, c, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package accounts
+
+import (
+	id "chariottakehome/internal/identifier"
+	"chariottakehome/internal/logging"
+	"chariottakehome/internal/money"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName string = "chariottakehome/internal/accounts"
+
+// tracedRepository wraps each repository call in a span, which the SQL statement spans it makes
+// nest under
+type tracedRepository struct {
+	next AccountRepository
+}
+
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "AccountRepository."+method)
+	// Ties the span to the request's log line
+	if requestId := logging.RequestId(ctx); requestId != "" {
+		span.SetAttributes(attribute.String("request_id", requestId))
+	}
+
+	return ctx, span
+}
+
+// endSpan ends span, marking it failed if err is set, and returns err
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	return err
+}
+
+func (r *tracedRepository) CreateAccount(ctx context.Context, userId id.Identifier, name string, currency money.Currency, overdraftLimit int64) (*Account, error) {
+	ctx, span := startSpan(ctx, "CreateAccount")
+	account, err := r.next.CreateAccount(ctx, userId, name, currency, overdraftLimit)
+	return account, endSpan(span, err)
+}
+
+func (r *tracedRepository) UpdateAccount(ctx context.Context, accountId id.Identifier, name *string, overdraftLimit *int64) (*Account, error) {
+	ctx, span := startSpan(ctx, "UpdateAccount")
+	account, err := r.next.UpdateAccount(ctx, accountId, name, overdraftLimit)
+	return account, endSpan(span, err)
+}
+
+func (r *tracedRepository) GetAccount(ctx context.Context, accountId id.Identifier) (*Account, error) {
+	ctx, span := startSpan(ctx, "GetAccount")
+	account, err := r.next.GetAccount(ctx, accountId)
+	return account, endSpan(span, err)
+}
+
+func (r *tracedRepository) ListAccounts(ctx context.Context, userId id.Identifier, startCursor *id.Identifier, pageSize int) (*ListAccountsResp, error) {
+	ctx, span := startSpan(ctx, "ListAccounts")
+	resp, err := r.next.ListAccounts(ctx, userId, startCursor, pageSize)
+	return resp, endSpan(span, err)
+}
+
+func (r *tracedRepository) DepositFunds(ctx context.Context, accountId id.Identifier, amount int64, description string, idempotencyKey string) (*Transaction, error) {
+	ctx, span := startSpan(ctx, "DepositFunds")
+	transaction, err := r.next.DepositFunds(ctx, accountId, amount, description, idempotencyKey)
+	return transaction, endSpan(span, err)
+}
+
+func (r *tracedRepository) WithdrawFunds(ctx context.Context, accountId id.Identifier, amount int64, description string, idempotencyKey string) (*Transaction, error) {
+	ctx, span := startSpan(ctx, "WithdrawFunds")
+	transaction, err := r.next.WithdrawFunds(ctx, accountId, amount, description, idempotencyKey)
+	return transaction, endSpan(span, err)
+}
+
+func (r *tracedRepository) AccountTransfer(ctx context.Context, sourceAccountId, destAccountId id.Identifier, amount int64, description string, idempotencyKey string) (*AccountTransferResp, error) {
+	ctx, span := startSpan(ctx, "AccountTransfer")
+	resp, err := r.next.AccountTransfer(ctx, sourceAccountId, destAccountId, amount, description, idempotencyKey)
+	return resp, endSpan(span, err)
+}
+
+func (r *tracedRepository) GetTransaction(ctx context.Context, transactionId id.Identifier) (*Transaction, error) {
+	ctx, span := startSpan(ctx, "GetTransaction")
+	transaction, err := r.next.GetTransaction(ctx, transactionId)
+	return transaction, endSpan(span, err)
+}
+
+func (r *tracedRepository) ListTransactions(ctx context.Context, accountId id.Identifier, page Page, filter TransactionFilter) (*ListTransactionsResp, error) {
+	ctx, span := startSpan(ctx, "ListTransactions")
+	resp, err := r.next.ListTransactions(ctx, accountId, page, filter)
+	return resp, endSpan(span, err)
+}
+
+func (r *tracedRepository) AuthorizeWithdrawal(ctx context.Context, accountId id.Identifier, amount int64, description string, idempotencyKey string, expiresAt time.Time) (*Transaction, error) {
+	ctx, span := startSpan(ctx, "AuthorizeWithdrawal")
+	hold, err := r.next.AuthorizeWithdrawal(ctx, accountId, amount, description, idempotencyKey, expiresAt)
+	return hold, endSpan(span, err)
+}
+
+func (r *tracedRepository) CaptureTransaction(ctx context.Context, transactionId id.Identifier, amount *int64) (*Transaction, error) {
+	ctx, span := startSpan(ctx, "CaptureTransaction")
+	hold, err := r.next.CaptureTransaction(ctx, transactionId, amount)
+	return hold, endSpan(span, err)
+}
+
+func (r *tracedRepository) VoidTransaction(ctx context.Context, transactionId id.Identifier) (*Transaction, error) {
+	ctx, span := startSpan(ctx, "VoidTransaction")
+	hold, err := r.next.VoidTransaction(ctx, transactionId)
+	return hold, endSpan(span, err)
+}
+
+func (r *tracedRepository) ReverseTransaction(ctx context.Context, transactionId id.Identifier, amount *int64, description string, idempotencyKey string) (*JournalEntry, error) {
+	ctx, span := startSpan(ctx, "ReverseTransaction")
+	entry, err := r.next.ReverseTransaction(ctx, transactionId, amount, description, idempotencyKey)
+	return entry, endSpan(span, err)
+}
+
+func (r *tracedRepository) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "ExpireHolds")
+	count, err := r.next.ExpireHolds(ctx, now)
+	return count, endSpan(span, err)
+}
+
+func (r *tracedRepository) GetBalance(ctx context.Context, accountId id.Identifier, timestamp *time.Time) (money.Money, error) {
+	ctx, span := startSpan(ctx, "GetBalance")
+	balance, err := r.next.GetBalance(ctx, accountId, timestamp)
+	return balance, endSpan(span, err)
+}
+
+func (r *tracedRepository) SnapshotBalances(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "SnapshotBalances")
+	count, err := r.next.SnapshotBalances(ctx, cutoff)
+	return count, endSpan(span, err)
+}
+
+func (r *tracedRepository) GetJournalEntry(ctx context.Context, journalEntryId id.Identifier) (*JournalEntry, error) {
+	ctx, span := startSpan(ctx, "GetJournalEntry")
+	entry, err := r.next.GetJournalEntry(ctx, journalEntryId)
+	return entry, endSpan(span, err)
+}
+
+func (r *tracedRepository) AddAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier, permissions []Permission) (*Member, error) {
+	ctx, span := startSpan(ctx, "AddAccountMember")
+	member, err := r.next.AddAccountMember(ctx, accountId, userId, permissions)
+	return member, endSpan(span, err)
+}
+
+func (r *tracedRepository) RemoveAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier) (*Member, error) {
+	ctx, span := startSpan(ctx, "RemoveAccountMember")
+	member, err := r.next.RemoveAccountMember(ctx, accountId, userId)
+	return member, endSpan(span, err)
+}
+
+func (r *tracedRepository) GetAccountMember(ctx context.Context, accountId id.Identifier, userId id.Identifier) (*Member, error) {
+	ctx, span := startSpan(ctx, "GetAccountMember")
+	member, err := r.next.GetAccountMember(ctx, accountId, userId)
+	return member, endSpan(span, err)
+}
+
+func (r *tracedRepository) ListAccountMembers(ctx context.Context, accountId id.Identifier) ([]Member, error) {
+	ctx, span := startSpan(ctx, "ListAccountMembers")
+	members, err := r.next.ListAccountMembers(ctx, accountId)
+	return members, endSpan(span, err)
+}
+
+func (r *tracedRepository) SetLimitTier(ctx context.Context, name string, limits Limits) (*LimitTier, error) {
+	ctx, span := startSpan(ctx, "SetLimitTier")
+	tier, err := r.next.SetLimitTier(ctx, name, limits)
+	return tier, endSpan(span, err)
+}
+
+func (r *tracedRepository) GetLimitTier(ctx context.Context, name string) (*LimitTier, error) {
+	ctx, span := startSpan(ctx, "GetLimitTier")
+	tier, err := r.next.GetLimitTier(ctx, name)
+	return tier, endSpan(span, err)
+}
+
+func (r *tracedRepository) SetAccountLimits(ctx context.Context, accountId id.Identifier, tier *string, limits Limits) (*AccountLimits, error) {
+	ctx, span := startSpan(ctx, "SetAccountLimits")
+	accountLimits, err := r.next.SetAccountLimits(ctx, accountId, tier, limits)
+	return accountLimits, endSpan(span, err)
+}
+
+func (r *tracedRepository) GetAccountLimits(ctx context.Context, accountId id.Identifier) (*AccountLimits, error) {
+	ctx, span := startSpan(ctx, "GetAccountLimits")
+	limits, err := r.next.GetAccountLimits(ctx, accountId)
+	return limits, endSpan(span, err)
+}
+
+func (r *tracedRepository) GetLimitUsage(ctx context.Context, accountId id.Identifier) ([]LimitUsage, error) {
+	ctx, span := startSpan(ctx, "GetLimitUsage")
+	usage, err := r.next.GetLimitUsage(ctx, accountId)
+	return usage, endSpan(span, err)
+}
@@ -0,0 +1,18 @@
+package apikeys
+
+import (
+	"chariottakehome/internal/auth"
+	id "chariottakehome/internal/identifier"
+	"time"
+)
+
+type ApiKey struct {
+	Id        id.Identifier
+	UserId    id.Identifier
+	Name      string
+	Role      auth.Role
+	KeyHash   []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	RevokedAt *time.Time
+}
@@ -0,0 +1,127 @@
+package apikeys
+
+import (
+	"chariottakehome/internal/auth"
+	"chariottakehome/internal/database"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ApiKeyRepository interface {
+	CreateApiKey(ctx context.Context, userId id.Identifier, name string, role auth.Role) (*ApiKey, string, error)
+	GetApiKey(ctx context.Context, keyId id.Identifier) (*ApiKey, error)
+	ListApiKeys(ctx context.Context, userId id.Identifier) ([]ApiKey, error)
+	RevokeApiKey(ctx context.Context, keyId id.Identifier) (*ApiKey, error)
+	VerifyApiKey(ctx context.Context, key string) (*auth.Principal, error)
+}
+
+type apiKeyRepository struct {
+	database *database.DatabasePool
+}
+
+func NewRepo(database *database.DatabasePool) ApiKeyRepository {
+	return &apiKeyRepository{database}
+}
+
+// CreateApiKey issues a key for the user, returning it alongside the stored record. Only the
+// key's hash is kept, so this is the only time it's available.
+func (r *apiKeyRepository) CreateApiKey(ctx context.Context, userId id.Identifier, name string, role auth.Role) (*ApiKey, string, error) {
+	keyId, err := id.New()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := auth.NewApiKey(keyId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now().UTC()
+	apiKey := ApiKey{
+		Id:        keyId,
+		UserId:    userId,
+		Name:      name,
+		Role:      role,
+		KeyHash:   auth.HashApiKey(key),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	sql, args := prepareInsertApiKey(apiKey)
+	_, err = r.database.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &apiKey, key, nil
+}
+
+func (r *apiKeyRepository) GetApiKey(ctx context.Context, keyId id.Identifier) (*ApiKey, error) {
+	var apiKey ApiKey
+	row := r.database.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, keyId)
+	if err := scanApiKey(row, &apiKey); err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (r *apiKeyRepository) ListApiKeys(ctx context.Context, userId id.Identifier) ([]ApiKey, error) {
+	rows, err := r.database.Query(ctx, `SELECT `+apiKeyColumns+`
+	FROM api_keys
+	WHERE user_id = $1
+	ORDER BY id`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]ApiKey, 0)
+	for rows.Next() {
+		var k ApiKey
+		if err := scanApiKey(rows, &k); err != nil {
+			return nil, err
+		}
+
+		results = append(results, k)
+	}
+
+	return results, rows.Err()
+}
+
+// RevokeApiKey stops the key authenticating. Revoking a key twice returns it unchanged.
+func (r *apiKeyRepository) RevokeApiKey(ctx context.Context, keyId id.Identifier) (*ApiKey, error) {
+	var apiKey ApiKey
+	if err := scanApiKey(r.database.QueryRow(ctx, apiKeyRevoke, keyId, time.Now().UTC()), &apiKey); err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+// VerifyApiKey returns the principal for a key that exists and hasn't been revoked
+func (r *apiKeyRepository) VerifyApiKey(ctx context.Context, key string) (*auth.Principal, error) {
+	keyId, err := auth.ParseApiKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := r.GetApiKey(ctx, keyId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(apiKey.KeyHash, auth.HashApiKey(key)) != 1 || apiKey.RevokedAt != nil {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	return &auth.Principal{UserId: apiKey.UserId, Role: apiKey.Role, Method: auth.ApiKey, CredentialId: apiKey.Id.String()}, nil
+}
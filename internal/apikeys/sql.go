@@ -0,0 +1,26 @@
+package apikeys
+
+import "github.com/jackc/pgx/v5"
+
+const (
+	insertApiKey string = `INSERT INTO api_keys (
+	id, user_id, name, role, key_hash, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	apiKeyColumns string = `id, user_id, name, role, key_hash, created_at, updated_at, revoked_at`
+
+	apiKeyRevoke string = `UPDATE api_keys
+	SET revoked_at = COALESCE(revoked_at, $2)
+	WHERE id = $1
+	RETURNING ` + apiKeyColumns
+)
+
+func prepareInsertApiKey(k ApiKey) (string, []any) {
+	args := []any{k.Id, k.UserId, k.Name, k.Role.String(), k.KeyHash, k.CreatedAt, k.UpdatedAt}
+
+	return insertApiKey, args
+}
+
+func scanApiKey(row pgx.Row, k *ApiKey) error {
+	return row.Scan(&k.Id, &k.UserId, &k.Name, &k.Role, &k.KeyHash, &k.CreatedAt, &k.UpdatedAt, &k.RevokedAt)
+}
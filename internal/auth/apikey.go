@@ -0,0 +1,59 @@
+package auth
+
+import (
+	id "chariottakehome/internal/identifier"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// apiKeyPrefix marks a bearer token as an API key rather than a JWT
+const apiKeyPrefix string = "chk_"
+
+// ApiKeyVerifier looks up who an API key was issued to
+type ApiKeyVerifier interface {
+	VerifyApiKey(ctx context.Context, key string) (*Principal, error)
+}
+
+// NewApiKey generates the key for keyId, in the form chk_<key id>_<secret>. The id lets the key
+// be found without storing anything but its hash.
+func NewApiKey(keyId id.Identifier) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return apiKeyPrefix + keyId.String() + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// ParseApiKey returns the id of the key, without checking the secret
+func ParseApiKey(key string) (id.Identifier, error) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return id.Identifier{}, ErrInvalidCredentials
+	}
+
+	keyId, secret, ok := strings.Cut(rest, "_")
+	if !ok || secret == "" {
+		return id.Identifier{}, ErrInvalidCredentials
+	}
+
+	parsed, err := id.FromString(keyId)
+	if err != nil {
+		return id.Identifier{}, ErrInvalidCredentials
+	}
+
+	return parsed, nil
+}
+
+// HashApiKey is what's stored in place of the key. The secret is random, so a fast hash is enough.
+func HashApiKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
+
+func isApiKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
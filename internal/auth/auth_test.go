@@ -0,0 +1,215 @@
+package auth_test
+
+import (
+	"chariottakehome/internal/auth"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testKid      string = "test-key"
+	testIssuer   string = "https://issuer.example"
+	testAudience string = "chariot"
+)
+
+func newUserId(t *testing.T) id.Identifier {
+	userId, err := id.New()
+	if err != nil {
+		t.Fatalf("Failed to generate identifier: %s", err)
+	}
+
+	return userId
+}
+
+func newSigningKey(t *testing.T) (*rsa.PrivateKey, *auth.KeySet) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+
+	jwks := fmt.Sprintf(`{"keys": [{"kty": "RSA", "kid": %q, "use": "sig", "n": %q, "e": %q}]}`,
+		testKid,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	)
+	keys, err := auth.ParseKeySet([]byte(jwks))
+	if err != nil {
+		t.Fatalf("Failed to parse JWKS: %s", err)
+	}
+
+	return key, keys
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.RegisteredClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %s", err)
+	}
+
+	return signed
+}
+
+func validClaims(subject string) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   subject,
+		Issuer:    testIssuer,
+		Audience:  jwt.ClaimStrings{testAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		ID:        "token-id",
+	}
+}
+
+func TestApiKeyRoundTrip(t *testing.T) {
+	keyId := newUserId(t)
+	key, err := auth.NewApiKey(keyId)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %s", err)
+	}
+
+	parsed, err := auth.ParseApiKey(key)
+	if err != nil {
+		t.Fatalf("Failed to parse API key: %s", err)
+	}
+	if parsed != keyId {
+		t.Fatalf("Expected key id %s, got %s", keyId, parsed)
+	}
+}
+
+func TestParseApiKeyRejectsMalformedKeys(t *testing.T) {
+	for _, key := range []string{"", "chk_", "chk_notanid_secret", "xyz_c-0000000000000000aa_secret", "chk_c-0000000000000000aa_"} {
+		if _, err := auth.ParseApiKey(key); !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("Expected '%s' to be rejected, got %v", key, err)
+		}
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	key, keys := newSigningKey(t)
+	verifier := auth.NewTokenVerifier(keys, testIssuer, testAudience)
+	userId := newUserId(t)
+
+	principal, err := verifier.Verify(signToken(t, key, validClaims(userId.String())))
+	if err != nil {
+		t.Fatalf("Failed to verify token: %s", err)
+	}
+	if principal.UserId != userId || principal.Method != auth.Jwt || principal.CredentialId != "token-id" {
+		t.Fatalf("Unexpected principal %+v", principal)
+	}
+}
+
+func TestVerifyTokenRejections(t *testing.T) {
+	key, keys := newSigningKey(t)
+	otherKey, _ := newSigningKey(t)
+	verifier := auth.NewTokenVerifier(keys, testIssuer, testAudience)
+	subject := newUserId(t).String()
+
+	expired := validClaims(subject)
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	wrongIssuer := validClaims(subject)
+	wrongIssuer.Issuer = "https://someone.else"
+	wrongAudience := validClaims(subject)
+	wrongAudience.Audience = jwt.ClaimStrings{"other"}
+	noExpiry := validClaims(subject)
+	noExpiry.ExpiresAt = nil
+
+	cases := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"expired", signToken(t, key, expired), auth.ErrExpiredCredentials},
+		{"wrong issuer", signToken(t, key, wrongIssuer), auth.ErrInvalidCredentials},
+		{"wrong audience", signToken(t, key, wrongAudience), auth.ErrInvalidCredentials},
+		{"no expiry", signToken(t, key, noExpiry), auth.ErrInvalidCredentials},
+		{"subject isn't a user", signToken(t, key, validClaims("someone")), auth.ErrInvalidCredentials},
+		{"signed by another key", signToken(t, otherKey, validClaims(subject)), auth.ErrInvalidCredentials},
+		{"unsigned", "eyJhbGciOiJub25lIn0.e30.", auth.ErrInvalidCredentials},
+	}
+
+	for _, c := range cases {
+		if _, err := verifier.Verify(c.token); !errors.Is(err, c.err) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+type staticApiKeys struct {
+	key       string
+	principal *auth.Principal
+}
+
+func (s staticApiKeys) VerifyApiKey(ctx context.Context, key string) (*auth.Principal, error) {
+	if key != s.key {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return s.principal, nil
+}
+
+func callUnary(a *auth.Authenticator, method string, authorization string) (*auth.Principal, error) {
+	ctx := context.Background()
+	if authorization != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+	}
+
+	var principal *auth.Principal
+	_, err := a.UnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, _ = auth.FromContext(ctx)
+		return nil, nil
+	})
+
+	return principal, err
+}
+
+func TestInterceptor(t *testing.T) {
+	key, keys := newSigningKey(t)
+	userId := newUserId(t)
+	apiKey, err := auth.NewApiKey(newUserId(t))
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %s", err)
+	}
+
+	a := &auth.Authenticator{
+		ApiKeys: staticApiKeys{key: apiKey, principal: &auth.Principal{UserId: userId, Method: auth.ApiKey}},
+		Tokens:  auth.NewTokenVerifier(keys, testIssuer, testAudience),
+		Public:  map[string]bool{"/test.Service/Public": true},
+	}
+
+	principal, err := callUnary(a, "/test.Service/Private", "Bearer "+apiKey)
+	if err != nil || principal == nil || principal.Method != auth.ApiKey {
+		t.Fatalf("Expected API key principal, got %+v, %v", principal, err)
+	}
+
+	principal, err = callUnary(a, "/test.Service/Private", "bearer "+signToken(t, key, validClaims(userId.String())))
+	if err != nil || principal == nil || principal.UserId != userId || principal.Method != auth.Jwt {
+		t.Fatalf("Expected JWT principal, got %+v, %v", principal, err)
+	}
+
+	principal, err = callUnary(a, "/test.Service/Public", "")
+	if err != nil || principal != nil {
+		t.Fatalf("Expected public call without a principal, got %+v, %v", principal, err)
+	}
+
+	for _, authorization := range []string{"", "Basic abc", "Bearer chk_wrong", "Bearer not-a-jwt"} {
+		_, err = callUnary(a, "/test.Service/Private", authorization)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("Expected Unauthenticated for '%s', got %v", authorization, err)
+		}
+	}
+}
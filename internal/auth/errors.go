@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type errReason string
+
+const (
+	MissingCredentials errReason = "No credentials were provided."
+	InvalidCredentials errReason = "Credentials are invalid."
+	ExpiredCredentials errReason = "Credentials have expired."
+)
+
+// AuthError deliberately doesn't say why a credential was rejected beyond it having expired
+type AuthError struct {
+	reason errReason
+}
+
+func (e AuthError) Error() string {
+	return "Authentication failed: " + string(e.reason)
+}
+
+func (e AuthError) GRPCStatus() *status.Status {
+	return status.New(codes.Unauthenticated, e.Error())
+}
+
+const (
+	NotOwner     errReason = "Caller does not have access to this resource."
+	ReadOnly     errReason = "Caller only has read access."
+	OperatorOnly errReason = "Only operators can do this."
+	NotPermitted errReason = "Caller's access doesn't allow this."
+)
+
+// PermissionError is returned when an authenticated caller isn't allowed to make a call
+type PermissionError struct {
+	reason errReason
+}
+
+func (e PermissionError) Error() string {
+	return "Permission denied: " + string(e.reason)
+}
+
+func (e PermissionError) GRPCStatus() *status.Status {
+	return status.New(codes.PermissionDenied, e.Error())
+}
+
+var (
+	ErrMissingCredentials = AuthError{reason: MissingCredentials}
+	ErrInvalidCredentials = AuthError{reason: InvalidCredentials}
+	ErrExpiredCredentials = AuthError{reason: ExpiredCredentials}
+)
+
+var (
+	ErrNotOwner     = PermissionError{reason: NotOwner}
+	ErrReadOnly     = PermissionError{reason: ReadOnly}
+	ErrOperatorOnly = PermissionError{reason: OperatorOnly}
+	ErrNotPermitted = PermissionError{reason: NotPermitted}
+)
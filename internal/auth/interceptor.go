@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Authenticator rejects calls without a valid bearer token, which is either an API key or a JWT
+type Authenticator struct {
+	ApiKeys ApiKeyVerifier
+	// Tokens is nil when JWTs aren't accepted
+	Tokens *TokenVerifier
+	// Public is the set of full method names that can be called without credentials
+	Public map[string]bool
+}
+
+// Authenticate returns the principal for the bearer token in the call's metadata
+func (a *Authenticator) Authenticate(ctx context.Context) (*Principal, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return nil, ErrMissingCredentials
+	}
+
+	if isApiKey(token) {
+		return a.ApiKeys.VerifyApiKey(ctx, token)
+	}
+	if a.Tokens == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return a.Tokens.Verify(token)
+}
+
+func (a *Authenticator) UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, err := a.authenticateCall(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, err := a.authenticateCall(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+func (a *Authenticator) authenticateCall(ctx context.Context, method string) (context.Context, error) {
+	if a.Public[method] {
+		return ctx, nil
+	}
+
+	principal, err := a.Authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewContext(ctx, principal), nil
+}
+
+// authenticatedStream hands the handler a context carrying the principal
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	for _, value := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(value, " ")
+		if ok && strings.EqualFold(scheme, "bearer") && token != "" {
+			return strings.TrimSpace(token), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	id "chariottakehome/internal/identifier"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Tolerated clock skew between us and the token issuer
+const tokenLeeway time.Duration = 30 * time.Second
+
+// TokenVerifier checks JWTs signed by one of a KeySet's keys. The token's subject must be the
+// id of the user it was issued for, and its role claim is the principal's role, Owner if absent.
+type TokenVerifier struct {
+	keys   *KeySet
+	parser *jwt.Parser
+}
+
+// NewTokenVerifier only checks the issuer and audience claims when they're not empty
+func NewTokenVerifier(keys *KeySet, issuer string, audience string) *TokenVerifier {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(tokenLeeway),
+	}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+
+	return &TokenVerifier{keys: keys, parser: jwt.NewParser(options...)}
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+}
+
+func (v *TokenVerifier) Verify(token string) (*Principal, error) {
+	var claims claims
+	_, err := v.parser.ParseWithClaims(token, &claims, v.keyFunc)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrExpiredCredentials
+	}
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	userId, err := id.FromString(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	role := Owner
+	if claims.Role != "" {
+		if err := role.Scan(claims.Role); err != nil {
+			return nil, ErrInvalidCredentials
+		}
+	}
+
+	return &Principal{UserId: userId, Role: role, Method: Jwt, CredentialId: claims.ID}, nil
+}
+
+func (v *TokenVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := v.keys.key(kid)
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	id "chariottakehome/internal/identifier"
+	"errors"
+)
+
+// Role is what a credential allows, on top of which user it belongs to
+type Role int
+
+const (
+	// Owner can read and move money for the user's own accounts
+	Owner Role = iota
+	// Viewer can read the user's own accounts but not change anything
+	Viewer
+	// Operator can act on any user's accounts
+	Operator
+)
+
+func (r Role) String() string {
+	switch r {
+	case Owner:
+		return "owner"
+	case Viewer:
+		return "viewer"
+	case Operator:
+		return "operator"
+	default:
+		return ""
+	}
+}
+
+func (r *Role) Scan(value interface{}) error {
+	if value == nil {
+		return errors.New("nil value")
+	}
+
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return errors.New("unsupported data type")
+	}
+
+	switch str {
+	case "owner":
+		*r = Owner
+	case "viewer":
+		*r = Viewer
+	case "operator":
+		*r = Operator
+	default:
+		return errors.New("unsupported string value")
+	}
+
+	return nil
+}
+
+type Action int
+
+const (
+	Read Action = iota
+	Write
+)
+
+// Authorize decides whether the principal may take action on a resource belonging to owner
+func Authorize(principal *Principal, owner id.Identifier, action Action) error {
+	isOwner := principal != nil && principal.UserId == owner
+	return AuthorizeShared(principal, isOwner, isOwner, action)
+}
+
+// AuthorizeShared decides whether the principal may take action on a resource shared between
+// users. member is whether the principal's user has been given access to it at all, and permitted
+// whether that access covers the action.
+func AuthorizeShared(principal *Principal, member bool, permitted bool, action Action) error {
+	if err := AuthorizeRole(principal, Viewer); err != nil {
+		return err
+	}
+	if principal.Role == Operator {
+		return nil
+	}
+
+	if !member {
+		return ErrNotOwner
+	}
+	if action == Write && principal.Role == Viewer {
+		return ErrReadOnly
+	}
+	if !permitted {
+		return ErrNotPermitted
+	}
+
+	return nil
+}
+
+// AuthorizeRole requires the principal to hold at least role, where Viewer < Owner < Operator
+func AuthorizeRole(principal *Principal, role Role) error {
+	if principal == nil {
+		return ErrNotOwner
+	}
+
+	if rank(principal.Role) < rank(role) {
+		if principal.Role == Viewer {
+			return ErrReadOnly
+		}
+		return ErrOperatorOnly
+	}
+
+	return nil
+}
+
+func rank(role Role) int {
+	switch role {
+	case Viewer:
+		return 0
+	case Owner:
+		return 1
+	case Operator:
+		return 2
+	default:
+		return -1
+	}
+}
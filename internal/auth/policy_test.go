@@ -0,0 +1,130 @@
+package auth_test
+
+import (
+	"chariottakehome/internal/auth"
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAuthorize(t *testing.T) {
+	user, other := newUserId(t), newUserId(t)
+
+	cases := []struct {
+		name   string
+		role   auth.Role
+		owner  bool
+		action auth.Action
+		err    error
+	}{
+		{"owner reads own", auth.Owner, true, auth.Read, nil},
+		{"owner writes own", auth.Owner, true, auth.Write, nil},
+		{"owner reads other's", auth.Owner, false, auth.Read, auth.ErrNotOwner},
+		{"owner writes other's", auth.Owner, false, auth.Write, auth.ErrNotOwner},
+		{"viewer reads own", auth.Viewer, true, auth.Read, nil},
+		{"viewer writes own", auth.Viewer, true, auth.Write, auth.ErrReadOnly},
+		{"viewer reads other's", auth.Viewer, false, auth.Read, auth.ErrNotOwner},
+		{"operator reads other's", auth.Operator, false, auth.Read, nil},
+		{"operator writes other's", auth.Operator, false, auth.Write, nil},
+	}
+
+	for _, c := range cases {
+		owner := other
+		if c.owner {
+			owner = user
+		}
+
+		err := auth.Authorize(&auth.Principal{UserId: user, Role: c.role}, owner, c.action)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+	}
+
+	if err := auth.Authorize(nil, user, auth.Read); !errors.Is(err, auth.ErrNotOwner) {
+		t.Fatalf("Expected a missing principal to be denied, got %v", err)
+	}
+}
+
+func TestAuthorizeRole(t *testing.T) {
+	cases := []struct {
+		role     auth.Role
+		required auth.Role
+		err      error
+	}{
+		{auth.Viewer, auth.Viewer, nil},
+		{auth.Viewer, auth.Owner, auth.ErrReadOnly},
+		{auth.Owner, auth.Owner, nil},
+		{auth.Owner, auth.Operator, auth.ErrOperatorOnly},
+		{auth.Operator, auth.Operator, nil},
+	}
+
+	for _, c := range cases {
+		err := auth.AuthorizeRole(&auth.Principal{Role: c.role}, c.required)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("%s requiring %s: expected %v, got %v", c.role, c.required, c.err, err)
+		}
+	}
+}
+
+func TestVerifyTokenRole(t *testing.T) {
+	key, keys := newSigningKey(t)
+	verifier := auth.NewTokenVerifier(keys, testIssuer, testAudience)
+	userId := newUserId(t)
+
+	sign := func(role string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub":  userId.String(),
+			"iss":  testIssuer,
+			"aud":  testAudience,
+			"exp":  jwt.NewNumericDate(validClaims("").ExpiresAt.Time),
+			"role": role,
+		})
+		token.Header["kid"] = testKid
+
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %s", err)
+		}
+		return signed
+	}
+
+	principal, err := verifier.Verify(sign("operator"))
+	if err != nil || principal.Role != auth.Operator {
+		t.Fatalf("Expected an operator, got %+v, %v", principal, err)
+	}
+
+	principal, err = verifier.Verify(signToken(t, key, validClaims(userId.String())))
+	if err != nil || principal.Role != auth.Owner {
+		t.Fatalf("Expected an owner by default, got %+v, %v", principal, err)
+	}
+
+	if _, err := verifier.Verify(sign("superuser")); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Fatalf("Expected an unknown role to be rejected, got %v", err)
+	}
+}
+
+func TestAuthorizeShared(t *testing.T) {
+	cases := []struct {
+		name      string
+		role      auth.Role
+		member    bool
+		permitted bool
+		action    auth.Action
+		err       error
+	}{
+		{"permitted member", auth.Owner, true, true, auth.Write, nil},
+		{"member without the permission", auth.Owner, true, false, auth.Write, auth.ErrNotPermitted},
+		{"not a member", auth.Owner, false, false, auth.Read, auth.ErrNotOwner},
+		{"viewer writing as a permitted member", auth.Viewer, true, true, auth.Write, auth.ErrReadOnly},
+		{"viewer reading as a permitted member", auth.Viewer, true, true, auth.Read, nil},
+		{"operator who isn't a member", auth.Operator, false, false, auth.Write, nil},
+	}
+
+	for _, c := range cases {
+		err := auth.AuthorizeShared(&auth.Principal{UserId: newUserId(t), Role: c.role}, c.member, c.permitted, c.action)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Package auth authenticates gRPC calls. Callers present either an API key or a JWT as a bearer
+// token, and the principal they authenticate as is carried in the request's context.
+package auth
+
+import (
+	id "chariottakehome/internal/identifier"
+	"context"
+)
+
+type Method int
+
+const (
+	ApiKey Method = iota
+	Jwt
+)
+
+func (m Method) String() string {
+	switch m {
+	case ApiKey:
+		return "api_key"
+	case Jwt:
+		return "jwt"
+	default:
+		return ""
+	}
+}
+
+// Principal is who a call was authenticated as
+type Principal struct {
+	UserId id.Identifier
+	Role   Role
+	Method Method
+	// CredentialId is the API key's id, or the token's jti if it has one
+	CredentialId string
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal the call was authenticated as. It's only missing for public
+// methods.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok
+}
@@ -0,0 +1,403 @@
+// Package config loads the server's settings. Each setting has a default, which is overridden in
+// turn by an optional YAML file, environment variables, and command line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// ListenAddr is where the gRPC server listens
+	ListenAddr string `yaml:"listen_addr"`
+	// HttpListenAddr is where the REST gateway listens, with no gateway if it's empty
+	HttpListenAddr string `yaml:"http_listen_addr"`
+	// RequestTimeout bounds each unary call, with no limit if zero
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// ShutdownTimeout is how long in-flight calls get to finish on shutdown before they're cut off
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	LogLevel        slog.Level    `yaml:"log_level"`
+	Database        Database      `yaml:"database"`
+	Auth            Auth          `yaml:"auth"`
+	Telemetry       Telemetry     `yaml:"telemetry"`
+	RateLimit       RateLimit     `yaml:"rate_limit"`
+	Webhooks        Webhooks      `yaml:"webhooks"`
+	Accounts        Accounts      `yaml:"accounts"`
+	// CursorSecret signs pagination cursors. A random key is used when it's empty.
+	CursorSecret string `yaml:"cursor_secret"`
+}
+
+type Database struct {
+	Dsn      string `yaml:"dsn"`
+	MinConns int32  `yaml:"min_conns"`
+	MaxConns int32  `yaml:"max_conns"`
+	// ConnectTimeout bounds a single connection attempt
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// StartupTimeout is how long to keep retrying before giving up on the database at startup
+	StartupTimeout time.Duration `yaml:"startup_timeout"`
+}
+
+type Auth struct {
+	// JwksFile holds the keys JWTs are verified against. Only API keys are accepted without one.
+	JwksFile    string `yaml:"jwks_file"`
+	JwtIssuer   string `yaml:"jwt_issuer"`
+	JwtAudience string `yaml:"jwt_audience"`
+}
+
+// Trace exporters
+const (
+	TraceExporterNone   string = "none"
+	TraceExporterOtlp   string = "otlp"
+	TraceExporterStdout string = "stdout"
+)
+
+type Telemetry struct {
+	// MetricsListenAddr is where Prometheus metrics are served, with none if it's empty
+	MetricsListenAddr string `yaml:"metrics_listen_addr"`
+	// TraceExporter is one of none, otlp, or stdout
+	TraceExporter string `yaml:"trace_exporter"`
+	// OtlpEndpoint is the host:port of the OTLP/gRPC collector traces are sent to
+	OtlpEndpoint string `yaml:"otlp_endpoint"`
+	// OtlpInsecure sends traces to the collector without TLS
+	OtlpInsecure bool `yaml:"otlp_insecure"`
+}
+
+type Webhooks struct {
+	// AllowHttp accepts endpoints without TLS. It's meant for development only, as deliveries and
+	// their signatures would be sent in the clear.
+	AllowHttp bool `yaml:"allow_http"`
+}
+
+type Accounts struct {
+	// MaxTransactionAmount caps a single deposit, withdrawal, or transfer in minor units, with the
+	// service's default if zero
+	MaxTransactionAmount int64 `yaml:"max_transaction_amount"`
+	// HoldTTL is how long an authorization hold lasts before it lapses, with the service's
+	// default if zero
+	HoldTTL time.Duration `yaml:"hold_ttl"`
+}
+
+// Rate limit backends
+const (
+	RateLimitBackendNone     string = "none"
+	RateLimitBackendMemory   string = "memory"
+	RateLimitBackendPostgres string = "postgres"
+)
+
+type RateLimit struct {
+	// Backend is one of none, memory, or postgres. Buckets are per process in memory, and shared
+	// between replicas in Postgres.
+	Backend string `yaml:"backend"`
+	// Default limits each principal's calls to each method without a limit of its own
+	Default Limit `yaml:"default"`
+	// Methods limits particular methods, by full method name
+	Methods map[string]Limit `yaml:"methods"`
+	// AuthFailures limits how often each client address can fail authentication
+	AuthFailures Limit `yaml:"auth_failures"`
+}
+
+// Limit is a token bucket refilled at Rate tokens a second, holding up to Burst. A Rate of zero
+// means no limit.
+type Limit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int32   `yaml:"burst"`
+}
+
+func Default() Config {
+	return Config{
+		ListenAddr:      ":8080",
+		HttpListenAddr:  ":8081",
+		ShutdownTimeout: 30 * time.Second,
+		LogLevel:        slog.LevelInfo,
+		Database: Database{
+			MinConns:       0,
+			MaxConns:       10,
+			ConnectTimeout: 5 * time.Second,
+			StartupTimeout: 30 * time.Second,
+		},
+		Telemetry: Telemetry{
+			// Metrics describe internal traffic, so they're only served locally unless asked
+			MetricsListenAddr: "127.0.0.1:9090",
+			TraceExporter:     TraceExporterNone,
+			OtlpEndpoint:      "localhost:4317",
+		},
+		RateLimit: RateLimit{
+			Backend:      RateLimitBackendMemory,
+			Default:      Limit{Rate: 20, Burst: 40},
+			AuthFailures: Limit{Rate: 0.1, Burst: 10},
+		},
+	}
+}
+
+// Load reads the configuration for the command line args, returning the args left after the
+// flags. The YAML file is named by the -config flag or CONFIG_FILE.
+func Load(args []string) (Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("chariottakehome", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file")
+	flags := cfg.flags(fs)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return Config{}, nil, err
+	}
+
+	// Only flags that were actually given override the file and environment
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if set, ok := flags[f.Name]; ok && err == nil {
+			err = set(f.Value.String())
+		}
+	})
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+func (c *Config) Validate() error {
+	if c.ListenAddr == "" {
+		return errors.New("listen address is required")
+	}
+	if c.Database.Dsn == "" {
+		return errors.New("database DSN is required")
+	}
+	if c.Database.MaxConns < 1 {
+		return errors.New("database max conns must be at least 1")
+	}
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		return fmt.Errorf("database min conns must be between 0 and %d", c.Database.MaxConns)
+	}
+	if c.RequestTimeout < 0 || c.ShutdownTimeout < 0 || c.Database.ConnectTimeout < 0 || c.Database.StartupTimeout < 0 {
+		return errors.New("timeouts cannot be negative")
+	}
+
+	if c.Accounts.MaxTransactionAmount < 0 {
+		return errors.New("max transaction amount cannot be negative")
+	}
+	if c.Accounts.HoldTTL < 0 {
+		return errors.New("hold TTL cannot be negative")
+	}
+
+	switch c.Telemetry.TraceExporter {
+	case TraceExporterNone, TraceExporterStdout:
+	case TraceExporterOtlp:
+		if c.Telemetry.OtlpEndpoint == "" {
+			return errors.New("OTLP endpoint is required to export traces over OTLP")
+		}
+	default:
+		return fmt.Errorf("unknown trace exporter %q", c.Telemetry.TraceExporter)
+	}
+
+	switch c.RateLimit.Backend {
+	case RateLimitBackendNone, RateLimitBackendMemory, RateLimitBackendPostgres:
+	default:
+		return fmt.Errorf("unknown rate limit backend %q", c.RateLimit.Backend)
+	}
+	if err := c.RateLimit.Default.validate(); err != nil {
+		return fmt.Errorf("invalid default rate limit: %w", err)
+	}
+	if err := c.RateLimit.AuthFailures.validate(); err != nil {
+		return fmt.Errorf("invalid auth failure rate limit: %w", err)
+	}
+	for method, limit := range c.RateLimit.Methods {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("invalid rate limit for %s: %w", method, err)
+		}
+	}
+
+	return nil
+}
+
+func (l Limit) validate() error {
+	if l.Rate < 0 {
+		return errors.New("rate cannot be negative")
+	}
+	// A bucket that can't hold a whole token would never allow a call
+	if l.Rate > 0 && l.Burst < 1 {
+		return errors.New("burst must be at least 1")
+	}
+
+	return nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// setter parses a setting's value from a string
+type setter func(value string) error
+
+type setting struct {
+	env  string
+	flag string
+	set  setter
+}
+
+// settings lists what can be set from the environment, and from flags if they have a flag name.
+// Secrets don't get flags since they'd show up in process listings.
+func (c *Config) settings() []setting {
+	return []setting{
+		{"LISTEN_ADDR", "listen", stringSetter(&c.ListenAddr)},
+		{"HTTP_LISTEN_ADDR", "http-listen", stringSetter(&c.HttpListenAddr)},
+		{"REQUEST_TIMEOUT", "request-timeout", durationSetter(&c.RequestTimeout)},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", durationSetter(&c.ShutdownTimeout)},
+		{"LOG_LEVEL", "log-level", func(value string) error { return c.LogLevel.UnmarshalText([]byte(value)) }},
+		{"DATABASE_URL", "db-dsn", stringSetter(&c.Database.Dsn)},
+		{"DB_MIN_CONNS", "db-min-conns", int32Setter(&c.Database.MinConns)},
+		{"DB_MAX_CONNS", "db-max-conns", int32Setter(&c.Database.MaxConns)},
+		{"DB_CONNECT_TIMEOUT", "db-connect-timeout", durationSetter(&c.Database.ConnectTimeout)},
+		{"DB_STARTUP_TIMEOUT", "db-startup-timeout", durationSetter(&c.Database.StartupTimeout)},
+		{"JWKS_FILE", "jwks-file", stringSetter(&c.Auth.JwksFile)},
+		{"JWT_ISSUER", "jwt-issuer", stringSetter(&c.Auth.JwtIssuer)},
+		{"JWT_AUDIENCE", "jwt-audience", stringSetter(&c.Auth.JwtAudience)},
+		{"CURSOR_SECRET", "", stringSetter(&c.CursorSecret)},
+		{"METRICS_LISTEN_ADDR", "metrics-listen", stringSetter(&c.Telemetry.MetricsListenAddr)},
+		{"TRACE_EXPORTER", "trace-exporter", stringSetter(&c.Telemetry.TraceExporter)},
+		{"OTLP_ENDPOINT", "otlp-endpoint", stringSetter(&c.Telemetry.OtlpEndpoint)},
+		{"OTLP_INSECURE", "otlp-insecure", boolSetter(&c.Telemetry.OtlpInsecure)},
+		{"RATE_LIMIT_BACKEND", "rate-limit-backend", stringSetter(&c.RateLimit.Backend)},
+		{"RATE_LIMIT_RATE", "rate-limit-rate", float64Setter(&c.RateLimit.Default.Rate)},
+		{"RATE_LIMIT_BURST", "rate-limit-burst", int32Setter(&c.RateLimit.Default.Burst)},
+		{"RATE_LIMIT_AUTH_FAILURE_RATE", "rate-limit-auth-failure-rate", float64Setter(&c.RateLimit.AuthFailures.Rate)},
+		{"RATE_LIMIT_AUTH_FAILURE_BURST", "rate-limit-auth-failure-burst", int32Setter(&c.RateLimit.AuthFailures.Burst)},
+		{"WEBHOOK_ALLOW_HTTP", "webhook-allow-http", boolSetter(&c.Webhooks.AllowHttp)},
+		{"MAX_TRANSACTION_AMOUNT", "max-transaction-amount", int64Setter(&c.Accounts.MaxTransactionAmount)},
+		{"HOLD_TTL", "hold-ttl", durationSetter(&c.Accounts.HoldTTL)},
+	}
+}
+
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	for _, s := range c.settings() {
+		if value, ok := lookup(s.env); ok && value != "" {
+			if err := s.set(value); err != nil {
+				return fmt.Errorf("invalid %s: %w", s.env, err)
+			}
+		}
+	}
+
+	// Falls back to the libpq-style variables the docker-compose setup provides
+	if c.Database.Dsn == "" {
+		if host, ok := lookup("PGHOST"); ok && host != "" {
+			user, _ := lookup("PGUSER")
+			password, _ := lookup("POSTGRES_PASSWORD")
+			dbname, _ := lookup("POSTGRES_DB")
+			c.Database.Dsn = (&url.URL{
+				Scheme: "postgres",
+				User:   url.UserPassword(user, password),
+				Host:   host,
+				Path:   dbname,
+			}).String()
+		}
+	}
+
+	return nil
+}
+
+// flags registers the settings' flags, returning their setters by flag name
+func (c *Config) flags(fs *flag.FlagSet) map[string]setter {
+	flags := make(map[string]setter)
+	for _, s := range c.settings() {
+		if s.flag == "" {
+			continue
+		}
+
+		fs.String(s.flag, "", "overrides "+s.env)
+		flags[s.flag] = s.set
+	}
+
+	return flags
+}
+
+func stringSetter(target *string) setter {
+	return func(value string) error {
+		*target = value
+		return nil
+	}
+}
+
+func durationSetter(target *time.Duration) setter {
+	return func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*target = d
+		return nil
+	}
+}
+
+func int32Setter(target *int32) setter {
+	return func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		*target = int32(n)
+		return nil
+	}
+}
+
+func int64Setter(target *int64) setter {
+	return func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*target = n
+		return nil
+	}
+}
+
+func float64Setter(target *float64) setter {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*target = f
+		return nil
+	}
+}
+
+func boolSetter(target *bool) setter {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*target = b
+		return nil
+	}
+}
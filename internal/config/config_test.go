@@ -0,0 +1,176 @@
+package config_test
+
+import (
+	"chariottakehome/internal/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets everything Load reads so the host environment can't leak into a test
+func clearEnv(t *testing.T) {
+	for _, name := range []string{
+		"CONFIG_FILE", "LISTEN_ADDR", "HTTP_LISTEN_ADDR", "REQUEST_TIMEOUT", "SHUTDOWN_TIMEOUT", "LOG_LEVEL", "DATABASE_URL", "DB_MIN_CONNS",
+		"DB_MAX_CONNS", "DB_CONNECT_TIMEOUT", "DB_STARTUP_TIMEOUT", "JWKS_FILE", "JWT_ISSUER",
+		"JWT_AUDIENCE", "CURSOR_SECRET", "PGHOST", "PGUSER", "POSTGRES_PASSWORD", "POSTGRES_DB",
+		"METRICS_LISTEN_ADDR", "TRACE_EXPORTER", "OTLP_ENDPOINT", "OTLP_INSECURE", "RATE_LIMIT_BACKEND",
+		"RATE_LIMIT_RATE", "RATE_LIMIT_BURST", "WEBHOOK_ALLOW_HTTP",
+		"MAX_TRANSACTION_AMOUNT", "HOLD_TTL", "RATE_LIMIT_AUTH_FAILURE_RATE", "RATE_LIMIT_AUTH_FAILURE_BURST",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %s", err)
+	}
+
+	return path
+}
+
+func TestDefaults(t *testing.T) {
+	clearEnv(t)
+
+	cfg, args, err := config.Load([]string{"-db-dsn", "postgres://localhost/bank"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %s", err)
+	}
+
+	if len(args) != 0 {
+		t.Fatalf("Expected no remaining args, got %v", args)
+	}
+	if cfg.ListenAddr != ":8080" || cfg.LogLevel != slog.LevelInfo || cfg.Database.MaxConns != 10 {
+		t.Fatalf("Expected defaults, got %+v", cfg)
+	}
+	if cfg.Telemetry.MetricsListenAddr != "127.0.0.1:9090" {
+		t.Fatalf("Expected metrics to only be served locally, got %q", cfg.Telemetry.MetricsListenAddr)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, `
+listen_addr: ":9000"
+request_timeout: 2s
+log_level: debug
+database:
+  dsn: postgres://file/bank
+  max_conns: 20
+  startup_timeout: 1m
+accounts:
+  max_transaction_amount: 5000
+  hold_ttl: 72h
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_MAX_CONNS", "30")
+	t.Setenv("LISTEN_ADDR", ":9001")
+
+	cfg, args, err := config.Load([]string{"-listen", ":9002", "create-api-key", "user"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %s", err)
+	}
+
+	if cfg.ListenAddr != ":9002" {
+		t.Fatalf("Expected the flag to win, got %s", cfg.ListenAddr)
+	}
+	if cfg.Database.MaxConns != 30 {
+		t.Fatalf("Expected the environment to override the file, got %d", cfg.Database.MaxConns)
+	}
+	if cfg.Database.Dsn != "postgres://file/bank" || cfg.RequestTimeout != 2*time.Second ||
+		cfg.Database.StartupTimeout != time.Minute || cfg.LogLevel != slog.LevelDebug ||
+		cfg.Accounts.MaxTransactionAmount != 5000 || cfg.Accounts.HoldTTL != 72*time.Hour {
+		t.Fatalf("Expected settings from the file, got %+v", cfg)
+	}
+	if cfg.Database.ConnectTimeout != 5*time.Second {
+		t.Fatalf("Expected the default connect timeout, got %s", cfg.Database.ConnectTimeout)
+	}
+	if len(args) != 2 || args[0] != "create-api-key" {
+		t.Fatalf("Expected the command args to be left, got %v", args)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, `
+database:
+  dsn: postgres://file/bank
+rate_limit:
+  backend: postgres
+  default:
+    rate: 5
+    burst: 10
+  methods:
+    /users.AccountService/WithdrawFunds:
+      rate: 0.5
+      burst: 2
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("RATE_LIMIT_RATE", "7.5")
+
+	cfg, _, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %s", err)
+	}
+
+	if cfg.RateLimit.Backend != config.RateLimitBackendPostgres || cfg.RateLimit.Default != (config.Limit{Rate: 7.5, Burst: 10}) {
+		t.Fatalf("Expected the default limit from the file and environment, got %+v", cfg.RateLimit)
+	}
+	if cfg.RateLimit.Methods["/users.AccountService/WithdrawFunds"] != (config.Limit{Rate: 0.5, Burst: 2}) {
+		t.Fatalf("Expected the method's limit from the file, got %+v", cfg.RateLimit.Methods)
+	}
+	if cfg.RateLimit.AuthFailures != (config.Limit{Rate: 0.1, Burst: 10}) {
+		t.Fatalf("Expected the default auth failure limit, got %+v", cfg.RateLimit.AuthFailures)
+	}
+}
+
+func TestPostgresFallback(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("PGHOST", "db")
+	t.Setenv("PGUSER", "bank")
+	t.Setenv("POSTGRES_PASSWORD", "p@ss")
+	t.Setenv("POSTGRES_DB", "ledger")
+
+	cfg, _, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %s", err)
+	}
+
+	if cfg.Database.Dsn != "postgres://bank:p%40ss@db/ledger" {
+		t.Fatalf("Unexpected DSN %s", cfg.Database.Dsn)
+	}
+}
+
+func TestRejectsInvalidConfig(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DATABASE_URL", "postgres://localhost/bank")
+
+	for _, args := range [][]string{
+		{"-db-max-conns", "0"},
+		{"-db-min-conns", "11"},
+		{"-request-timeout", "soon"},
+		{"-log-level", "loud"},
+		{"-listen", ""},
+		{"-trace-exporter", "jaeger"},
+		{"-otlp-insecure", "maybe"},
+		{"-rate-limit-backend", "redis"},
+		{"-rate-limit-rate", "-1"},
+		{"-rate-limit-burst", "0"},
+		{"-rate-limit-auth-failure-burst", "0"},
+		{"-max-transaction-amount", "-1"},
+		{"-hold-ttl", "-1h"},
+		{"-config", filepath.Join(t.TempDir(), "missing.yaml")},
+	} {
+		if _, _, err := config.Load(args); err == nil {
+			t.Fatalf("Expected %v to be rejected", args)
+		}
+	}
+
+	t.Setenv("DATABASE_URL", "")
+	if _, _, err := config.Load(nil); err == nil {
+		t.Fatalf("Expected a missing DSN to be rejected")
+	}
+}
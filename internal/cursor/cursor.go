@@ -0,0 +1,84 @@
+// Package cursor encodes pagination positions as opaque, signed tokens so clients can't forge
+// or edit them, or reuse them with a different query.
+package cursor
+
+import (
+	id "chariottakehome/internal/identifier"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+const (
+	version byte = 1
+
+	// version + direction + identifier
+	payloadLength int = 2 + 20
+	macLength     int = 16
+)
+
+type Direction byte
+
+const (
+	Forward Direction = iota
+	Backward
+)
+
+// Cursor is a keyset position. Pages read in its direction start just past Position.
+type Cursor struct {
+	Position  id.Identifier
+	Direction Direction
+}
+
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{key: key}
+}
+
+// Encode signs the cursor together with scope, which should describe the query the cursor
+// belongs to (e.g. the account and filters). Scope isn't stored in the token.
+func (s *Signer) Encode(c Cursor, scope string) string {
+	position := c.Position.Bytes()
+
+	buf := make([]byte, 0, payloadLength+macLength)
+	buf = append(buf, version, byte(c.Direction))
+	buf = append(buf, position[:]...)
+	buf = append(buf, s.sign(buf, scope)...)
+
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// Decode verifies a token produced by Encode with the same scope
+func (s *Signer) Decode(token string, scope string) (Cursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(buf) != payloadLength+macLength || buf[0] != version {
+		return Cursor{}, ErrMalformed
+	}
+
+	payload, mac := buf[:payloadLength], buf[payloadLength:]
+	if !hmac.Equal(mac, s.sign(payload, scope)) {
+		return Cursor{}, ErrBadSignature
+	}
+
+	direction := Direction(payload[1])
+	if direction != Forward && direction != Backward {
+		return Cursor{}, ErrMalformed
+	}
+
+	position, err := id.FromBytes(payload[2:])
+	if err != nil {
+		return Cursor{}, ErrMalformed
+	}
+
+	return Cursor{Position: position, Direction: direction}, nil
+}
+
+func (s *Signer) sign(payload []byte, scope string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	mac.Write([]byte(scope))
+	return mac.Sum(nil)[:macLength]
+}
@@ -0,0 +1,73 @@
+package cursor_test
+
+import (
+	"chariottakehome/internal/cursor"
+	id "chariottakehome/internal/identifier"
+	"errors"
+	"testing"
+)
+
+func newCursor(t *testing.T, direction cursor.Direction) cursor.Cursor {
+	position, err := id.New()
+	if err != nil {
+		t.Fatalf("Failed to generate identifier: %s", err)
+	}
+
+	return cursor.Cursor{Position: position, Direction: direction}
+}
+
+func TestRoundTrip(t *testing.T) {
+	signer := cursor.NewSigner([]byte("secret"))
+
+	for _, direction := range []cursor.Direction{cursor.Forward, cursor.Backward} {
+		c := newCursor(t, direction)
+
+		decoded, err := signer.Decode(signer.Encode(c, "scope"), "scope")
+		if err != nil {
+			t.Fatalf("Failed to decode cursor: %s", err)
+		}
+
+		if decoded != c {
+			t.Fatalf("Expected %v, got %v", c, decoded)
+		}
+	}
+}
+
+func TestRejectsTamperedToken(t *testing.T) {
+	signer := cursor.NewSigner([]byte("secret"))
+	token := []byte(signer.Encode(newCursor(t, cursor.Forward), "scope"))
+
+	// Flip a character in the payload
+	if token[4] == 'A' {
+		token[4] = 'B'
+	} else {
+		token[4] = 'A'
+	}
+
+	if _, err := signer.Decode(string(token), "scope"); !errors.Is(err, cursor.ErrBadSignature) {
+		t.Fatalf("Failed to reject tampered cursor, got %v", err)
+	}
+}
+
+func TestRejectsOtherScopeAndKey(t *testing.T) {
+	signer := cursor.NewSigner([]byte("secret"))
+	token := signer.Encode(newCursor(t, cursor.Forward), "scope")
+
+	if _, err := signer.Decode(token, "other scope"); !errors.Is(err, cursor.ErrBadSignature) {
+		t.Fatalf("Failed to reject cursor from another scope, got %v", err)
+	}
+
+	if _, err := cursor.NewSigner([]byte("other secret")).Decode(token, "scope"); !errors.Is(err, cursor.ErrBadSignature) {
+		t.Fatalf("Failed to reject cursor signed with another key, got %v", err)
+	}
+}
+
+func TestRejectsMalformedToken(t *testing.T) {
+	signer := cursor.NewSigner([]byte("secret"))
+
+	for _, token := range []string{"", "not a cursor", "c-0000000000SYSTEM00"} {
+		if _, err := signer.Decode(token, "scope"); !errors.Is(err, cursor.ErrMalformed) {
+			t.Fatalf("Failed to reject malformed cursor '%s', got %v", token, err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package cursor
+
+type errReason string
+
+const (
+	Malformed    errReason = "Cursor is malformed."
+	BadSignature errReason = "Cursor wasn't issued for this request."
+)
+
+type CursorError struct {
+	reason errReason
+}
+
+func (e CursorError) Error() string {
+	return "Invalid cursor: " + string(e.reason)
+}
+
+var (
+	ErrMalformed    = CursorError{reason: Malformed}
+	ErrBadSignature = CursorError{reason: BadSignature}
+)
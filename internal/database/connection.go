@@ -1,47 +1,64 @@
 package database
 
 import (
+	"chariottakehome/internal/config"
+	"chariottakehome/internal/telemetry"
 	"context"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	initialRetryDelay time.Duration = 250 * time.Millisecond
+	maxRetryDelay     time.Duration = 5 * time.Second
+)
+
 type DatabasePool struct {
 	*pgxpool.Pool
 }
 
-var dbPool *DatabasePool
-
-func init() {
-	var err error
-	uname := os.Getenv("PGUSER")
-	pword := os.Getenv("POSTGRES_PASSWORD")
-	host := os.Getenv("PGHOST")
-	dbname := os.Getenv("POSTGRES_DB")
-	connStr := fmt.Sprintf("postgres://%s:%s@%s/%s", uname, pword, host, dbname)
-	ctx := context.Background()
-
-	// Could expand database config to allow for more control over the min/max connections
-	config, err := pgxpool.ParseConfig(connStr)
+// Open connects to the database, retrying with backoff for up to cfg.StartupTimeout so the server
+// can start before the database is ready
+func Open(ctx context.Context, cfg config.Database) (*DatabasePool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.Dsn)
 	if err != nil {
-		log.Fatalf("unable to parse database connection string: %v\n", err)
+		return nil, fmt.Errorf("unable to parse database connection string: %w", err)
 	}
-
-	connPool, err := pgxpool.NewWithConfig(context.Background(), config)
-	if err != nil {
-		log.Fatalf("unable to create database connection pool: %v\n", err)
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConns = cfg.MaxConns
+	if cfg.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
 	}
-	dbPool = &DatabasePool{connPool}
+	poolConfig.ConnConfig.Tracer = telemetry.QueryTracer{}
 
-	err = dbPool.Ping(ctx)
+	connPool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		log.Fatalf("unable to reach database: %v\n", err)
+		return nil, fmt.Errorf("unable to create database connection pool: %w", err)
 	}
-}
 
-func ConnPool() *DatabasePool {
-	return dbPool
+	deadline := time.Now().Add(cfg.StartupTimeout)
+	delay := initialRetryDelay
+	for {
+		err = connPool.Ping(ctx)
+		if err == nil {
+			return &DatabasePool{connPool}, nil
+		}
+		if time.Now().Add(delay).After(deadline) {
+			connPool.Close()
+			return nil, fmt.Errorf("unable to reach database: %w", err)
+		}
+
+		slog.WarnContext(ctx, "database isn't reachable yet, retrying", "delay", delay.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			connPool.Close()
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxRetryDelay)
+	}
 }
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Publisher is handed each batch of events once the relay has published them
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+}
+
+// Broker wakes local watchers when the relay publishes events. Watchers read the events from the
+// outbox themselves, so a slow watcher never holds up the relay or misses anything.
+type Broker struct {
+	mu      sync.Mutex
+	updated chan struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{updated: make(chan struct{})}
+}
+
+// Updated returns a channel that's closed the next time events are published. Take it before
+// reading the outbox so events published in between aren't missed.
+func (b *Broker) Updated() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.updated
+}
+
+func (b *Broker) Publish(ctx context.Context, events []Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	close(b.updated)
+	b.updated = make(chan struct{})
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// scriptedRepo hands out the queued batches from Relay, then nothing
+type scriptedRepo struct {
+	batches [][]Event
+	relays  int
+}
+
+func (r *scriptedRepo) ListAccountEvents(ctx context.Context, accountId id.Identifier, after *id.Identifier, limit int) ([]Event, error) {
+	return nil, nil
+}
+
+func (r *scriptedRepo) Relay(ctx context.Context, limit int) ([]Event, error) {
+	r.relays++
+	if len(r.batches) == 0 {
+		return nil, nil
+	}
+
+	batch := r.batches[0]
+	r.batches = r.batches[1:]
+	return batch, nil
+}
+
+// recordingPublisher counts the events it's handed, failing every call if err is set
+type recordingPublisher struct {
+	received int
+	err      error
+	done     chan struct{}
+	want     int
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, events []Event) error {
+	p.received += len(events)
+	if p.received == p.want {
+		close(p.done)
+	}
+	return p.err
+}
+
+func batch(size int) []Event {
+	return make([]Event, size)
+}
+
+func TestRunRelayDrainsBacklogAndFansOut(t *testing.T) {
+	repo := &scriptedRepo{batches: [][]Event{batch(relayBatchSize), batch(relayBatchSize), batch(3)}}
+	want := 2*relayBatchSize + 3
+	failing := &recordingPublisher{err: errors.New("unavailable"), done: make(chan struct{}), want: want}
+	working := &recordingPublisher{done: make(chan struct{}), want: want}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		// The interval is long enough that only following up full batches can drain the backlog
+		RunRelay(ctx, repo, time.Hour, failing, working)
+		close(stopped)
+	}()
+
+	select {
+	case <-working.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the backlog to drain without waiting for the interval, got %d events", working.received)
+	}
+	cancel()
+	<-stopped
+
+	if failing.received != want {
+		t.Fatalf("Expected every publisher to get every event, got %d", failing.received)
+	}
+	// Two full batches are followed up straight away, and the short one waits for the ticker
+	if repo.relays != 3 {
+		t.Fatalf("Expected 3 relays, got %d", repo.relays)
+	}
+}
+
+func TestBrokerWakesEveryWatcher(t *testing.T) {
+	broker := NewBroker()
+	first, second := broker.Updated(), broker.Updated()
+
+	select {
+	case <-first:
+		t.Fatalf("Expected watchers to wait until events are published")
+	default:
+	}
+
+	if err := broker.Publish(context.Background(), batch(1)); err != nil {
+		t.Fatalf("Failed to publish: %s", err)
+	}
+
+	for _, updated := range []<-chan struct{}{first, second} {
+		select {
+		case <-updated:
+		default:
+			t.Fatalf("Expected every watcher to be woken")
+		}
+	}
+
+	// Watchers taking the channel after a publish wait for the next one
+	select {
+	case <-broker.Updated():
+		t.Fatalf("Expected a fresh channel after publishing")
+	default:
+	}
+}
@@ -0,0 +1,28 @@
+package events
+
+import (
+	id "chariottakehome/internal/identifier"
+	"errors"
+	"time"
+)
+
+var ErrUnknownEvent = errors.New("no published event with that id")
+
+type EventType string
+
+const (
+	AccountCreated      EventType = "AccountCreated"
+	FundsDeposited      EventType = "FundsDeposited"
+	FundsWithdrawn      EventType = "FundsWithdrawn"
+	TransferCompleted   EventType = "TransferCompleted"
+	TransactionReversed EventType = "TransactionReversed"
+)
+
+// Event is a published outbox row. Payload is the JSON body describing the change.
+type Event struct {
+	Id         id.Identifier
+	Type       EventType
+	AccountIds []id.Identifier
+	Payload    []byte
+	CreatedAt  time.Time
+}
@@ -0,0 +1,27 @@
+package events
+
+import (
+	id "chariottakehome/internal/identifier"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxAppend writes an event to the outbox as part of tx, so it's only published if the change it
+// describes commits. payload is marshalled to JSON.
+func TxAppend(ctx context.Context, tx pgx.Tx, eventType EventType, accountIds []id.Identifier, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	_, err = tx.Exec(ctx, outboxInsert, eventType, accountIds, body, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert %s event: %w", eventType, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const relayBatchSize int = 100
+
+// RunRelay publishes outbox rows to each publisher, polling every interval until ctx is done.
+// Full batches are followed up straight away so a backlog drains quickly.
+func RunRelay(ctx context.Context, repo EventRepository, interval time.Duration, publishers ...Publisher) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		events, err := repo.Relay(ctx, relayBatchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to relay events", "error", err)
+		}
+
+		if len(events) > 0 {
+			for _, publisher := range publishers {
+				if err := publisher.Publish(ctx, events); err != nil {
+					slog.ErrorContext(ctx, "failed to publish events", "error", err)
+				}
+			}
+		}
+
+		if len(events) == relayBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"chariottakehome/internal/database"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type EventRepository interface {
+	ListAccountEvents(ctx context.Context, accountId id.Identifier, after *id.Identifier, limit int) ([]Event, error)
+	Relay(ctx context.Context, limit int) ([]Event, error)
+}
+
+type eventRepository struct {
+	database *database.DatabasePool
+}
+
+func NewRepo(database *database.DatabasePool) EventRepository {
+	return &eventRepository{database}
+}
+
+// ListAccountEvents returns up to limit published events involving the account, in the order
+// they were published, starting after the given event. An event id that was never published is
+// ErrUnknownEvent.
+func (r *eventRepository) ListAccountEvents(ctx context.Context, accountId id.Identifier, after *id.Identifier, limit int) ([]Event, error) {
+	var start int64
+	if after != nil {
+		err := r.database.QueryRow(ctx, eventPublishedSequence, *after).Scan(&start)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUnknownEvent
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := r.database.Query(ctx, accountEvents, accountId, start, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]Event, 0)
+	for rows.Next() {
+		var e Event
+		if err := scanEvent(rows, &e, &e.Id); err != nil {
+			return nil, err
+		}
+
+		results = append(results, e)
+	}
+
+	return results, rows.Err()
+}
+
+// Relay publishes up to limit outbox rows by assigning their event ids and published sequences,
+// returning them in order.
+// It returns nothing while another relay holds the lock.
+func (r *eventRepository) Relay(ctx context.Context, limit int) ([]Event, error) {
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	err = tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, relayLockKey).Scan(&locked)
+	if err != nil || !locked {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, unpublishedEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	sequences := make([]int64, 0)
+	results := make([]Event, 0)
+	for rows.Next() {
+		var (
+			e        Event
+			sequence int64
+		)
+		if err := scanEvent(rows, &e, &sequence); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		sequences = append(sequences, sequence)
+		results = append(results, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	for i := range results {
+		results[i].Id, err = id.New()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec(ctx, eventPublish, results[i].Id, now, sequences[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to publish event: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return results, nil
+}
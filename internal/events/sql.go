@@ -0,0 +1,63 @@
+package events
+
+import (
+	id "chariottakehome/internal/identifier"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	outboxInsert string = `INSERT INTO outbox (
+	event_type, account_ids, payload, created_at
+	) VALUES ($1, $2, $3, $4)`
+
+	eventColumns string = `event_id, event_type, account_ids, payload, created_at`
+
+	// Only one relay publishes at a time, so published sequences are taken in commit order and a
+	// consumer resuming after one can't miss a later commit
+	relayLockKey int64 = 0x6f7574626f78
+
+	unpublishedEvents string = `SELECT sequence, event_type, account_ids, payload, created_at
+	FROM outbox
+	WHERE event_id IS NULL
+	ORDER BY sequence
+	LIMIT $1`
+
+	eventPublish string = `UPDATE outbox SET
+	event_id = $1,
+	published_at = $2,
+	published_sequence = nextval('outbox_published_sequence')
+	WHERE sequence = $3`
+
+	eventPublishedSequence string = `SELECT published_sequence FROM outbox WHERE event_id = $1`
+
+	// Events are ordered by when they were published rather than by id, since ids come from the
+	// publishing instance's clock
+	accountEvents string = `SELECT ` + eventColumns + `
+	FROM outbox
+	WHERE published_sequence > $2
+		AND account_ids @> ARRAY[$1]::TEXT[]
+	ORDER BY published_sequence
+	LIMIT $3`
+)
+
+// scanEvent reads a row selected as eventColumns. key receives the first column, which is the
+// event id once published and the outbox sequence before that.
+func scanEvent(row pgx.Row, e *Event, key any) error {
+	var accountIds []string
+	err := row.Scan(key, &e.Type, &accountIds, &e.Payload, &e.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	e.AccountIds = make([]id.Identifier, 0, len(accountIds))
+	for _, accountId := range accountIds {
+		parsed, err := id.FromString(accountId)
+		if err != nil {
+			return err
+		}
+		e.AccountIds = append(e.AccountIds, parsed)
+	}
+
+	return nil
+}
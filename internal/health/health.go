@@ -0,0 +1,69 @@
+// Package health reports the server's readiness through the standard grpc.health.v1 service
+package health
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Pinger is anything whose reachability decides whether the server can serve, like the database
+// pool
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Run pings db every interval until ctx is done, reporting services (and the server as a whole) as
+// NOT_SERVING while it fails. Every call needs the database, so there's nothing useful a service
+// can do without it.
+func Run(ctx context.Context, server *health.Server, db Pinger, interval time.Duration, services ...string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var serving *bool
+	for {
+		ok := check(ctx, db, interval)
+		if ctx.Err() != nil {
+			return
+		}
+		if serving == nil || *serving != ok {
+			setStatus(server, ok, services)
+			serving = &ok
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check pings db, giving up after timeout so a hung connection reads as unhealthy
+func check(ctx context.Context, db Pinger, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := db.Ping(ctx); err != nil {
+		slog.WarnContext(ctx, "health check failed", "error", err)
+		return false
+	}
+
+	return true
+}
+
+func setStatus(server *health.Server, serving bool, services []string) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if !serving {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	// The empty service name stands for the server as a whole
+	server.SetServingStatus("", status)
+	for _, service := range services {
+		server.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,58 @@
+package health_test
+
+import (
+	"chariottakehome/internal/health"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type fakePinger struct {
+	down atomic.Bool
+}
+
+func (p *fakePinger) Ping(ctx context.Context) error {
+	if p.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+// waitForStatus polls the health server until service reports want, failing after a second
+func waitForStatus(t *testing.T, server *grpchealth.Server, service string, want healthpb.HealthCheckResponse_ServingStatus) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err == nil && resp.GetStatus() == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %q to be %s, got %v (%v)", service, want, resp.GetStatus(), err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReportsDatabaseReachability(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := grpchealth.NewServer()
+	db := &fakePinger{}
+	go health.Run(ctx, server, db, 10*time.Millisecond, "accounts.AccountService")
+
+	waitForStatus(t, server, "", healthpb.HealthCheckResponse_SERVING)
+	waitForStatus(t, server, "accounts.AccountService", healthpb.HealthCheckResponse_SERVING)
+
+	db.down.Store(true)
+	waitForStatus(t, server, "", healthpb.HealthCheckResponse_NOT_SERVING)
+	waitForStatus(t, server, "accounts.AccountService", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	db.down.Store(false)
+	waitForStatus(t, server, "accounts.AccountService", healthpb.HealthCheckResponse_SERVING)
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	e "chariottakehome/api/errors"
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// UnaryInterceptor tags the call with a request ID, returns it to the client in the response
+// header, and logs one line for the call once it's done. It sits inside the interceptor
+// translating errors into statuses, so the log has the underlying error.
+func UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	requestId := incomingRequestId(ctx)
+	ctx = NewContext(ctx, requestId)
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIdKey, requestId))
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	logRequest(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// StreamInterceptor does the same as UnaryInterceptor for streams, logging when the stream ends
+func StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	requestId := incomingRequestId(ss.Context())
+	ctx := NewContext(ss.Context(), requestId)
+	ss.SetHeader(metadata.Pairs(RequestIdKey, requestId))
+	start := time.Now()
+
+	err := handler(srv, &loggedStream{ServerStream: ss, ctx: ctx})
+
+	logRequest(ctx, info.FullMethod, start, err)
+	return err
+}
+
+type loggedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *loggedStream) Context() context.Context {
+	return s.ctx
+}
+
+func logRequest(ctx context.Context, method string, start time.Time, err error) {
+	code := codes.OK
+	if err != nil {
+		code = e.ToStatus(err).Code()
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		slog.String("code", code.String()),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+
+	level := slog.LevelInfo
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+		if e.IsServerError(code) {
+			level = slog.LevelError
+		}
+	}
+
+	slog.LogAttrs(ctx, level, "request", attrs...)
+}
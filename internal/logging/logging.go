@@ -0,0 +1,76 @@
+// Package logging sets up structured JSON logging, tagging each line with the ID of the request
+// it was logged for and redacting personal data
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+)
+
+const redacted string = "[REDACTED]"
+
+// sensitiveKeys are attributes whose values are never logged
+var sensitiveKeys = map[string]bool{
+	"email":         true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+	"api_key":       true,
+	"key":           true,
+}
+
+// Emails can also turn up inside other values, like error messages
+var emailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+
+// NewHandler returns a JSON handler writing records at level or above to w
+func NewHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return &contextHandler{
+		Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: redact}),
+	}
+}
+
+// contextHandler adds the request ID from the record's context, so anything logged with a
+// request's context can be tied back to it
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if requestId := RequestId(ctx); requestId != "" {
+		r.AddAttrs(slog.String("request_id", requestId))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
+
+// redact blanks sensitive attributes and masks emails in everything else
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[a.Key] {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, redactString(err.Error()))
+		}
+	}
+
+	return a
+}
+
+func redactString(s string) string {
+	return emailPattern.ReplaceAllString(s, redacted)
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// captureLogs sends the default logger's output to the returned buffer for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(NewHandler(&buf, slog.LevelDebug)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return &buf
+}
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one log line, got %d: %s", len(lines), buf)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("Failed to decode log line: %s", err)
+	}
+
+	return line
+}
+
+func TestRedaction(t *testing.T) {
+	buf := captureLogs(t)
+
+	slog.Info("created user",
+		"email", "jane@example.com",
+		"error", errors.New(`user jane@example.com already exists`),
+		"note", "contact bob.smith+bank@mail.example.org today",
+		"user_id", "u_123",
+	)
+
+	line := decodeLine(t, buf)
+	if line["email"] != redacted {
+		t.Fatalf("Expected the email to be redacted, got %v", line["email"])
+	}
+	if line["error"] != "user [REDACTED] already exists" || line["note"] != "contact [REDACTED] today" {
+		t.Fatalf("Expected emails inside values to be redacted, got %v and %v", line["error"], line["note"])
+	}
+	if line["user_id"] != "u_123" {
+		t.Fatalf("Expected other values to be left alone, got %v", line["user_id"])
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	buf := captureLogs(t)
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.AccountService/GetAccount"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIdKey, "req-42"))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}})
+
+	var handlerRequestId string
+	_, err := UnaryInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerRequestId = RequestId(ctx)
+		// Logged from deeper in the call, like the repo layer
+		slog.DebugContext(ctx, "querying")
+		return nil, status.Error(codes.NotFound, "resource not found")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected the handler's error to be returned, got %v", err)
+	}
+	if handlerRequestId != "req-42" {
+		t.Fatalf("Expected the client's request ID, got %q", handlerRequestId)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"request_id":"req-42"`) {
+		t.Fatalf("Expected the nested log line to carry the request ID, got %s", buf)
+	}
+
+	buf.Reset()
+	buf.WriteString(lines[1])
+	line := decodeLine(t, buf)
+	if line["msg"] != "request" || line["method"] != info.FullMethod || line["code"] != "NotFound" ||
+		line["peer"] != "10.0.0.1:5000" || line["request_id"] != "req-42" || line["level"] != "INFO" {
+		t.Fatalf("Unexpected request line %v", line)
+	}
+	if _, ok := line["duration_ms"].(float64); !ok {
+		t.Fatalf("Expected a duration, got %v", line["duration_ms"])
+	}
+}
+
+func TestGeneratesRequestId(t *testing.T) {
+	for _, md := range []metadata.MD{
+		nil,
+		metadata.Pairs(RequestIdKey, "has spaces and\nnewlines"),
+		metadata.Pairs(RequestIdKey, strings.Repeat("a", 65)),
+	} {
+		ctx := context.Background()
+		if md != nil {
+			ctx = metadata.NewIncomingContext(ctx, md)
+		}
+
+		requestId := incomingRequestId(ctx)
+		if !requestIdPattern.MatchString(requestId) || (md != nil && requestId == md.Get(RequestIdKey)[0]) {
+			t.Fatalf("Expected a generated request ID, got %q", requestId)
+		}
+	}
+}
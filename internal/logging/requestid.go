@@ -0,0 +1,47 @@
+package logging
+
+import (
+	id "chariottakehome/internal/identifier"
+	"context"
+	"regexp"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIdKey is the metadata key (and, over the REST gateway, the header) a request ID is read
+// from and returned in
+const RequestIdKey string = "x-request-id"
+
+// Client supplied IDs end up in every log line for the request, so keep them short and plain
+var requestIdPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,64}$`)
+
+type requestIdKey struct{}
+
+// NewContext returns a copy of ctx carrying requestId
+func NewContext(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+// RequestId returns the ID of the request ctx belongs to, or an empty string outside a request
+func RequestId(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
+
+// incomingRequestId returns the ID the client sent with the call, or a new one if it didn't send
+// a usable one
+func incomingRequestId(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIdKey); len(values) > 0 && requestIdPattern.MatchString(values[0]) {
+			return values[0]
+		}
+	}
+
+	requestId, err := id.New()
+	if err != nil {
+		// Losing the ID isn't worth failing the request over
+		return ""
+	}
+
+	return requestId.String()
+}
@@ -0,0 +1,266 @@
+package migrate
+
+import (
+	"chariottakehome/internal/database"
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type State string
+
+const (
+	Applied State = "applied"
+	Pending State = "pending"
+	// Drifted migrations were edited after they were applied
+	Drifted State = "drifted"
+	// Missing migrations were applied by a build that had a migration this one doesn't
+	Missing State = "missing"
+)
+
+type Status struct {
+	Version   int64
+	Name      string
+	State     State
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	database   *database.DatabasePool
+	migrations []Migration
+}
+
+// New returns a migrator for the migrations in fsys
+func New(database *database.DatabasePool, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{database, migrations}, nil
+}
+
+// Up applies every migration that hasn't been applied yet
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(done []applied) ([]step, error) {
+		return plan(m.migrations, done, math.MaxInt64)
+	})
+}
+
+// Down rolls back the newest applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.run(ctx, func(done []applied) ([]step, error) {
+		if len(done) == 0 {
+			return nil, nil
+		}
+
+		latest := find(m.migrations, done[len(done)-1].Version)
+		if latest.Down == "" {
+			return nil, fmt.Errorf("%w: %04d-%s", ErrIrreversible, latest.Version, latest.Name)
+		}
+
+		return []step{{migration: *latest, down: true}}, nil
+	})
+}
+
+// To applies or rolls back migrations until version is the most recent one applied
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if find(m.migrations, version) == nil {
+		return fmt.Errorf("%w: %d", ErrNoSuchVersion, version)
+	}
+
+	return m.run(ctx, func(done []applied) ([]step, error) {
+		return plan(m.migrations, done, version)
+	})
+}
+
+// Baseline records every migration up to and including version as applied without running any of
+// them. It's for adopting a database whose schema was created before migrations were tracked, so
+// it refuses if any migration is recorded already.
+func (m *Migrator) Baseline(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := selectMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		recorded, err := baseline(m.migrations, done, version)
+		if err != nil {
+			return err
+		}
+
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			for _, r := range recorded {
+				slog.InfoContext(ctx, "recording migration as applied", "version", r.Version, "name", r.Name)
+				if _, err := tx.Exec(ctx, insertApplied, r.Version, r.Name, r.Checksum); err != nil {
+					return fmt.Errorf("failed to record migration %04d-%s: %w", r.Version, r.Name, err)
+				}
+			}
+
+			return nil
+		})
+	})
+}
+
+// Status reports every migration, including any that were applied but aren't in this build. It
+// reports drift rather than refusing to run, so drift can be investigated.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := selectMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = status(m.migrations, done)
+		return nil
+	})
+
+	return statuses, err
+}
+
+// run holds the lock while it works out which steps to take and takes them. The plan is made
+// under the lock so it can't be based on what another process is part way through changing.
+func (m *Migrator) run(ctx context.Context, planSteps func([]applied) ([]step, error)) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := selectMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, u := range upgradeChecksums(m.migrations, done) {
+			if _, err := conn.Exec(ctx, updateChecksum, u.Version, u.Checksum); err != nil {
+				return fmt.Errorf("failed to update checksum of %04d-%s: %w", u.Version, u.Name, err)
+			}
+		}
+		if err := verify(m.migrations, done); err != nil {
+			return err
+		}
+
+		steps, err := planSteps(done)
+		if err != nil {
+			return err
+		}
+		if len(steps) == 0 {
+			slog.InfoContext(ctx, "schema is up to date")
+			return nil
+		}
+
+		for _, s := range steps {
+			if err := apply(ctx, conn, s); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// withLock runs fn on a single connection holding the migration lock, creating
+// schema_migrations first if it doesn't exist. The lock is session-level so it's held across
+// each migration's transaction.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.database.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, lock, lockId); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		// Unlocked even if ctx was cancelled, or the lock would outlive the migration
+		if _, err := conn.Exec(context.WithoutCancel(ctx), unlock, lockId); err != nil {
+			slog.WarnContext(ctx, "failed to release migration lock", "error", err)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// apply runs one step in a transaction along with recording it, so a failed migration leaves
+// neither the schema nor schema_migrations changed
+func apply(ctx context.Context, conn *pgxpool.Conn, s step) error {
+	m := s.migration
+	if s.down {
+		slog.InfoContext(ctx, "rolling back migration", "version", m.Version, "name", m.Name)
+	} else {
+		slog.InfoContext(ctx, "applying migration", "version", m.Version, "name", m.Name)
+	}
+
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if s.down {
+			if _, err := tx.Exec(ctx, m.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, deleteApplied, m.Version)
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, m.Up); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, insertApplied, m.Version, m.Name, m.Checksum)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %04d-%s failed: %w", m.Version, m.Name, err)
+	}
+
+	return nil
+}
+
+func selectMigrations(ctx context.Context, conn *pgxpool.Conn) ([]applied, error) {
+	rows, err := conn.Query(ctx, selectApplied)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (applied, error) {
+		var a applied
+		err := row.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt)
+		return a, err
+	})
+}
+
+func status(migrations []Migration, done []applied) []Status {
+	byVersion := make(map[int64]applied, len(done))
+	for _, a := range done {
+		byVersion[a.Version] = a
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		a, ok := byVersion[m.Version]
+		switch {
+		case !ok:
+			statuses = append(statuses, Status{Version: m.Version, Name: m.Name, State: Pending})
+		case a.Checksum != m.Checksum && a.Checksum != m.upChecksum:
+			statuses = append(statuses, Status{Version: m.Version, Name: m.Name, State: Drifted, AppliedAt: &a.AppliedAt})
+		default:
+			statuses = append(statuses, Status{Version: m.Version, Name: m.Name, State: Applied, AppliedAt: &a.AppliedAt})
+		}
+	}
+
+	for i := range done {
+		a := &done[i]
+		if find(migrations, a.Version) == nil {
+			statuses = append(statuses, Status{Version: a.Version, Name: a.Name, State: Missing, AppliedAt: &a.AppliedAt})
+		}
+	}
+	slices.SortFunc(statuses, func(a, b Status) int { return cmp.Compare(a.Version, b.Version) })
+
+	return statuses
+}
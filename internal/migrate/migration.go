@@ -0,0 +1,232 @@
+// Package migrate applies and rolls back the schema migrations, recording which have been applied
+// in the schema_migrations table
+package migrate
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrChecksumDrift    = errors.New("applied migration has changed since it was applied")
+	ErrUnknownMigration = errors.New("applied migration is missing from this build")
+	ErrIrreversible     = errors.New("migration has no down script")
+	ErrNoSuchVersion    = errors.New("no migration with that version")
+	ErrAlreadyTracked   = errors.New("database already has migrations recorded")
+)
+
+const downSuffix = ".down.sql"
+
+// Migration is one NNNN-name.sql file and its optional NNNN-name.down.sql counterpart
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of Up and Down, recorded when the migration is applied so later
+	// edits to either can be caught
+	Checksum string
+	// upChecksum is the SHA-256 of Up alone, which is what builds before Down was covered
+	// recorded
+	upChecksum string
+}
+
+// applied is a row of schema_migrations
+type applied struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Load reads the migrations in fsys, in version order
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		version, name, down, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %04d is named both %q and %q", version, m.Name, name)
+		}
+
+		if down {
+			m.Down = string(contents)
+		} else {
+			if m.Up != "" {
+				return nil, fmt.Errorf("migration %04d is defined twice", version)
+			}
+			m.Up = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d-%s has a down script but no up script", m.Version, m.Name)
+		}
+		m.Checksum = checksum([]byte(m.Up), []byte(m.Down))
+		m.upChecksum = checksum([]byte(m.Up))
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+
+	return migrations, nil
+}
+
+// parseFileName splits NNNN-name.sql or NNNN-name.down.sql into its parts
+func parseFileName(fileName string) (int64, string, bool, error) {
+	base, down := strings.CutSuffix(fileName, downSuffix)
+	if !down {
+		base = strings.TrimSuffix(fileName, ".sql")
+	}
+
+	prefix, name, ok := strings.Cut(base, "-")
+	if !ok || name == "" {
+		return 0, "", false, fmt.Errorf("migration %q isn't named NNNN-name.sql", fileName)
+	}
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version < 0 {
+		return 0, "", false, fmt.Errorf("migration %q doesn't start with a version number", fileName)
+	}
+
+	return version, name, down, nil
+}
+
+// checksum hashes each script in turn, ending each with a NUL so moving text from the end of one
+// to the start of the next still changes the sum
+func checksum(scripts ...[]byte) string {
+	h := sha256.New()
+	for _, script := range scripts {
+		h.Write(script)
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// upgradeChecksums finds the rows in done recorded with an up-only checksum and updates them to
+// the checksum of both scripts, returning the migrations whose rows need rewriting. The up script
+// still has to match, so only the down script is trusted as it is now.
+func upgradeChecksums(migrations []Migration, done []applied) []Migration {
+	var upgraded []Migration
+	for i := range done {
+		a := &done[i]
+		m := find(migrations, a.Version)
+		if m != nil && a.Checksum != m.Checksum && a.Checksum == m.upChecksum {
+			a.Checksum = m.Checksum
+			upgraded = append(upgraded, *m)
+		}
+	}
+
+	return upgraded
+}
+
+// baseline returns the migrations up to and including target, to be recorded as applied without
+// running them. Only a database with nothing recorded can be baselined, otherwise it would hide
+// whichever of them hadn't actually been applied.
+func baseline(migrations []Migration, done []applied, target int64) ([]Migration, error) {
+	if len(done) > 0 {
+		return nil, fmt.Errorf("%w: newest is %04d-%s", ErrAlreadyTracked, done[len(done)-1].Version, done[len(done)-1].Name)
+	}
+	if find(migrations, target) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrNoSuchVersion, target)
+	}
+
+	var recorded []Migration
+	for _, m := range migrations {
+		if m.Version <= target {
+			recorded = append(recorded, m)
+		}
+	}
+
+	return recorded, nil
+}
+
+// verify refuses to go on if an applied migration has been edited or removed since it was
+// applied, as the schema would no longer match what the migrations describe
+func verify(migrations []Migration, done []applied) error {
+	for _, a := range done {
+		m := find(migrations, a.Version)
+		if m == nil {
+			return fmt.Errorf("%w: %04d-%s", ErrUnknownMigration, a.Version, a.Name)
+		}
+		if m.Checksum != a.Checksum {
+			return fmt.Errorf("%w: %04d-%s", ErrChecksumDrift, m.Version, m.Name)
+		}
+	}
+
+	return nil
+}
+
+// step is a migration to apply, or to roll back if down is set
+type step struct {
+	migration Migration
+	down      bool
+}
+
+// plan returns the steps that leave exactly the migrations up to and including target applied:
+// newer ones are rolled back newest first, then missing ones are applied oldest first
+func plan(migrations []Migration, done []applied, target int64) ([]step, error) {
+	isApplied := make(map[int64]bool, len(done))
+	for _, a := range done {
+		isApplied[a.Version] = true
+	}
+
+	var steps []step
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version > target && isApplied[m.Version] {
+			if m.Down == "" {
+				return nil, fmt.Errorf("%w: %04d-%s", ErrIrreversible, m.Version, m.Name)
+			}
+			steps = append(steps, step{migration: m, down: true})
+		}
+	}
+	for _, m := range migrations {
+		if m.Version <= target && !isApplied[m.Version] {
+			steps = append(steps, step{migration: m})
+		}
+	}
+
+	return steps, nil
+}
+
+func find(migrations []Migration, version int64) *Migration {
+	for i := range migrations {
+		if migrations[i].Version == version {
+			return &migrations[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package migrate
+
+import (
+	"chariottakehome/migrations"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func testMigrations(t *testing.T) []Migration {
+	fsys := fstest.MapFS{
+		"0000-create-users.sql":         {Data: []byte("CREATE TABLE users ();")},
+		"0000-create-users.down.sql":    {Data: []byte("DROP TABLE users;")},
+		"0001-add-email.sql":            {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"0001-add-email.down.sql":       {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+		"0002-backfill-emails.sql":      {Data: []byte("UPDATE users SET email = '';")},
+		"0003-create-accounts.sql":      {Data: []byte("CREATE TABLE accounts ();")},
+		"0003-create-accounts.down.sql": {Data: []byte("DROP TABLE accounts;")},
+		"README.md":                     {Data: []byte("not a migration")},
+	}
+
+	ms, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %s", err)
+	}
+
+	return ms
+}
+
+func appliedUpTo(ms []Migration, versions ...int64) []applied {
+	var done []applied
+	for _, v := range versions {
+		m := find(ms, v)
+		done = append(done, applied{Version: m.Version, Name: m.Name, Checksum: m.Checksum})
+	}
+
+	return done
+}
+
+func TestLoad(t *testing.T) {
+	ms := testMigrations(t)
+
+	if len(ms) != 4 {
+		t.Fatalf("Expected 4 migrations, got %d", len(ms))
+	}
+	for i, m := range ms {
+		if m.Version != int64(i) {
+			t.Fatalf("Expected migrations in version order, got %d at %d", m.Version, i)
+		}
+	}
+	if ms[1].Name != "add-email" || ms[1].Down == "" || ms[2].Down != "" {
+		t.Fatalf("Expected down scripts to be paired with their migration, got %+v", ms[1:3])
+	}
+	if ms[0].Checksum == ms[1].Checksum || len(ms[0].Checksum) != 64 {
+		t.Fatalf("Expected a SHA-256 checksum per migration, got %q", ms[0].Checksum)
+	}
+
+	edited, err := Load(fstest.MapFS{
+		"0000-create-users.sql":      {Data: []byte("CREATE TABLE users ();")},
+		"0000-create-users.down.sql": {Data: []byte("DROP TABLE IF EXISTS users;")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %s", err)
+	}
+	if edited[0].Checksum == ms[0].Checksum {
+		t.Fatalf("Expected editing the down script to change the checksum")
+	}
+
+	for _, fsys := range []fstest.MapFS{
+		{"create-users.sql": {}},
+		{"0001.sql": {}},
+		{"0001-a.sql": {}, "0001-b.sql": {}},
+		{"0001-a.down.sql": {}},
+	} {
+		if _, err := Load(fsys); err == nil {
+			t.Fatalf("Expected %v to be rejected", fsys)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	ms, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load embedded migrations: %s", err)
+	}
+	if len(ms) == 0 {
+		t.Fatalf("Expected migrations to be embedded")
+	}
+
+	for _, m := range ms {
+		if m.Down == "" {
+			t.Fatalf("Expected %04d-%s to have a down script", m.Version, m.Name)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ms := testMigrations(t)
+
+	if err := verify(ms, appliedUpTo(ms, 0, 1)); err != nil {
+		t.Fatalf("Expected unchanged migrations to verify, got %s", err)
+	}
+
+	drifted := appliedUpTo(ms, 0, 1)
+	drifted[1].Checksum = "edited"
+	if err := verify(ms, drifted); !errors.Is(err, ErrChecksumDrift) {
+		t.Fatalf("Expected ErrChecksumDrift, got %v", err)
+	}
+
+	unknown := append(appliedUpTo(ms, 0), applied{Version: 7, Name: "from-another-branch"})
+	if err := verify(ms, unknown); !errors.Is(err, ErrUnknownMigration) {
+		t.Fatalf("Expected ErrUnknownMigration, got %v", err)
+	}
+}
+
+func TestUpgradeChecksums(t *testing.T) {
+	ms := testMigrations(t)
+
+	done := appliedUpTo(ms, 0, 1, 2)
+	done[0].Checksum = ms[0].upChecksum
+	done[2].Checksum = ms[2].upChecksum
+
+	upgraded := upgradeChecksums(ms, done)
+	if len(upgraded) != 2 || upgraded[0].Version != 0 || upgraded[1].Version != 2 {
+		t.Fatalf("Expected 0 and 2 to be upgraded, got %+v", upgraded)
+	}
+	if err := verify(ms, done); err != nil {
+		t.Fatalf("Expected upgraded checksums to verify, got %s", err)
+	}
+
+	// An up script edited since it was applied is still drift
+	drifted := appliedUpTo(ms, 0)
+	drifted[0].Checksum = checksum([]byte("CREATE TABLE people ();"))
+	if upgraded := upgradeChecksums(ms, drifted); len(upgraded) != 0 {
+		t.Fatalf("Expected nothing to be upgraded, got %+v", upgraded)
+	}
+	if err := verify(ms, drifted); !errors.Is(err, ErrChecksumDrift) {
+		t.Fatalf("Expected ErrChecksumDrift, got %v", err)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	ms := testMigrations(t)
+
+	recorded, err := baseline(ms, nil, 2)
+	if err != nil {
+		t.Fatalf("Failed to baseline: %s", err)
+	}
+	if len(recorded) != 3 || recorded[0].Version != 0 || recorded[2].Version != 2 {
+		t.Fatalf("Expected 0 to 2 to be recorded, got %+v", recorded)
+	}
+
+	if _, err := baseline(ms, nil, 7); !errors.Is(err, ErrNoSuchVersion) {
+		t.Fatalf("Expected ErrNoSuchVersion, got %v", err)
+	}
+	if _, err := baseline(ms, appliedUpTo(ms, 0), 2); !errors.Is(err, ErrAlreadyTracked) {
+		t.Fatalf("Expected ErrAlreadyTracked, got %v", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	ms := testMigrations(t)
+
+	steps, err := plan(ms, appliedUpTo(ms, 0), 3)
+	if err != nil {
+		t.Fatalf("Failed to plan: %s", err)
+	}
+	if len(steps) != 3 || steps[0].migration.Version != 1 || steps[2].migration.Version != 3 || steps[0].down {
+		t.Fatalf("Expected 1 to 3 to be applied in order, got %+v", steps)
+	}
+
+	// 2 has no down script, but only 3 needs rolling back
+	steps, err = plan(ms, appliedUpTo(ms, 0, 1, 2, 3), 2)
+	if err != nil {
+		t.Fatalf("Failed to plan: %s", err)
+	}
+	if len(steps) != 1 || steps[0].migration.Version != 3 || !steps[0].down {
+		t.Fatalf("Expected 3 to be rolled back, got %+v", steps)
+	}
+
+	if _, err := plan(ms, appliedUpTo(ms, 0, 1, 2, 3), 1); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("Expected ErrIrreversible, got %v", err)
+	}
+
+	// A migration merged below the newest applied one is still applied
+	steps, err = plan(ms, appliedUpTo(ms, 0, 1, 3), 3)
+	if err != nil {
+		t.Fatalf("Failed to plan: %s", err)
+	}
+	if len(steps) != 1 || steps[0].migration.Version != 2 {
+		t.Fatalf("Expected the gap to be applied, got %+v", steps)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ms := testMigrations(t)
+
+	done := appliedUpTo(ms, 0, 1)
+	done[1].Checksum = "edited"
+	done = append(done, applied{Version: 7, Name: "from-another-branch"})
+
+	statuses := status(ms, done)
+	want := []State{Applied, Drifted, Pending, Pending, Missing}
+	if len(statuses) != len(want) {
+		t.Fatalf("Expected %d statuses, got %+v", len(want), statuses)
+	}
+	for i, s := range statuses {
+		if s.State != want[i] {
+			t.Fatalf("Expected %04d to be %s, got %s", s.Version, want[i], s.State)
+		}
+		if (s.AppliedAt == nil) != (s.State == Pending) {
+			t.Fatalf("Expected only applied migrations to have a time, got %+v", s)
+		}
+	}
+}
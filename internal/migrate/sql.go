@@ -0,0 +1,28 @@
+package migrate
+
+const (
+	// lockId keys the advisory lock held while migrating, so replicas starting together take
+	// turns rather than racing to apply the same migration
+	lockId int64 = 0x6d6967726174650a
+
+	createMigrationsTable string = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+	selectApplied string = `SELECT version, name, checksum, applied_at
+	FROM schema_migrations
+	ORDER BY version`
+
+	insertApplied string = `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`
+
+	updateChecksum string = `UPDATE schema_migrations SET checksum = $2 WHERE version = $1`
+
+	deleteApplied string = `DELETE FROM schema_migrations WHERE version = $1`
+
+	lock string = `SELECT pg_advisory_lock($1)`
+
+	unlock string = `SELECT pg_advisory_unlock($1)`
+)
@@ -0,0 +1,98 @@
+package money
+
+import (
+	"errors"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency. The exponent is the number of minor units in a major
+// unit, e.g. 2 for USD (cents) and 0 for JPY.
+type Currency struct {
+	code     string
+	exponent int
+}
+
+var (
+	USD = Currency{"USD", 2}
+	EUR = Currency{"EUR", 2}
+	GBP = Currency{"GBP", 2}
+	JPY = Currency{"JPY", 0}
+	BHD = Currency{"BHD", 3}
+)
+
+// Not the full ISO 4217 list, but the currencies we're likely to hold
+var currencies = map[string]Currency{
+	"AUD": {"AUD", 2},
+	"BHD": BHD,
+	"BRL": {"BRL", 2},
+	"CAD": {"CAD", 2},
+	"CHF": {"CHF", 2},
+	"CLP": {"CLP", 0},
+	"CNY": {"CNY", 2},
+	"DKK": {"DKK", 2},
+	"EUR": EUR,
+	"GBP": GBP,
+	"HKD": {"HKD", 2},
+	"INR": {"INR", 2},
+	"ISK": {"ISK", 0},
+	"JOD": {"JOD", 3},
+	"JPY": JPY,
+	"KRW": {"KRW", 0},
+	"KWD": {"KWD", 3},
+	"MXN": {"MXN", 2},
+	"NOK": {"NOK", 2},
+	"NZD": {"NZD", 2},
+	"OMR": {"OMR", 3},
+	"SEK": {"SEK", 2},
+	"SGD": {"SGD", 2},
+	"TND": {"TND", 3},
+	"USD": USD,
+	"VND": {"VND", 0},
+	"ZAR": {"ZAR", 2},
+}
+
+func ParseCurrency(code string) (Currency, error) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, ErrUnknownCurrency
+	}
+
+	return currency, nil
+}
+
+func (c Currency) Code() string {
+	return c.code
+}
+
+func (c Currency) Exponent() int {
+	return c.exponent
+}
+
+func (c Currency) String() string {
+	return c.code
+}
+
+func (c *Currency) Scan(value interface{}) error {
+	if value == nil {
+		return errors.New("nil value")
+	}
+
+	switch v := value.(type) {
+	case string:
+		currency, err := ParseCurrency(v)
+		if err != nil {
+			return err
+		}
+		*c = currency
+	case []byte:
+		currency, err := ParseCurrency(string(v))
+		if err != nil {
+			return err
+		}
+		*c = currency
+	default:
+		return errors.New("unsupported data type")
+	}
+
+	return nil
+}
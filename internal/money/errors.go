@@ -0,0 +1,23 @@
+package money
+
+type errReason string
+
+const (
+	UnknownCurrency  errReason = "Unknown currency code."
+	CurrencyMismatch errReason = "Currencies don't match."
+	Overflow         errReason = "Amount out of range."
+)
+
+type MoneyError struct {
+	reason errReason
+}
+
+func (e MoneyError) Error() string {
+	return "Invalid money operation: " + string(e.reason)
+}
+
+var (
+	ErrUnknownCurrency  = MoneyError{reason: UnknownCurrency}
+	ErrCurrencyMismatch = MoneyError{reason: CurrencyMismatch}
+	ErrOverflow         = MoneyError{reason: Overflow}
+)
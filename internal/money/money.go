@@ -0,0 +1,71 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Money is an amount in a currency's minor units, e.g. cents for USD
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func New(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func Zero(currency Currency) Money {
+	return Money{Currency: currency}
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	a, b := m.Amount, other.Amount
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{Amount: a + b, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// String formats the amount in major units, e.g. "-12.34 USD" or "1234 JPY"
+func (m Money) String() string {
+	sign := ""
+	// Working in uint64 keeps math.MinInt64 representable
+	abs := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		abs = -abs
+	}
+
+	exponent := m.Currency.exponent
+	if exponent == 0 {
+		return fmt.Sprintf("%s%d %s", sign, abs, m.Currency.code)
+	}
+
+	digits := fmt.Sprintf("%0*d", exponent+1, abs)
+	split := len(digits) - exponent
+
+	var b strings.Builder
+	b.WriteString(sign)
+	b.WriteString(digits[:split])
+	b.WriteString(".")
+	b.WriteString(digits[split:])
+	b.WriteString(" ")
+	b.WriteString(m.Currency.code)
+
+	return b.String()
+}
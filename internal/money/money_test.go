@@ -0,0 +1,81 @@
+package money_test
+
+import (
+	"chariottakehome/internal/money"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseCurrency(t *testing.T) {
+	currency, err := money.ParseCurrency("usd")
+	if err != nil {
+		t.Fatalf("Failed to parse currency: %s", err)
+	}
+
+	if currency != money.USD || currency.Exponent() != 2 {
+		t.Fatalf("Expected USD with exponent 2, got %s with exponent %d", currency, currency.Exponent())
+	}
+
+	if _, err := money.ParseCurrency("XXX"); !errors.Is(err, money.ErrUnknownCurrency) {
+		t.Fatalf("Failed to reject unknown currency, got %v", err)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	sum, err := money.New(150, money.USD).Add(money.New(-200, money.USD))
+	if err != nil {
+		t.Fatalf("Failed to add: %s", err)
+	}
+
+	if sum != money.New(-50, money.USD) {
+		t.Fatalf("Expected -50 USD, got %v", sum)
+	}
+}
+
+func TestAddCurrencyMismatch(t *testing.T) {
+	_, err := money.New(1, money.USD).Add(money.New(1, money.EUR))
+	if !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Fatalf("Failed to reject mismatched currencies, got %v", err)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	cases := []func() (money.Money, error){
+		func() (money.Money, error) {
+			return money.New(math.MaxInt64, money.USD).Add(money.New(1, money.USD))
+		},
+		func() (money.Money, error) {
+			return money.New(math.MinInt64, money.USD).Add(money.New(-1, money.USD))
+		},
+		func() (money.Money, error) {
+			return money.New(0, money.USD).Sub(money.New(math.MinInt64, money.USD))
+		},
+		func() (money.Money, error) {
+			return money.New(math.MinInt64+1, money.USD).Sub(money.New(2, money.USD))
+		},
+	}
+
+	for i, c := range cases {
+		if _, err := c(); !errors.Is(err, money.ErrOverflow) {
+			t.Fatalf("Case %d: failed to detect overflow, got %v", i, err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	cases := map[string]money.Money{
+		"12.34 USD":                 money.New(1234, money.USD),
+		"0.05 USD":                  money.New(5, money.USD),
+		"-0.05 EUR":                 money.New(-5, money.EUR),
+		"1234 JPY":                  money.New(1234, money.JPY),
+		"1.234 BHD":                 money.New(1234, money.BHD),
+		"-92233720368547758.08 USD": money.New(math.MinInt64, money.USD),
+	}
+
+	for expected, m := range cases {
+		if m.String() != expected {
+			t.Fatalf("Expected '%s', got '%s'", expected, m.String())
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package ratelimit limits how often each principal can call each method, with a token bucket
+// per principal per method
+package ratelimit
+
+import (
+	e "chariottakehome/api/errors"
+	"chariottakehome/internal/auth"
+	"chariottakehome/internal/config"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ForwardedForKey is the metadata the REST gateway passes its client's address in, since its
+// calls to the gRPC server all come from loopback
+const ForwardedForKey = "x-forwarded-for"
+
+// bucketIdleTimeout is how long a bucket is kept after its last use. A limit that takes longer
+// than this to refill from empty is reset early for callers who wait that long.
+const bucketIdleTimeout = time.Hour
+
+var ErrRateLimited = errors.New("too many calls, try again later")
+
+// Decision is the outcome of taking a token from a bucket
+type Decision struct {
+	Allowed bool
+	// RetryAfter is how long until the bucket holds a token again, when the call isn't allowed
+	RetryAfter time.Duration
+}
+
+// Store holds the buckets
+type Store interface {
+	// Take takes a token from key's bucket if it has one, creating a full bucket for limit if
+	// key doesn't have one yet
+	Take(ctx context.Context, key string, limit config.Limit) (Decision, error)
+	// Peek reports whether Take would allow a call, without taking a token
+	Peek(ctx context.Context, key string, limit config.Limit) (Decision, error)
+	// Sweep forgets buckets that haven't been used for idle. A bucket that's refilled since is
+	// the same as a new one.
+	Sweep(ctx context.Context, idle time.Duration) error
+}
+
+type Limiter struct {
+	Store Store
+	// Default applies to methods that aren't in Methods
+	Default config.Limit
+	Methods map[string]config.Limit
+	// Exempt methods are never limited, like health checks
+	Exempt map[string]bool
+	// AuthFailures limits how often each address can fail authentication, so credentials can't
+	// be guessed at the default rate
+	AuthFailures config.Limit
+}
+
+// UnaryInterceptor has to run after authentication, so calls are limited by who made them
+func (l *Limiter) UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if err := l.allow(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// AuthUnaryInterceptor has to run before authentication. It turns away addresses that have
+// failed authentication too often, and counts the calls from each address that fail it.
+func (l *Limiter) AuthUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	var resp interface{}
+	err := l.guardAuthentication(ctx, info.FullMethod, func() error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	})
+
+	return resp, err
+}
+
+func (l *Limiter) AuthStreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	return l.guardAuthentication(ss.Context(), info.FullMethod, func() error {
+		return handler(srv, ss)
+	})
+}
+
+// StreamInterceptor limits how often streams are opened, rather than the messages sent on them
+func (l *Limiter) StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := l.allow(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+func (l *Limiter) allow(ctx context.Context, method string) error {
+	if l.Exempt[method] {
+		return nil
+	}
+
+	limit, ok := l.Methods[method]
+	if !ok {
+		limit = l.Default
+	}
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	decision, err := l.Store.Take(ctx, method+"|"+caller(ctx), limit)
+	if err != nil {
+		// Failing open, so the limiter's store being down doesn't take every method down with it
+		slog.WarnContext(ctx, "failed to check rate limit, allowing call", "method", method, "error", err)
+		return nil
+	}
+	if !decision.Allowed {
+		rateLimited.WithLabelValues(method).Inc()
+		return e.RateLimitError{Err: ErrRateLimited, RetryAfter: decision.RetryAfter}
+	}
+
+	return nil
+}
+
+// guardAuthentication runs call, which authenticates the call and handles it, unless the caller's
+// address is out of failures. Only failures take a token, so clients with valid credentials are
+// never held up by it.
+func (l *Limiter) guardAuthentication(ctx context.Context, method string, call func() error) error {
+	if l.Exempt[method] || l.AuthFailures.Rate <= 0 {
+		return call()
+	}
+
+	// Before authentication there's no principal, so this is the client's address
+	key := "auth-failures|" + caller(ctx)
+	decision, err := l.Store.Peek(ctx, key, l.AuthFailures)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to check auth failure limit, allowing call", "method", method, "error", err)
+	} else if !decision.Allowed {
+		rateLimited.WithLabelValues(method).Inc()
+		return e.RateLimitError{Err: ErrRateLimited, RetryAfter: decision.RetryAfter}
+	}
+
+	err = call()
+	var authErr auth.AuthError
+	if errors.As(err, &authErr) {
+		if _, takeErr := l.Store.Take(ctx, key, l.AuthFailures); takeErr != nil {
+			slog.WarnContext(ctx, "failed to record auth failure", "method", method, "error", takeErr)
+		}
+	}
+
+	return err
+}
+
+// caller identifies who's calling: the authenticated user, or the client's address for public
+// methods
+func caller(ctx context.Context) string {
+	if principal, ok := auth.FromContext(ctx); ok {
+		return "user:" + principal.UserId.String()
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "addr:unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+
+	// Only trusted from this host, where the gateway calls from, so clients can't pick their own
+	// bucket. That's over loopback, or from the address the server listens on when it's dialled
+	// there.
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || isLocalAddr(ip, p.LocalAddr)) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if forwarded := md.Get(ForwardedForKey); len(forwarded) > 0 {
+			return "addr:" + forwarded[0]
+		}
+	}
+
+	return "addr:" + host
+}
+
+// isLocalAddr reports whether ip is the address of local, the server's end of the connection
+func isLocalAddr(ip net.IP, local net.Addr) bool {
+	tcpAddr, ok := local.(*net.TCPAddr)
+	return ok && tcpAddr.IP.Equal(ip)
+}
+
+// RunSweep sweeps idle buckets from store every interval until ctx is done
+func RunSweep(ctx context.Context, store Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := store.Sweep(ctx, bucketIdleTimeout); err != nil {
+			slog.ErrorContext(ctx, "failed to sweep rate limit buckets", "error", err)
+		}
+	}
+}
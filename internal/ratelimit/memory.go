@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"chariottakehome/internal/config"
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxMemoryBuckets bounds how many buckets a MemoryStore holds, so callers rotating through
+// addresses can't grow it without limit
+const maxMemoryBuckets = 100_000
+
+// MemoryStore keeps buckets in the process, so each replica limits callers separately. Once it
+// holds maxBuckets the least recently used bucket is dropped for each new one, which only resets
+// the limit of a caller that's been quiet the longest.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// byUse orders buckets most recently used first
+	byUse      *list.List
+	maxBuckets int
+	now        func() time.Time
+}
+
+type bucket struct {
+	key       string
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:    make(map[string]*list.Element),
+		byUse:      list.New(),
+		maxBuckets: maxMemoryBuckets,
+		now:        time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit config.Limit) (Decision, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.buckets[key]
+	if !ok {
+		if s.byUse.Len() >= s.maxBuckets {
+			oldest := s.byUse.Back()
+			delete(s.buckets, oldest.Value.(*bucket).key)
+			s.byUse.Remove(oldest)
+		}
+		elem = s.byUse.PushFront(&bucket{key: key, tokens: float64(limit.Burst), updatedAt: now})
+		s.buckets[key] = elem
+	}
+	s.byUse.MoveToFront(elem)
+
+	b := elem.Value.(*bucket)
+	b.tokens = refill(b.tokens, now.Sub(b.updatedAt), limit)
+	b.updatedAt = now
+	if b.tokens < 1 {
+		return Decision{RetryAfter: retryAfter(b.tokens, limit)}, nil
+	}
+
+	b.tokens--
+	return Decision{Allowed: true}, nil
+}
+
+func (s *MemoryStore) Peek(ctx context.Context, key string, limit config.Limit) (Decision, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.buckets[key]
+	if !ok {
+		return Decision{Allowed: true}, nil
+	}
+
+	b := elem.Value.(*bucket)
+	tokens := refill(b.tokens, now.Sub(b.updatedAt), limit)
+	if tokens < 1 {
+		return Decision{RetryAfter: retryAfter(tokens, limit)}, nil
+	}
+
+	return Decision{Allowed: true}, nil
+}
+
+func (s *MemoryStore) Sweep(ctx context.Context, idle time.Duration) error {
+	cutoff := s.now().Add(-idle)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Least recently used first, so it can stop at the first bucket that's still in use
+	for elem := s.byUse.Back(); elem != nil; elem = s.byUse.Back() {
+		b := elem.Value.(*bucket)
+		if !b.updatedAt.Before(cutoff) {
+			break
+		}
+		delete(s.buckets, b.key)
+		s.byUse.Remove(elem)
+	}
+
+	return nil
+}
+
+// refill returns how many tokens a bucket holding tokens has after elapsed
+func refill(tokens float64, elapsed time.Duration, limit config.Limit) float64 {
+	if elapsed < 0 {
+		// The clock went backwards, don't take tokens away for it
+		elapsed = 0
+	}
+
+	return math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+}
+
+// retryAfter returns how long until a bucket holding tokens holds a whole token
+func retryAfter(tokens float64, limit config.Limit) time.Duration {
+	seconds := (1 - tokens) / limit.Rate
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
@@ -0,0 +1,11 @@
+package ratelimit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "grpc_server_rate_limited_total",
+	Help: "Calls rejected for exceeding their rate limit, by method.",
+}, []string{"method"})
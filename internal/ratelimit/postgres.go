@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"chariottakehome/internal/config"
+	"chariottakehome/internal/database"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so every replica shares them. It
+// goes by the database's clock, so replicas' clocks don't need to agree.
+type PostgresStore struct {
+	database *database.DatabasePool
+}
+
+func NewPostgresStore(database *database.DatabasePool) *PostgresStore {
+	return &PostgresStore{database}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit config.Limit) (Decision, error) {
+	var tokens float64
+	err := s.database.QueryRow(ctx, takeToken, key, limit.Rate, float64(limit.Burst)).Scan(&tokens)
+	if err == nil {
+		return Decision{Allowed: true}, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Decision{}, err
+	}
+
+	// The bucket is empty. This read is only for the retry delay, so it doesn't matter if it
+	// races with other calls.
+	err = s.database.QueryRow(ctx, selectTokens, key, limit.Rate, float64(limit.Burst)).Scan(&tokens)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Swept in between, so it'll be full by the time the caller retries
+		return Decision{RetryAfter: retryAfter(0, limit)}, nil
+	}
+	if err != nil {
+		return Decision{}, err
+	}
+
+	return Decision{RetryAfter: retryAfter(tokens, limit)}, nil
+}
+
+func (s *PostgresStore) Peek(ctx context.Context, key string, limit config.Limit) (Decision, error) {
+	var tokens float64
+	err := s.database.QueryRow(ctx, selectTokens, key, limit.Rate, float64(limit.Burst)).Scan(&tokens)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Decision{Allowed: true}, nil
+	}
+	if err != nil {
+		return Decision{}, err
+	}
+	if tokens < 1 {
+		return Decision{RetryAfter: retryAfter(tokens, limit)}, nil
+	}
+
+	return Decision{Allowed: true}, nil
+}
+
+func (s *PostgresStore) Sweep(ctx context.Context, idle time.Duration) error {
+	_, err := s.database.Exec(ctx, deleteIdleBuckets, idle.Seconds())
+	return err
+}
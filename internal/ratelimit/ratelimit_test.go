@@ -0,0 +1,286 @@
+package ratelimit
+
+import (
+	e "chariottakehome/api/errors"
+	"chariottakehome/internal/auth"
+	"chariottakehome/internal/config"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const withdrawMethod string = "/users.AccountService/WithdrawFunds"
+
+// fakeClock is a MemoryStore clock that only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+
+	return store, clock
+}
+
+func take(t *testing.T, store Store, key string, limit config.Limit) Decision {
+	decision, err := store.Take(context.Background(), key, limit)
+	if err != nil {
+		t.Fatalf("Failed to take a token: %s", err)
+	}
+
+	return decision
+}
+
+func TestMemoryStore(t *testing.T) {
+	store, clock := newTestStore()
+	limit := config.Limit{Rate: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if !take(t, store, "a", limit).Allowed {
+			t.Fatalf("Expected the burst of 3 to be allowed, call %d wasn't", i+1)
+		}
+	}
+
+	decision := take(t, store, "a", limit)
+	if decision.Allowed || decision.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("Expected to be told to retry in 500ms, got %+v", decision)
+	}
+	if !take(t, store, "b", limit).Allowed {
+		t.Fatalf("Expected other keys to have their own bucket")
+	}
+
+	clock.now = clock.now.Add(250 * time.Millisecond)
+	if decision := take(t, store, "a", limit); decision.Allowed || decision.RetryAfter != 250*time.Millisecond {
+		t.Fatalf("Expected half a token after 250ms, got %+v", decision)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if !take(t, store, "a", limit).Allowed {
+			t.Fatalf("Expected the bucket to refill up to its burst")
+		}
+	}
+	if take(t, store, "a", limit).Allowed {
+		t.Fatalf("Expected the bucket not to refill past its burst")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store, clock := newTestStore()
+	limit := config.Limit{Rate: 1, Burst: 1}
+
+	take(t, store, "old", limit)
+	clock.now = clock.now.Add(2 * time.Hour)
+	take(t, store, "new", limit)
+
+	if err := store.Sweep(context.Background(), time.Hour); err != nil {
+		t.Fatalf("Failed to sweep: %s", err)
+	}
+	if _, ok := store.buckets["old"]; ok {
+		t.Fatalf("Expected the idle bucket to be swept")
+	}
+	if _, ok := store.buckets["new"]; !ok {
+		t.Fatalf("Expected the recent bucket to be kept")
+	}
+}
+
+func TestMemoryStoreBound(t *testing.T) {
+	store, clock := newTestStore()
+	store.maxBuckets = 2
+	limit := config.Limit{Rate: 1, Burst: 1}
+
+	take(t, store, "a", limit)
+	clock.now = clock.now.Add(time.Millisecond)
+	take(t, store, "b", limit)
+	clock.now = clock.now.Add(time.Millisecond)
+	// a is used again, so b is now the least recently used
+	take(t, store, "a", limit)
+	take(t, store, "c", limit)
+
+	if len(store.buckets) != 2 || store.byUse.Len() != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", len(store.buckets))
+	}
+	if _, ok := store.buckets["b"]; ok {
+		t.Fatalf("Expected the least recently used bucket to be dropped")
+	}
+	if take(t, store, "a", limit).Allowed {
+		t.Fatalf("Expected the recently used bucket to be kept")
+	}
+}
+
+// failingStore stands in for a store that can't be reached
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit config.Limit) (Decision, error) {
+	return Decision{}, errors.New("connection refused")
+}
+
+func (failingStore) Peek(ctx context.Context, key string, limit config.Limit) (Decision, error) {
+	return Decision{}, errors.New("connection refused")
+}
+
+func (failingStore) Sweep(ctx context.Context, idle time.Duration) error {
+	return nil
+}
+
+func userContext(t *testing.T) context.Context {
+	userId, err := id.New()
+	if err != nil {
+		t.Fatalf("Failed to generate identifier: %s", err)
+	}
+
+	return auth.NewContext(context.Background(), &auth.Principal{UserId: userId})
+}
+
+func TestLimiter(t *testing.T) {
+	store, _ := newTestStore()
+	limiter := &Limiter{
+		Store:   store,
+		Default: config.Limit{Rate: 1, Burst: 5},
+		Methods: map[string]config.Limit{
+			withdrawMethod:                     {Rate: 1, Burst: 1},
+			"/users.AccountService/GetAccount": {Rate: 0},
+		},
+		Exempt: map[string]bool{"/grpc.health.v1.Health/Check": true},
+	}
+
+	alice, bob := userContext(t), userContext(t)
+	if err := limiter.allow(alice, withdrawMethod); err != nil {
+		t.Fatalf("Expected the first call to be allowed, got %s", err)
+	}
+
+	err := limiter.allow(alice, withdrawMethod)
+	var rateLimitErr e.RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != time.Second {
+		t.Fatalf("Expected a RateLimitError retrying in 1s, got %v", err)
+	}
+	if e.ToStatus(err).Code() != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %s", e.ToStatus(err).Code())
+	}
+
+	if err := limiter.allow(bob, withdrawMethod); err != nil {
+		t.Fatalf("Expected each principal to have their own bucket, got %s", err)
+	}
+	if err := limiter.allow(alice, "/users.AccountService/DepositFunds"); err != nil {
+		t.Fatalf("Expected each method to have its own bucket, got %s", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := limiter.allow(alice, "/users.AccountService/GetAccount"); err != nil {
+			t.Fatalf("Expected a zero rate to mean no limit, got %s", err)
+		}
+		if err := limiter.allow(alice, "/grpc.health.v1.Health/Check"); err != nil {
+			t.Fatalf("Expected exempt methods not to be limited, got %s", err)
+		}
+	}
+
+	limiter.Store = failingStore{}
+	if err := limiter.allow(alice, withdrawMethod); err != nil {
+		t.Fatalf("Expected calls to be allowed when the store fails, got %s", err)
+	}
+}
+
+func TestGuardAuthentication(t *testing.T) {
+	store, clock := newTestStore()
+	limiter := &Limiter{Store: store, AuthFailures: config.Limit{Rate: 1, Burst: 2}}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 5000}})
+
+	calls := 0
+	guess := func() error {
+		return limiter.guardAuthentication(ctx, withdrawMethod, func() error {
+			calls++
+			return auth.ErrInvalidCredentials
+		})
+	}
+	succeed := func() error {
+		return limiter.guardAuthentication(ctx, withdrawMethod, func() error {
+			calls++
+			return nil
+		})
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := succeed(); err != nil {
+			t.Fatalf("Expected calls that authenticate not to be limited, got %s", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := guess(); !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("Expected the failure to be returned, got %v", err)
+		}
+	}
+
+	calls = 0
+	var rateLimitErr e.RateLimitError
+	if err := guess(); !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != time.Second {
+		t.Fatalf("Expected a RateLimitError retrying in 1s, got %v", err)
+	}
+	if err := succeed(); !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected the address to be turned away, got %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("Expected credentials not to be checked once the address is limited")
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	if err := succeed(); err != nil {
+		t.Fatalf("Expected the bucket to refill, got %s", err)
+	}
+
+	limiter.Store = failingStore{}
+	if err := guess(); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Fatalf("Expected calls to go ahead when the store fails, got %v", err)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	withPeer := func(ip string, md metadata.MD) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+		return metadata.NewIncomingContext(ctx, md)
+	}
+	forwarded := metadata.Pairs(ForwardedForKey, "203.0.113.7")
+	// The gateway dialling the server on the address it listens on, rather than loopback
+	withLocalPeer := func(ip string, md metadata.MD) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			Addr:      &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000},
+			LocalAddr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 8080},
+		})
+		return metadata.NewIncomingContext(ctx, md)
+	}
+
+	cases := []struct {
+		ctx  context.Context
+		want string
+	}{
+		{withPeer("198.51.100.1", nil), "addr:198.51.100.1"},
+		{withPeer("127.0.0.1", forwarded), "addr:203.0.113.7"},
+		// Only the gateway, on this host, is trusted to say who it's calling for
+		{withPeer("198.51.100.1", forwarded), "addr:198.51.100.1"},
+		{withLocalPeer("10.0.0.5", forwarded), "addr:203.0.113.7"},
+		{context.Background(), "addr:unknown"},
+	}
+	for _, c := range cases {
+		if got := caller(c.ctx); got != c.want {
+			t.Fatalf("Expected %s, got %s", c.want, got)
+		}
+	}
+
+	ctx := userContext(t)
+	principal, _ := auth.FromContext(ctx)
+	if got := caller(ctx); got != "user:"+principal.UserId.String() {
+		t.Fatalf("Expected the principal's user, got %s", got)
+	}
+}
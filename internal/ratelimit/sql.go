@@ -0,0 +1,29 @@
+package ratelimit
+
+const (
+	// utcNow is when the statement started, in UTC. updated_at has no time zone, so LOCALTIMESTAMP
+	// would depend on each session's TimeZone setting. It's the same throughout a statement, so
+	// the refill checked is the refill written.
+	utcNow string = `(statement_timestamp() AT TIME ZONE 'UTC')`
+
+	// refilledTokens is how many tokens bucket b holds now, for a rate of $2 and burst of $3. Time
+	// going backwards doesn't take tokens away, as in the memory store's refill.
+	refilledTokens string = `LEAST($3::float8, b.tokens + GREATEST(0, EXTRACT(EPOCH FROM ` + utcNow + ` - b.updated_at)::float8) * $2::float8)`
+
+	// takeToken returns the tokens left if a token was taken, and no row if the bucket didn't
+	// have one. The check and the update are one statement, so concurrent calls can't both take
+	// the last token.
+	takeToken string = `INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at)
+	VALUES ($1, $3::float8 - 1, ` + utcNow + `)
+	ON CONFLICT (key) DO UPDATE
+	SET tokens = ` + refilledTokens + ` - 1, updated_at = ` + utcNow + `
+	WHERE ` + refilledTokens + ` >= 1
+	RETURNING tokens`
+
+	selectTokens string = `SELECT ` + refilledTokens + `
+	FROM rate_limit_buckets AS b
+	WHERE key = $1`
+
+	deleteIdleBuckets string = `DELETE FROM rate_limit_buckets
+	WHERE updated_at < ` + utcNow + ` - make_interval(secs => $1)`
+)
@@ -0,0 +1,123 @@
+package telemetry
+
+import (
+	e "chariottakehome/api/errors"
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryInterceptor traces and measures each call. It should be the outermost interceptor so it
+// sees the status code the client actually receives.
+func UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, span := startSpan(ctx, info.FullMethod)
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	finish(span, info.FullMethod, start, err)
+	return resp, err
+}
+
+// StreamInterceptor traces and measures each stream, like UnaryInterceptor
+func StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, span := startSpan(ss.Context(), info.FullMethod)
+	start := time.Now()
+
+	err := handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+
+	finish(span, info.FullMethod, start, err)
+	return err
+}
+
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
+
+// startSpan starts a server span for fullMethod, continuing the caller's trace if it sent one
+func startSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+	service, method := splitMethod(fullMethod)
+	return otel.Tracer(instrumentationName).Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", method),
+		),
+	)
+}
+
+func finish(span trace.Span, fullMethod string, start time.Time, err error) {
+	code := status.Code(err)
+
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if e.IsServerError(code) {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+
+	rpcHandled.WithLabelValues(fullMethod, code.String()).Inc()
+	rpcDuration.WithLabelValues(fullMethod).Observe(time.Since(start).Seconds())
+}
+
+// splitMethod splits "/package.Service/Method" into its service and method
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+
+	return "", fullMethod
+}
+
+// metadataCarrier lets the propagator read trace context from gRPC metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
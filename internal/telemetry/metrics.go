@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	rpcHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "RPCs completed on the server, by method and status code.",
+	}, []string{"grpc_method", "grpc_code"})
+
+	rpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Time taken to handle RPCs, by method.",
+		Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"grpc_method"})
+)
+
+// poolCollector reports a connection pool's stats each time metrics are scraped
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquired     *prometheus.Desc
+	idle         *prometheus.Desc
+	total        *prometheus.Desc
+	max          *prometheus.Desc
+	acquires     *prometheus.Desc
+	emptyAcquire *prometheus.Desc
+	acquireWait  *prometheus.Desc
+}
+
+// NewPoolCollector collects the stats returned by stat, which is usually a pgxpool.Pool's Stat
+func NewPoolCollector(stat func() *pgxpool.Stat) prometheus.Collector {
+	return &poolCollector{
+		stat:         stat,
+		acquired:     prometheus.NewDesc("pgxpool_acquired_conns", "Connections currently checked out of the pool.", nil, nil),
+		idle:         prometheus.NewDesc("pgxpool_idle_conns", "Idle connections in the pool.", nil, nil),
+		total:        prometheus.NewDesc("pgxpool_total_conns", "Connections in the pool, including ones being opened.", nil, nil),
+		max:          prometheus.NewDesc("pgxpool_max_conns", "Most connections the pool will open.", nil, nil),
+		acquires:     prometheus.NewDesc("pgxpool_acquires_total", "Connections checked out of the pool.", nil, nil),
+		emptyAcquire: prometheus.NewDesc("pgxpool_empty_acquires_total", "Acquires that had to wait for a connection.", nil, nil),
+		acquireWait:  prometheus.NewDesc("pgxpool_acquire_wait_seconds_total", "Time spent waiting to acquire connections.", nil, nil),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
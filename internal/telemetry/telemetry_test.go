@@ -0,0 +1,83 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// recordSpans installs a tracer provider that keeps every span for the rest of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	recorder := recordSpans(t)
+	const method = "/users.AccountService/GetAccount"
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	notFound := testutil.ToFloat64(rpcHandled.WithLabelValues(method, codes.NotFound.String()))
+
+	// The caller's trace is continued
+	parent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", parent))
+
+	var handlerSpan trace.SpanContext
+	_, err := UnaryInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return nil, status.Error(codes.NotFound, "resource not found")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected the handler's error to be returned, got %v", err)
+	}
+
+	if got := testutil.ToFloat64(rpcHandled.WithLabelValues(method, codes.NotFound.String())); got != notFound+1 {
+		t.Fatalf("Expected the call to be counted, got %v", got-notFound)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "users.AccountService/GetAccount" || !span.SpanContext().Equal(handlerSpan) {
+		t.Fatalf("Unexpected span %s", span.Name())
+	}
+	if span.Parent().TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Fatalf("Expected the caller's trace to continue, got %s", span.Parent().TraceID())
+	}
+	// A refused request isn't a server error
+	if span.Status().Code.String() != "Unset" {
+		t.Fatalf("Expected the span status to be unset, got %s", span.Status().Code)
+	}
+}
+
+func TestStatementName(t *testing.T) {
+	for sql, want := range map[string]string{
+		"SELECT id FROM accounts":         "db.SELECT",
+		"\n\tinsert into transactions ()": "db.INSERT",
+		"begin":                           "db.BEGIN",
+		"":                                "db.query",
+	} {
+		if got := statementName(sql); got != want {
+			t.Fatalf("Expected %q to be named %s, got %s", sql, want, got)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package telemetry exposes Prometheus metrics and OpenTelemetry traces for the gRPC server and
+// the database
+package telemetry
+
+import (
+	"chariottakehome/internal/config"
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	serviceName string = "chariottakehome"
+
+	// instrumentationName identifies the spans started by this package
+	instrumentationName string = "chariottakehome/internal/telemetry"
+)
+
+// SetupTracing installs the global tracer provider for the configured exporter, returning a
+// function that flushes any buffered spans. With no exporter spans aren't recorded at all.
+func SetupTracing(ctx context.Context, cfg config.Telemetry) (func(context.Context) error, error) {
+	// Trace context is propagated either way, so callers' traces continue through to any
+	// services we call
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch cfg.TraceExporter {
+	case config.TraceExporterOtlp:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OtlpEndpoint)}
+		if cfg.OtlpInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case config.TraceExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.TraceExporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
@@ -7,8 +7,16 @@ import (
 	"time"
 )
 
+type ListUsersResp struct {
+	Users      []User
+	NextCursor *id.Identifier
+}
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, email string) (*User, error)
+	GetUser(ctx context.Context, userId id.Identifier) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	ListUsers(ctx context.Context, startCursor *id.Identifier, pageSize int) (*ListUsersResp, error)
 }
 
 type userRepository struct {
@@ -42,3 +50,66 @@ func (r *userRepository) CreateUser(ctx context.Context, email string) (*User, e
 
 	return &user, nil
 }
+
+func (r *userRepository) GetUser(ctx context.Context, userId id.Identifier) (*User, error) {
+	var user User
+	row := r.database.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, userId)
+	if err := scanUser(row, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	var user User
+	row := r.database.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1`, email)
+	if err := scanUser(row, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *userRepository) ListUsers(ctx context.Context, startCursor *id.Identifier, pageSize int) (*ListUsersResp, error) {
+	start := "00000000000000000000"
+	if startCursor != nil {
+		start = startCursor.String()
+	}
+
+	rows, err := r.database.Query(ctx, `SELECT `+userColumns+`
+	FROM users
+	WHERE id >= $1
+	ORDER BY id
+	LIMIT $2
+	`, start, pageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]User, 0)
+	for rows.Next() {
+		var u User
+		if err := scanUser(rows, &u); err != nil {
+			return nil, err
+		}
+
+		results = append(results, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var nextCursor *id.Identifier
+	// the extra row is the first result of the next page
+	if len(results) > pageSize {
+		nextCursor = &results[pageSize].Id
+		results = results[:pageSize]
+	}
+
+	return &ListUsersResp{
+		Users:      results,
+		NextCursor: nextCursor,
+	}, nil
+}
@@ -1,14 +1,24 @@
 package users
 
-const insertUser string = `INSERT INTO users (
+import "github.com/jackc/pgx/v5"
+
+const (
+	insertUser string = `INSERT INTO users (
 	id,
 	email,
 	created_at,
 	updated_at
 	) VALUES ($1, $2, $3, $4)`
 
+	userColumns string = `id, email, created_at, updated_at`
+)
+
 func prepareInsertUser(u User) (string, []any) {
 	args := []any{u.Id, u.Email, u.CreatedAt, u.UpdatedAt}
 
 	return insertUser, args
 }
+
+func scanUser(row pgx.Row, u *User) error {
+	return row.Scan(&u.Id, &u.Email, &u.CreatedAt, &u.UpdatedAt)
+}
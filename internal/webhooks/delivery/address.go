@@ -0,0 +1,109 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// nonGlobalPrefixes are the ranges that aren't reachable on the public internet, from the IANA
+// special-purpose address registries, plus the IPv6 ranges that embed an IPv4 address, so an
+// internal address can't be reached through a translator.
+var nonGlobalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // shared address space (CGNAT)
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local, including cloud metadata services
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, including broadcast
+
+	netip.MustParsePrefix("::/128"),         // unspecified
+	netip.MustParsePrefix("::1/128"),        // loopback
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local NAT64
+	netip.MustParsePrefix("100::/64"),       // discard
+	netip.MustParsePrefix("2001::/23"),      // IETF protocol assignments, including Teredo
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("2002::/16"),      // 6to4
+	netip.MustParsePrefix("fc00::/7"),       // unique local
+	netip.MustParsePrefix("fe80::/10"),      // link-local
+	netip.MustParsePrefix("fec0::/10"),      // site-local
+	netip.MustParsePrefix("ff00::/8"),       // multicast
+}
+
+// CheckAddr refuses addresses inside our own network, so an endpoint can't point deliveries at
+// the metadata service, the database, or the API itself. IPv4-mapped addresses are checked as
+// the IPv4 address they map.
+func CheckAddr(addr netip.Addr) error {
+	addr = addr.Unmap().WithZone("")
+	if !addr.IsValid() {
+		return ErrForbiddenAddress
+	}
+
+	for _, prefix := range nonGlobalPrefixes {
+		if prefix.Contains(addr) {
+			return ErrForbiddenAddress
+		}
+	}
+
+	return nil
+}
+
+// CheckUrl checks a receiver url uses https, or http if allowHttp is set, and that its host only
+// resolves to addresses CheckAddr allows. The client checks again when it connects, as the host
+// can resolve somewhere else by then.
+func CheckUrl(ctx context.Context, rawUrl string, allowHttp bool) error {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return errors.New("invalid url")
+	}
+	if parsed.Scheme != "https" && !(allowHttp && parsed.Scheme == "http") {
+		if allowHttp {
+			return errors.New("url must use http or https")
+		}
+		return errors.New("url must use https")
+	}
+
+	host := parsed.Hostname()
+	if host == "" {
+		return errors.New("url must include a host")
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return CheckAddr(addr)
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return errors.New("url host doesn't resolve")
+	}
+	for _, addr := range addrs {
+		if err := CheckAddr(addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkDial is a net.Dialer Control hook applying CheckAddr to the resolved address being dialled
+func checkDial(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	return CheckAddr(addrPort.Addr())
+}
@@ -0,0 +1,25 @@
+package delivery
+
+import "time"
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it's dead-lettered
+	MaxAttempts int = 10
+
+	initialBackoff time.Duration = 30 * time.Second
+	maxBackoff     time.Duration = 6 * time.Hour
+)
+
+// Backoff is how long to wait after the given number of failed attempts, doubling each time
+// from 30 seconds up to 6 hours
+func Backoff(attempts int) time.Duration {
+	backoff := initialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return backoff
+}
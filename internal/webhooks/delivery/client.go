@@ -0,0 +1,102 @@
+// Package delivery sends signed webhook requests. It knows nothing about storage so it can be
+// exercised against any HTTP receiver.
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	EventIdHeader    string = "Chariot-Event-Id"
+	EventTypeHeader  string = "Chariot-Event-Type"
+	DeliveryIdHeader string = "Chariot-Delivery-Id"
+
+	requestTimeout time.Duration = 10 * time.Second
+)
+
+type Request struct {
+	Url        string
+	Secret     string
+	DeliveryId string
+	EventId    string
+	EventType  string
+	Payload    json.RawMessage
+	CreatedAt  time.Time
+}
+
+// envelope is the JSON body receivers get, with the event's payload under data
+type envelope struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type Client struct {
+	http *http.Client
+}
+
+// NewClient sends deliveries with httpClient, or if nil with a client that has a 10 second
+// timeout and refuses to connect to addresses CheckAddr rejects. Redirects are never followed,
+// since they could lead anywhere.
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// A proxy would make the connection on our behalf, out of reach of the dial check
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{Timeout: requestTimeout, Control: checkDial}).DialContext
+		httpClient = &http.Client{Timeout: requestTimeout, Transport: transport}
+	}
+
+	client := *httpClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Client{http: &client}
+}
+
+// Send POSTs the signed event and returns the receiver's status code. Any status outside 2xx is
+// reported as ErrRejected; the status code is 0 if no response was received.
+func (c *Client) Send(ctx context.Context, req Request) (int, error) {
+	body, err := json.Marshal(envelope{
+		Id:        req.EventId,
+		Type:      req.EventType,
+		CreatedAt: req.CreatedAt,
+		Data:      req.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(SignatureHeader, Sign(req.Secret, time.Now(), body))
+	httpReq.Header.Set(EventIdHeader, req.EventId)
+	httpReq.Header.Set(EventTypeHeader, req.EventType)
+	httpReq.Header.Set(DeliveryIdHeader, req.DeliveryId)
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, ErrRejected
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,211 @@
+package delivery_test
+
+import (
+	"chariottakehome/internal/webhooks/delivery"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+const secret = "whsec_test"
+
+func newRequest(url string) delivery.Request {
+	return delivery.Request{
+		Url:        url,
+		Secret:     secret,
+		DeliveryId: "c-0000000000DELIVERY",
+		EventId:    "c-000000000000EVENT0",
+		EventType:  "FundsDeposited",
+		Payload:    json.RawMessage(`{"amount":1234}`),
+		CreatedAt:  time.Now().UTC(),
+	}
+}
+
+func TestSendSignsDelivery(t *testing.T) {
+	var (
+		body    []byte
+		headers http.Header
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	status, err := delivery.NewClient(receiver.Client()).Send(context.Background(), newRequest(receiver.URL))
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("Expected delivery to succeed, got %d, %v", status, err)
+	}
+
+	if err := delivery.Verify(secret, headers.Get(delivery.SignatureHeader), body, time.Minute, time.Now()); err != nil {
+		t.Fatalf("Failed to verify signature: %s", err)
+	}
+
+	if headers.Get(delivery.EventTypeHeader) != "FundsDeposited" {
+		t.Fatalf("Unexpected event type header '%s'", headers.Get(delivery.EventTypeHeader))
+	}
+
+	var envelope struct {
+		Id   string          `json:"id"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("Failed to decode body: %s", err)
+	}
+	if envelope.Id != "c-000000000000EVENT0" || string(envelope.Data) != `{"amount":1234}` {
+		t.Fatalf("Unexpected body %s", body)
+	}
+}
+
+func TestSendReportsRejection(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	status, err := delivery.NewClient(receiver.Client()).Send(context.Background(), newRequest(receiver.URL))
+	if !errors.Is(err, delivery.ErrRejected) || status != http.StatusServiceUnavailable {
+		t.Fatalf("Expected a rejected delivery, got %d, %v", status, err)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	var followed bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	status, err := delivery.NewClient(receiver.Client()).Send(context.Background(), newRequest(receiver.URL))
+	if followed || !errors.Is(err, delivery.ErrRejected) || status != http.StatusTemporaryRedirect {
+		t.Fatalf("Expected the redirect to be reported rather than followed, got %d, %v", status, err)
+	}
+}
+
+func TestSendRefusesInternalAddresses(t *testing.T) {
+	var reached bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer receiver.Close()
+
+	// The default client checks the address it dials, whatever the url's host resolved to
+	_, err := delivery.NewClient(nil).Send(context.Background(), newRequest(receiver.URL))
+	if reached || !errors.Is(err, delivery.ErrForbiddenAddress) {
+		t.Fatalf("Expected the loopback receiver to be refused, got %v", err)
+	}
+}
+
+func TestCheckAddr(t *testing.T) {
+	cases := []struct {
+		addr    string
+		allowed bool
+	}{
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.10", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"fe80::1%eth0", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"0.1.2.3", false},
+		{"192.0.0.8", false},
+		{"198.18.0.1", false},
+		{"198.19.255.255", false},
+		{"240.0.0.1", false},
+		{"255.255.255.255", false},
+		{"224.0.0.251", false},
+		{"64:ff9b::a9fe:a9fe", false},
+		{"64:ff9b::7f00:1", false},
+		{"2002:7f00:1::1", false},
+		{"2001:0:4136:e378::1", false},
+		{"ff02::1", false},
+		{"100.128.0.1", true},
+		{"198.20.0.1", true},
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+	}
+
+	for _, c := range cases {
+		err := delivery.CheckAddr(netip.MustParseAddr(c.addr))
+		if (err == nil) != c.allowed {
+			t.Fatalf("Expected %s allowed to be %t, got %v", c.addr, c.allowed, err)
+		}
+	}
+}
+
+func TestCheckUrl(t *testing.T) {
+	ctx := context.Background()
+
+	if err := delivery.CheckUrl(ctx, "http://93.184.216.34/hook", false); err == nil {
+		t.Fatalf("Expected http to be refused unless allowed")
+	}
+	if err := delivery.CheckUrl(ctx, "http://93.184.216.34/hook", true); err != nil {
+		t.Fatalf("Expected http to be accepted when allowed, got %v", err)
+	}
+	if err := delivery.CheckUrl(ctx, "https://169.254.169.254/latest/meta-data", false); !errors.Is(err, delivery.ErrForbiddenAddress) {
+		t.Fatalf("Expected the metadata address to be refused, got %v", err)
+	}
+	if err := delivery.CheckUrl(ctx, "https:///hook", false); err == nil {
+		t.Fatalf("Expected a url without a host to be refused")
+	}
+}
+
+func TestVerifyRejectsTamperedAndStaleSignatures(t *testing.T) {
+	body := []byte(`{"id":"evt"}`)
+	signedAt := time.Now()
+	header := delivery.Sign(secret, signedAt, body)
+
+	if err := delivery.Verify(secret, header, []byte(`{"id":"other"}`), time.Minute, signedAt); !errors.Is(err, delivery.ErrBadSignature) {
+		t.Fatalf("Failed to reject tampered body, got %v", err)
+	}
+
+	if err := delivery.Verify("other secret", header, body, time.Minute, signedAt); !errors.Is(err, delivery.ErrBadSignature) {
+		t.Fatalf("Failed to reject wrong secret, got %v", err)
+	}
+
+	if err := delivery.Verify(secret, header, body, time.Minute, signedAt.Add(time.Hour)); !errors.Is(err, delivery.ErrStaleSignature) {
+		t.Fatalf("Failed to reject stale signature, got %v", err)
+	}
+
+	if err := delivery.Verify(secret, "garbage", body, time.Minute, signedAt); !errors.Is(err, delivery.ErrMalformedSignature) {
+		t.Fatalf("Failed to reject malformed header, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		backoff  time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{10, 4*time.Hour + 16*time.Minute},
+		{11, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+
+	for _, c := range cases {
+		if got := delivery.Backoff(c.attempts); got != c.backoff {
+			t.Fatalf("Expected %s after %d attempts, got %s", c.backoff, c.attempts, got)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package delivery
+
+type errReason string
+
+const (
+	Rejected           errReason = "Receiver didn't respond with a 2xx status."
+	MalformedSignature errReason = "Signature header is malformed."
+	BadSignature       errReason = "Signature doesn't match the payload."
+	StaleSignature     errReason = "Signature timestamp is outside the tolerance."
+	ForbiddenAddress   errReason = "Receiver address is loopback, private, link-local, or unspecified."
+)
+
+type DeliveryError struct {
+	reason errReason
+}
+
+func (e DeliveryError) Error() string {
+	return "Webhook delivery failed: " + string(e.reason)
+}
+
+var (
+	ErrRejected           = DeliveryError{reason: Rejected}
+	ErrMalformedSignature = DeliveryError{reason: MalformedSignature}
+	ErrBadSignature       = DeliveryError{reason: BadSignature}
+	ErrStaleSignature     = DeliveryError{reason: StaleSignature}
+	ErrForbiddenAddress   = DeliveryError{reason: ForbiddenAddress}
+)
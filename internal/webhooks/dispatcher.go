@@ -0,0 +1,136 @@
+package webhooks
+
+import (
+	"chariottakehome/internal/events"
+	"chariottakehome/internal/webhooks/delivery"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	enqueueBatchSize  int = 100
+	deliveryBatchSize int = 50
+	// Concurrent requests per batch, so one slow receiver doesn't hold up everyone else's
+	deliveryWorkers int = 8
+	// Comfortably longer than a request can take, so a lease never runs out mid-attempt
+	deliveryLease time.Duration = time.Minute
+)
+
+// Dispatcher turns published events into webhook deliveries and sends whichever are due
+type Dispatcher struct {
+	repo   WebhookRepository
+	client *delivery.Client
+	wake   chan struct{}
+}
+
+func NewDispatcher(repo WebhookRepository, client *delivery.Client) *Dispatcher {
+	return &Dispatcher{repo: repo, client: client, wake: make(chan struct{}, 1)}
+}
+
+// Publish wakes the dispatcher so newly published events go out without waiting for the next
+// poll. The events themselves are read back from the outbox.
+func (d *Dispatcher) Publish(ctx context.Context, events []events.Event) error {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run enqueues and sends deliveries every interval, or sooner when woken, until ctx is done.
+// Full batches are followed up straight away so a backlog drains quickly.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		enqueued, err := d.repo.EnqueueDeliveries(ctx, enqueueBatchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to enqueue webhook deliveries", "error", err)
+		}
+
+		sent, err := d.deliverDue(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to claim webhook deliveries", "error", err)
+		}
+
+		if enqueued == enqueueBatchSize || sent == deliveryBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue attempts a batch of due deliveries, returning how many were claimed
+func (d *Dispatcher) deliverDue(ctx context.Context) (int, error) {
+	due, err := d.repo.ClaimDueDeliveries(ctx, time.Now(), deliveryLease, deliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	work := make(chan DueDelivery)
+	for i := 0; i < deliveryWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dd := range work {
+				d.attempt(ctx, dd)
+			}
+		}()
+	}
+
+	for _, dd := range due {
+		work <- dd
+	}
+	close(work)
+	wg.Wait()
+
+	return len(due), nil
+}
+
+// attempt sends a delivery and records the outcome. Failures are retried with backoff until
+// delivery.MaxAttempts is reached, when the delivery is dead-lettered.
+func (d *Dispatcher) attempt(ctx context.Context, dd DueDelivery) {
+	statusCode, err := d.client.Send(ctx, delivery.Request{
+		Url:        dd.Url,
+		Secret:     dd.Secret,
+		DeliveryId: dd.Id.String(),
+		EventId:    dd.EventId.String(),
+		EventType:  dd.EventType,
+		Payload:    dd.Payload,
+		CreatedAt:  dd.EventCreatedAt,
+	})
+
+	now := time.Now()
+	outcome := AttemptOutcome{Status: Succeeded, AttemptedAt: now, NextAttemptAt: now}
+	if statusCode != 0 {
+		outcome.LastStatusCode = &statusCode
+	}
+
+	if err != nil {
+		message := err.Error()
+		outcome.LastError = &message
+
+		attempts := dd.Attempts + 1
+		if attempts >= delivery.MaxAttempts {
+			outcome.Status = DeadLetter
+		} else {
+			outcome.Status = Pending
+			outcome.NextAttemptAt = now.Add(delivery.Backoff(attempts))
+		}
+	}
+
+	if err := d.repo.RecordAttempt(ctx, dd.Id, outcome); err != nil {
+		slog.ErrorContext(ctx, "failed to record webhook delivery", "delivery_id", dd.Id.String(), "error", err)
+	}
+}
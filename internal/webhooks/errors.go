@@ -0,0 +1,19 @@
+package webhooks
+
+type errReason string
+
+const (
+	EndpointDisabled errReason = "Webhook endpoint is disabled."
+)
+
+type WebhookError struct {
+	reason errReason
+}
+
+func (e WebhookError) Error() string {
+	return "Webhook request rejected: " + string(e.reason)
+}
+
+var (
+	ErrEndpointDisabled = WebhookError{reason: EndpointDisabled}
+)
@@ -0,0 +1,100 @@
+package webhooks
+
+import (
+	id "chariottakehome/internal/identifier"
+	"errors"
+	"time"
+)
+
+type Endpoint struct {
+	Id         id.Identifier
+	UserId     id.Identifier
+	Url        string
+	Secret     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DisabledAt *time.Time
+}
+
+type Delivery struct {
+	Id             id.Identifier
+	EndpointId     id.Identifier
+	EventId        id.Identifier
+	EventType      string
+	Payload        []byte
+	EventCreatedAt time.Time
+	Status         DeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastAttemptAt  *time.Time
+	LastStatusCode *int
+	LastError      *string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// DueDelivery is a claimed delivery along with where to send it
+type DueDelivery struct {
+	Delivery
+	Url    string
+	Secret string
+}
+
+// AttemptOutcome is what a delivery attempt leaves behind in the log
+type AttemptOutcome struct {
+	Status         DeliveryStatus
+	AttemptedAt    time.Time
+	NextAttemptAt  time.Time
+	LastStatusCode *int
+	LastError      *string
+}
+
+type DeliveryStatus int
+
+const (
+	Pending DeliveryStatus = iota
+	Succeeded
+	DeadLetter
+)
+
+func (s DeliveryStatus) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Succeeded:
+		return "succeeded"
+	case DeadLetter:
+		return "dead_letter"
+	default:
+		return ""
+	}
+}
+
+func (s *DeliveryStatus) Scan(value interface{}) error {
+	if value == nil {
+		return errors.New("nil value")
+	}
+
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return errors.New("unsupported data type")
+	}
+
+	switch str {
+	case "pending":
+		*s = Pending
+	case "succeeded":
+		*s = Succeeded
+	case "dead_letter":
+		*s = DeadLetter
+	default:
+		return errors.New("unsupported string value")
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package webhooks
+
+import (
+	"chariottakehome/internal/database"
+	id "chariottakehome/internal/identifier"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+type ListDeliveriesResp struct {
+	Deliveries []Delivery
+	NextCursor *id.Identifier
+}
+
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, userId id.Identifier, url string) (*Endpoint, error)
+	GetEndpoint(ctx context.Context, endpointId id.Identifier) (*Endpoint, error)
+	ListEndpoints(ctx context.Context, userId id.Identifier) ([]Endpoint, error)
+	DisableEndpoint(ctx context.Context, endpointId id.Identifier) (*Endpoint, error)
+	GetDelivery(ctx context.Context, deliveryId id.Identifier) (*Delivery, error)
+	ListDeliveries(ctx context.Context, endpointId id.Identifier, status *DeliveryStatus, startCursor *id.Identifier, pageSize int) (*ListDeliveriesResp, error)
+	ReplayDelivery(ctx context.Context, deliveryId id.Identifier) (*Delivery, error)
+	EnqueueDeliveries(ctx context.Context, limit int) (int, error)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]DueDelivery, error)
+	RecordAttempt(ctx context.Context, deliveryId id.Identifier, outcome AttemptOutcome) error
+}
+
+type webhookRepository struct {
+	database *database.DatabasePool
+}
+
+func NewRepo(database *database.DatabasePool) WebhookRepository {
+	return &webhookRepository{database}
+}
+
+// CreateEndpoint registers url to receive the events of every account the user can view, signed
+// with a newly generated secret
+func (r *webhookRepository) CreateEndpoint(ctx context.Context, userId id.Identifier, url string) (*Endpoint, error) {
+	endpointId, err := id.New()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	endpoint := Endpoint{
+		Id:        endpointId,
+		UserId:    userId,
+		Url:       url,
+		Secret:    secret,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	sql, args := prepareInsertEndpoint(endpoint)
+	_, err = r.database.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &endpoint, nil
+}
+
+func (r *webhookRepository) GetEndpoint(ctx context.Context, endpointId id.Identifier) (*Endpoint, error) {
+	var endpoint Endpoint
+	row := r.database.QueryRow(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints WHERE id = $1`, endpointId)
+	if err := scanEndpoint(row, &endpoint); err != nil {
+		return nil, err
+	}
+
+	return &endpoint, nil
+}
+
+func (r *webhookRepository) ListEndpoints(ctx context.Context, userId id.Identifier) ([]Endpoint, error) {
+	rows, err := r.database.Query(ctx, `SELECT `+endpointColumns+`
+	FROM webhook_endpoints
+	WHERE user_id = $1
+	ORDER BY id`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]Endpoint, 0)
+	for rows.Next() {
+		var endpoint Endpoint
+		if err := scanEndpoint(rows, &endpoint); err != nil {
+			return nil, err
+		}
+
+		results = append(results, endpoint)
+	}
+
+	return results, rows.Err()
+}
+
+// DisableEndpoint stops all deliveries to the endpoint, dead-lettering any still outstanding.
+// Disabling an endpoint twice returns it unchanged.
+func (r *webhookRepository) DisableEndpoint(ctx context.Context, endpointId id.Identifier) (*Endpoint, error) {
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var endpoint Endpoint
+	err = scanEndpoint(tx.QueryRow(ctx, endpointDisable, endpointId, time.Now().UTC()), &endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, endpointDeliveriesAbandon, endpointId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to abandon deliveries: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &endpoint, nil
+}
+
+func (r *webhookRepository) GetDelivery(ctx context.Context, deliveryId id.Identifier) (*Delivery, error) {
+	var delivery Delivery
+	row := r.database.QueryRow(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1`, deliveryId)
+	if err := scanDelivery(row, &delivery); err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, endpointId id.Identifier, status *DeliveryStatus, startCursor *id.Identifier, pageSize int) (*ListDeliveriesResp, error) {
+	start := "00000000000000000000"
+	if startCursor != nil {
+		start = startCursor.String()
+	}
+
+	sql, args := prepareListDeliveries(endpointId, status, start, pageSize+1)
+	rows, err := r.database.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]Delivery, 0)
+	for rows.Next() {
+		var d Delivery
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+
+		results = append(results, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var nextCursor *id.Identifier
+	// the extra row is the first result of the next page
+	if len(results) > pageSize {
+		nextCursor = &results[pageSize].Id
+		results = results[:pageSize]
+	}
+
+	return &ListDeliveriesResp{
+		Deliveries: results,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// ReplayDelivery queues a delivery to be sent again straight away with a fresh set of attempts,
+// whatever state it finished in
+func (r *webhookRepository) ReplayDelivery(ctx context.Context, deliveryId id.Identifier) (*Delivery, error) {
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var endpoint Endpoint
+	row := tx.QueryRow(ctx, `SELECT e.`+endpointColumns+`
+	FROM webhook_endpoints e
+	JOIN webhook_deliveries d ON d.endpoint_id = e.id
+	WHERE d.id = $1
+	FOR SHARE OF e`, deliveryId)
+	if err := scanEndpoint(row, &endpoint); err != nil {
+		return nil, err
+	}
+	if endpoint.DisabledAt != nil {
+		return nil, ErrEndpointDisabled
+	}
+
+	var delivery Delivery
+	if err := scanDelivery(tx.QueryRow(ctx, deliveryReplay, deliveryId, time.Now().UTC()), &delivery); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+// EnqueueDeliveries creates a delivery for each endpoint interested in the next limit published
+// events, returning how many events were read. Events are only matched to the endpoints that
+// exist when they're enqueued.
+func (r *webhookRepository) EnqueueDeliveries(ctx context.Context, limit int) (int, error) {
+	tx, err := r.database.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var lastSequence int64
+	if err := tx.QueryRow(ctx, enqueueCursorLock).Scan(&lastSequence); err != nil {
+		return 0, err
+	}
+
+	events, err := txPublishedEventsAfter(ctx, tx, lastSequence, limit)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	accountIds := make([]string, 0)
+	for _, event := range events {
+		accountIds = append(accountIds, event.accountIds...)
+	}
+
+	endpoints, err := txAccountEndpoints(ctx, tx, accountIds)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	for _, event := range events {
+		for _, endpointId := range event.endpoints(endpoints) {
+			deliveryId, err := id.New()
+			if err != nil {
+				return 0, err
+			}
+
+			_, err = tx.Exec(ctx, insertDelivery, deliveryId, endpointId, event.id, event.eventType, event.payload, event.createdAt, now)
+			if err != nil {
+				return 0, fmt.Errorf("failed to insert delivery: %w", err)
+			}
+		}
+	}
+
+	_, err = tx.Exec(ctx, enqueueCursorUpdate, events[len(events)-1].sequence)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update enqueue cursor: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(events), nil
+}
+
+// ClaimDueDeliveries takes up to limit deliveries due by now, leasing them so no other dispatcher
+// attempts them until the lease runs out
+func (r *webhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]DueDelivery, error) {
+	now = now.UTC()
+	rows, err := r.database.Query(ctx, dueDeliveriesClaim, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]DueDelivery, 0)
+	for rows.Next() {
+		var d DueDelivery
+		if err := scanDueDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+
+		results = append(results, d)
+	}
+
+	return results, rows.Err()
+}
+
+func (r *webhookRepository) RecordAttempt(ctx context.Context, deliveryId id.Identifier, outcome AttemptOutcome) error {
+	_, err := r.database.Exec(ctx, deliveryAttemptRecord,
+		deliveryId, outcome.Status.String(), outcome.AttemptedAt.UTC(), outcome.NextAttemptAt.UTC(),
+		outcome.LastStatusCode, outcome.LastError,
+	)
+
+	return err
+}
+
+// newSecret generates an endpoint's signing secret
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
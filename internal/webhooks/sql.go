@@ -0,0 +1,205 @@
+package webhooks
+
+import (
+	id "chariottakehome/internal/identifier"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	insertEndpoint string = `INSERT INTO webhook_endpoints (
+	id, user_id, url, secret, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	endpointColumns string = `id, user_id, url, secret, created_at, updated_at, disabled_at`
+
+	endpointDisable string = `UPDATE webhook_endpoints
+	SET disabled_at = COALESCE(disabled_at, $2)
+	WHERE id = $1
+	RETURNING ` + endpointColumns
+
+	// Nothing is sent to a disabled endpoint, so its outstanding deliveries are given up on
+	endpointDeliveriesAbandon string = `UPDATE webhook_deliveries
+	SET status = 'dead_letter', last_error = 'endpoint disabled'
+	WHERE endpoint_id = $1 AND status = 'pending'`
+
+	deliveryColumns string = `id, endpoint_id, event_id, event_type, payload, event_created_at, status, attempts,
+	next_attempt_at, last_attempt_at, last_status_code, last_error, created_at, updated_at`
+
+	insertDelivery string = `INSERT INTO webhook_deliveries (
+	id, endpoint_id, event_id, event_type, payload, event_created_at, status, next_attempt_at
+	) VALUES ($1, $2, $3, $4, $5, $6, 'pending', $7)
+	ON CONFLICT (endpoint_id, event_id) DO NOTHING`
+
+	enqueueCursorLock string = `SELECT last_published_sequence FROM webhook_enqueue_cursor FOR UPDATE`
+
+	enqueueCursorUpdate string = `UPDATE webhook_enqueue_cursor SET last_published_sequence = $1`
+
+	// Reads the outbox directly so the events are taken under the same lock as the cursor, in the
+	// order they were published
+	publishedEventsAfter string = `SELECT published_sequence, event_id, event_type, account_ids, payload, created_at
+	FROM outbox
+	WHERE published_sequence > $1
+	ORDER BY published_sequence
+	LIMIT $2`
+
+	// The enabled endpoints belonging to members who can view the given accounts
+	accountEndpoints string = `SELECT m.account_id, e.id
+	FROM account_members m
+	JOIN webhook_endpoints e ON e.user_id = m.user_id
+	WHERE m.account_id = ANY($1)
+		AND 'view' = ANY(m.permissions)
+		AND e.disabled_at IS NULL`
+
+	// Claiming pushes next_attempt_at out by the lease, so a dispatcher that dies mid-attempt only
+	// delays the delivery rather than losing it. Deliveries to disabled endpoints are never claimed.
+	dueDeliveriesClaim string = `WITH claimed AS (
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1
+				AND e.disabled_at IS NULL
+			ORDER BY d.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns + `
+	)
+	SELECT claimed.*, e.url, e.secret
+	FROM claimed
+	JOIN webhook_endpoints e ON e.id = claimed.endpoint_id`
+
+	// Only pending deliveries are updated, so an attempt that finishes after its endpoint was
+	// disabled can't bring a dead-lettered delivery back
+	deliveryAttemptRecord string = `UPDATE webhook_deliveries
+	SET status = $2,
+		attempts = attempts + 1,
+		last_attempt_at = $3,
+		next_attempt_at = $4,
+		last_status_code = $5,
+		last_error = $6
+	WHERE id = $1 AND status = 'pending'`
+
+	deliveryReplay string = `UPDATE webhook_deliveries
+	SET status = 'pending', attempts = 0, next_attempt_at = $2
+	WHERE id = $1
+	RETURNING ` + deliveryColumns
+)
+
+func prepareInsertEndpoint(e Endpoint) (string, []any) {
+	args := []any{e.Id, e.UserId, e.Url, e.Secret, e.CreatedAt, e.UpdatedAt}
+
+	return insertEndpoint, args
+}
+
+func scanEndpoint(row pgx.Row, e *Endpoint) error {
+	return row.Scan(&e.Id, &e.UserId, &e.Url, &e.Secret, &e.CreatedAt, &e.UpdatedAt, &e.DisabledAt)
+}
+
+func deliveryFields(d *Delivery) []any {
+	return []any{
+		&d.Id, &d.EndpointId, &d.EventId, &d.EventType, &d.Payload, &d.EventCreatedAt, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.UpdatedAt,
+	}
+}
+
+func scanDelivery(row pgx.Row, d *Delivery) error {
+	return row.Scan(deliveryFields(d)...)
+}
+
+func scanDueDelivery(row pgx.Row, d *DueDelivery) error {
+	return row.Scan(append(deliveryFields(&d.Delivery), &d.Url, &d.Secret)...)
+}
+
+// prepareListDeliveries filters an endpoint's deliveries by status when one is given
+func prepareListDeliveries(endpointId id.Identifier, status *DeliveryStatus, start string, limit int) (string, []any) {
+	args := []any{endpointId, start}
+	conditions := []string{"endpoint_id = $1", "id >= $2"}
+
+	if status != nil {
+		args = append(args, status.String())
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	args = append(args, limit)
+	sql := fmt.Sprintf(`SELECT %s
+	FROM webhook_deliveries
+	WHERE %s
+	ORDER BY id
+	LIMIT $%d`, deliveryColumns, strings.Join(conditions, "\n\t\tAND "), len(args))
+
+	return sql, args
+}
+
+// outboxEvent is a published event as read for enqueuing, kept in its stored form
+type outboxEvent struct {
+	sequence   int64
+	id         string
+	eventType  string
+	accountIds []string
+	payload    []byte
+	createdAt  time.Time
+}
+
+// endpoints returns the endpoints of every account the event involves, without duplicates so a
+// transfer between two of a user's accounts is delivered once
+func (e outboxEvent) endpoints(accountEndpoints map[string][]string) []string {
+	results := make([]string, 0)
+	for _, accountId := range e.accountIds {
+		for _, endpointId := range accountEndpoints[accountId] {
+			if !slices.Contains(results, endpointId) {
+				results = append(results, endpointId)
+			}
+		}
+	}
+
+	return results
+}
+
+func txPublishedEventsAfter(ctx context.Context, tx pgx.Tx, after int64, limit int) ([]outboxEvent, error) {
+	rows, err := tx.Query(ctx, publishedEventsAfter, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]outboxEvent, 0)
+	for rows.Next() {
+		var e outboxEvent
+		if err := rows.Scan(&e.sequence, &e.id, &e.eventType, &e.accountIds, &e.payload, &e.createdAt); err != nil {
+			return nil, err
+		}
+
+		results = append(results, e)
+	}
+
+	return results, rows.Err()
+}
+
+// txAccountEndpoints maps each of the accounts to its owner's enabled endpoints
+func txAccountEndpoints(ctx context.Context, tx pgx.Tx, accountIds []string) (map[string][]string, error) {
+	rows, err := tx.Query(ctx, accountEndpoints, accountIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make(map[string][]string)
+	for rows.Next() {
+		var accountId, endpointId string
+		if err := rows.Scan(&accountId, &endpointId); err != nil {
+			return nil, err
+		}
+
+		results[accountId] = append(results[accountId], endpointId)
+	}
+
+	return results, rows.Err()
+}
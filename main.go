@@ -2,47 +2,536 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	e "chariottakehome/api/errors"
+	"chariottakehome/api/gateway"
 	accountspb "chariottakehome/api/services/accounts"
 	userspb "chariottakehome/api/services/users"
 	"chariottakehome/internal/accounts"
+	"chariottakehome/internal/apikeys"
+	"chariottakehome/internal/auth"
+	"chariottakehome/internal/config"
+	"chariottakehome/internal/cursor"
 	"chariottakehome/internal/database"
+	"chariottakehome/internal/events"
+	"chariottakehome/internal/health"
+	id "chariottakehome/internal/identifier"
+	"chariottakehome/internal/logging"
+	"chariottakehome/internal/migrate"
+	"chariottakehome/internal/ratelimit"
+	"chariottakehome/internal/telemetry"
 	"chariottakehome/internal/users"
+	"chariottakehome/internal/webhooks"
+	"chariottakehome/internal/webhooks/delivery"
+	"chariottakehome/migrations"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionalphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
+// fatal logs msg and exits, for failures the server can't start or keep running after
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// healthCheckInterval is how often the database is pinged to decide whether the server is serving
+const healthCheckInterval = 5 * time.Second
+
 func main() {
-	lis, err := net.Listen("tcp", ":8080")
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("failed to load config", "error", err)
+	}
+	slog.SetDefault(slog.New(logging.NewHandler(os.Stderr, cfg.LogLevel)))
+
+	// Cancelled on SIGINT or SIGTERM, which starts a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := telemetry.SetupTracing(ctx, cfg.Telemetry)
+	if err != nil {
+		fatal("failed to set up tracing", "error", err)
+	}
+	defer func() {
+		// Flushes spans that haven't been exported yet
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Warn("failed to flush traces", "error", err)
+		}
+	}()
+
+	db, err := database.Open(ctx, cfg.Database)
+	if err != nil {
+		fatal("failed to open database", "error", err)
+	}
+	defer db.Close()
+
+	if len(args) > 0 {
+		runCommand(ctx, db, args)
+		return
+	}
+
+	serve(ctx, cfg, db)
+}
+
+func serve(ctx context.Context, cfg config.Config, db *database.DatabasePool) {
+	lis, err := net.Listen("tcp", cfg.ListenAddr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		fatal("failed to listen", "addr", cfg.ListenAddr, "error", err)
+	}
+
+	// Load balancers and orchestrators check health without credentials, and the protos
+	// reflection serves are published with the repo anyway. Neither is rate limited.
+	infrastructure := map[string]bool{
+		healthpb.Health_Check_FullMethodName:                                   true,
+		healthpb.Health_Watch_FullMethodName:                                   true,
+		reflectionpb.ServerReflection_ServerReflectionInfo_FullMethodName:      true,
+		reflectionalphapb.ServerReflection_ServerReflectionInfo_FullMethodName: true,
+	}
+
+	apiKeyRepo := apikeys.NewRepo(db)
+	authenticator := &auth.Authenticator{
+		ApiKeys: apiKeyRepo,
+		Tokens:  tokenVerifier(cfg.Auth),
+		Public:  make(map[string]bool),
+	}
+	for method := range infrastructure {
+		authenticator.Public[method] = true
+	}
+
+	// Cancelled once the server starts draining, to end streams that would otherwise run forever
+	draining, stopStreams := context.WithCancel(context.Background())
+	defer stopStreams()
+
+	var limiter *ratelimit.Limiter
+	var limiterStore ratelimit.Store
+	switch cfg.RateLimit.Backend {
+	case config.RateLimitBackendMemory:
+		limiterStore = ratelimit.NewMemoryStore()
+	case config.RateLimitBackendPostgres:
+		limiterStore = ratelimit.NewPostgresStore(db)
+	}
+	if limiterStore != nil {
+		limiter = &ratelimit.Limiter{
+			Store:        limiterStore,
+			Default:      cfg.RateLimit.Default,
+			Methods:      cfg.RateLimit.Methods,
+			Exempt:       infrastructure,
+			AuthFailures: cfg.RateLimit.AuthFailures,
+		}
+	}
+
+	// Telemetry is outermost so it records the status the client actually gets, errorInterceptor
+	// comes before logging so the logs still see the underlying error, authentication comes after
+	// logging so rejected calls are logged too, and rate limiting comes after authentication so
+	// calls are limited by who made them. Failed authentications are limited by address, so that
+	// limiter goes just before authentication.
+	unaryInterceptors := []grpc.UnaryServerInterceptor{telemetry.UnaryInterceptor, errorInterceptor, logging.UnaryInterceptor}
+	if cfg.RequestTimeout > 0 {
+		unaryInterceptors = append(unaryInterceptors, timeoutInterceptor(cfg.RequestTimeout))
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{telemetry.StreamInterceptor, streamErrorInterceptor, logging.StreamInterceptor, drainStreamInterceptor(draining)}
+	if limiter != nil {
+		unaryInterceptors = append(unaryInterceptors, limiter.AuthUnaryInterceptor, authenticator.UnaryInterceptor, limiter.UnaryInterceptor)
+		streamInterceptors = append(streamInterceptors, limiter.AuthStreamInterceptor, authenticator.StreamInterceptor, limiter.StreamInterceptor)
+	} else {
+		unaryInterceptors = append(unaryInterceptors, authenticator.UnaryInterceptor)
+		streamInterceptors = append(streamInterceptors, authenticator.StreamInterceptor)
 	}
 
 	s := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
 	)
-	userspb.RegisterUserServiceServer(s, &userspb.UserService{Repo: users.NewRepo(database.ConnPool())})
-	accountspb.RegisterAccountServiceServer(s, &accountspb.AccountService{Repo: accounts.NewRepo(database.ConnPool())})
+	webhookRepo := webhooks.NewRepo(db)
+	userspb.RegisterUserServiceServer(s, &userspb.UserService{
+		Repo:              users.NewRepo(db),
+		Webhooks:          webhookRepo,
+		ApiKeys:           apiKeyRepo,
+		AllowHttpWebhooks: cfg.Webhooks.AllowHttp,
+	})
+	accountRepo := accounts.NewRepo(db)
+	eventRepo := events.NewRepo(db)
+	eventBroker := events.NewBroker()
+	accountspb.RegisterAccountServiceServer(s, &accountspb.AccountService{
+		Repo:                 accountRepo,
+		Events:               eventRepo,
+		EventBroker:          eventBroker,
+		Cursors:              cursor.NewSigner(cursorKey(cfg.CursorSecret)),
+		MaxTransactionAmount: cfg.Accounts.MaxTransactionAmount,
+		HoldTTL:              cfg.Accounts.HoldTTL,
+	})
+
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	reflection.Register(s)
+
+	// Background jobs outlive ctx so they keep running while in-flight calls drain
+	jobs, stopJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopJobs()
+
+	go health.Run(jobs, healthServer, db, healthCheckInterval, userspb.UserService_ServiceDesc.ServiceName, accountspb.AccountService_ServiceDesc.ServiceName)
+	go accounts.RunBalanceSnapshots(jobs, accountRepo, time.Hour)
+	go accounts.RunHoldExpiry(jobs, accountRepo, time.Minute)
+	dispatcher := webhooks.NewDispatcher(webhookRepo, delivery.NewClient(nil))
+	go events.RunRelay(jobs, eventRepo, time.Second, eventBroker, dispatcher)
+	go dispatcher.Run(jobs, 5*time.Second)
+	if limiterStore != nil {
+		go ratelimit.RunSweep(jobs, limiterStore, 5*time.Minute)
+	}
+
+	served := make(chan error, 3)
+	go func() {
+		served <- s.Serve(lis)
+	}()
+	slog.Info("server listening", "addr", lis.Addr().String())
+
+	var (
+		gatewayServer *http.Server
+		gatewayConn   *grpc.ClientConn
+	)
+	if cfg.HttpListenAddr != "" {
+		gatewayServer, gatewayConn = serveGateway(cfg.HttpListenAddr, lis.Addr(), served)
+	}
+
+	var metricsServer *http.Server
+	if cfg.Telemetry.MetricsListenAddr != "" {
+		prometheus.MustRegister(telemetry.NewPoolCollector(db.Stat))
+		metricsServer = serveMetrics(cfg.Telemetry.MetricsListenAddr, served)
+	}
+
+	select {
+	case err := <-served:
+		fatal("failed to serve", "error", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining calls", "timeout", cfg.ShutdownTimeout.String())
+	shutdown, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Fail health checks first so load balancers stop routing new calls here
+	healthServer.Shutdown()
+	stopStreams()
+	// The gateway's calls go through the gRPC server, so it has to finish first
+	if gatewayServer != nil {
+		if err := gatewayServer.Shutdown(shutdown); err != nil {
+			slog.Warn("gateway requests still running, stopping anyway", "error", err)
+			gatewayServer.Close()
+		}
+		gatewayConn.Close()
+	}
+	drain(shutdown, s)
+	// Kept up until the end so the drain shows up in the metrics
+	if metricsServer != nil {
+		metricsServer.Close()
+	}
+	slog.Info("server stopped")
+}
+
+// serveMetrics serves Prometheus metrics at /metrics on addr. Serve errors are sent to served.
+func serveMetrics(addr string, served chan<- error) *http.Server {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		fatal("failed to listen", "addr", addr, "error", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+			served <- err
+		}
+	}()
+	slog.Info("metrics listening", "addr", lis.Addr().String())
+
+	return server
+}
+
+// serveGateway serves the REST gateway on addr, forwarding its calls to the gRPC server listening
+// on grpcAddr. Serve errors are sent to served.
+func serveGateway(addr string, grpcAddr net.Addr, served chan<- error) (*http.Server, *grpc.ClientConn) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		fatal("failed to listen", "addr", addr, "error", err)
+	}
+
+	conn, err := grpc.NewClient(gatewayTarget(grpcAddr.(*net.TCPAddr)), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fatal("failed to connect gateway", "error", err)
+	}
+
+	gw, err := gateway.New(conn)
+	if err != nil {
+		fatal("failed to create gateway", "error", err)
+	}
+
+	server := &http.Server{
+		Handler:           gw,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+			served <- err
+		}
+	}()
+	slog.Info("gateway listening", "addr", lis.Addr().String())
+
+	return server, conn
+}
+
+// gatewayTarget is the address the gateway dials the gRPC server on. That's where the server
+// listens, unless it listens on every interface, when it's dialled over loopback instead.
+func gatewayTarget(grpcAddr *net.TCPAddr) string {
+	if grpcAddr.IP.IsUnspecified() {
+		return net.JoinHostPort("localhost", strconv.Itoa(grpcAddr.Port))
+	}
+
+	return grpcAddr.String()
+}
+
+// drain stops s once in-flight calls have finished, cutting off any still running when ctx is done
+func drain(ctx context.Context, s *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("calls still running, stopping anyway")
+		s.Stop()
+		<-stopped
+	}
+}
+
+// cursorKey returns the configured pagination cursor signing key. Without one a random key is
+// used, so cursors stop working when the server restarts and aren't shared between instances.
+func cursorKey(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+
+	slog.Warn("CURSOR_SECRET is not set, using a random key for pagination cursors")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		fatal("failed to generate cursor key", "error", err)
+	}
+	return key
+}
+
+// runCommand runs an administrative command instead of the server
+func runCommand(ctx context.Context, db *database.DatabasePool, args []string) {
+	switch args[0] {
+	case "create-user":
+		// Users are created by operators, so the first one has to come from somewhere other than
+		// the API
+		if len(args) != 2 {
+			fatal("usage: create-user <email>")
+		}
+
+		user, err := users.NewRepo(db).CreateUser(ctx, args[1])
+		if err != nil {
+			fatal("failed to create user", "error", err)
+		}
+		fmt.Println(user.Id)
+	case "create-api-key":
+		// The first key for a user, or the first operator, has to come from somewhere other than
+		// the API
+		if len(args) != 3 && len(args) != 4 {
+			fatal("usage: create-api-key <user_id> <name> [owner|viewer|operator]")
+		}
+		userId, err := id.FromString(args[1])
+		if err != nil {
+			fatal("invalid user id", "error", err)
+		}
+		role := auth.Owner
+		if len(args) == 4 {
+			if err := role.Scan(args[3]); err != nil {
+				fatal("invalid role", "role", args[3])
+			}
+		}
+
+		_, key, err := apikeys.NewRepo(db).CreateApiKey(ctx, userId, args[2], role)
+		if err != nil {
+			fatal("failed to create API key", "error", err)
+		}
+		fmt.Println(key)
+	case "migrate":
+		migrateCommand(ctx, db, args[1:])
+	default:
+		fatal("unknown command", "command", args[0])
+	}
+}
+
+// migrateCommand applies or rolls back the embedded migrations, records them as applied for a
+// database that predates them, or prints which are applied
+func migrateCommand(ctx context.Context, db *database.DatabasePool, args []string) {
+	const usage = "usage: migrate up|down|status|to <version>|baseline <version>"
+	if len(args) == 0 {
+		fatal(usage)
+	}
+
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		fatal("failed to load migrations", "error", err)
+	}
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		err = migrator.Up(ctx)
+	case args[0] == "down" && len(args) == 1:
+		err = migrator.Down(ctx)
+	case args[0] == "to" && len(args) == 2:
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil {
+			fatal("invalid version", "version", args[1])
+		}
+		err = migrator.To(ctx, version)
+	case args[0] == "baseline" && len(args) == 2:
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil {
+			fatal("invalid version", "version", args[1])
+		}
+		err = migrator.Baseline(ctx, version)
+	case args[0] == "status" && len(args) == 1:
+		var statuses []migrate.Status
+		statuses, err = migrator.Status(ctx)
+		if err == nil {
+			printMigrationStatus(statuses)
+		}
+	default:
+		fatal(usage)
+	}
+	if err != nil {
+		fatal("migration failed", "error", err)
+	}
+}
+
+func printMigrationStatus(statuses []migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+	}
+	w.Flush()
+}
+
+// tokenVerifier accepts JWTs signed by a key in the configured JWKS file, checking the issuer and
+// audience when they're set. Without a JWKS file only API keys are accepted.
+func tokenVerifier(cfg config.Auth) *auth.TokenVerifier {
+	if cfg.JwksFile == "" {
+		slog.Warn("JWKS_FILE is not set, only API keys will be accepted")
+		return nil
+	}
+
+	keys, err := auth.LoadKeySet(cfg.JwksFile)
+	if err != nil {
+		fatal("failed to load JWKS", "error", err)
+	}
+	return auth.NewTokenVerifier(keys, cfg.JwtIssuer, cfg.JwtAudience)
+}
+
+// timeoutInterceptor bounds each unary call, so a stuck query can't hold a connection forever
+func timeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
+
+// drainStreamInterceptor cancels each stream's context once draining is done, so long-lived
+// streams like WatchAccountEvents end instead of holding up a graceful stop. Watchers resume from
+// their last event on another instance.
+func drainStreamInterceptor(draining context.Context) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+		stop := context.AfterFunc(draining, cancel)
+		defer stop()
 
-	log.Printf("Server listening at %v", lis.Addr())
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+		return handler(srv, &drainingStream{ServerStream: ss, ctx: ctx})
 	}
 }
 
-func loggingInterceptor(
+type drainingStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *drainingStream) Context() context.Context {
+	return s.ctx
+}
+
+func errorInterceptor(
 	ctx context.Context,
 	req interface{},
 	info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler,
 ) (interface{}, error) {
-	log.Printf("Received request: %s", info.FullMethod)
-
 	resp, err := handler(ctx, req)
 	if err != nil {
-		log.Printf("Error handling request: %s", err)
+		return resp, e.ToStatus(err).Err()
+	}
+	return resp, nil
+}
+
+func streamErrorInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	err := handler(srv, ss)
+	if err != nil {
+		return e.ToStatus(err).Err()
 	}
-	return resp, err
+	return nil
 }
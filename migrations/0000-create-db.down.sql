@@ -0,0 +1 @@
+DROP FUNCTION update_timestamp();
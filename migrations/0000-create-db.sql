@@ -1,5 +1,4 @@
-CREATE DATABASE chariot;
-
+-- The database itself is created by Postgres from POSTGRES_DB, and migrations run inside it
 CREATE OR REPLACE FUNCTION update_timestamp()
 RETURNS TRIGGER AS $$
 BEGIN
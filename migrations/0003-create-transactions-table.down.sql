@@ -0,0 +1,3 @@
+DROP TABLE transactions;
+DROP TYPE status_type;
+DROP TYPE transaction_type_type;
@@ -0,0 +1,2 @@
+-- How far below zero an account's balance may go. 0 means no overdraft is allowed.
+ALTER TABLE accounts ADD COLUMN overdraft_limit INT NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0);
@@ -0,0 +1,10 @@
+DROP TRIGGER check_transactions_journal_entry_balanced ON transactions;
+DROP FUNCTION check_journal_entry_balanced();
+
+ALTER TABLE transactions DROP COLUMN journal_entry_id;
+DROP TABLE journal_entries;
+
+-- Without the ledger the system accounts are meaningless, and so are the legs posted to them
+DELETE FROM transactions WHERE account_id IN (SELECT id FROM accounts WHERE user_id = 'c-0000000000SYSTEM00');
+DELETE FROM accounts WHERE user_id = 'c-0000000000SYSTEM00';
+DELETE FROM users WHERE id = 'c-0000000000SYSTEM00';
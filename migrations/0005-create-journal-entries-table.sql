@@ -0,0 +1,44 @@
+-- Every money movement is posted as one journal entry whose legs (rows in transactions) sum to zero
+CREATE TABLE journal_entries (
+    id CHAR(20) PRIMARY KEY,
+    description VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Nullable as failed attempts are recorded without being posted to the ledger
+ALTER TABLE transactions ADD COLUMN journal_entry_id CHAR(20);
+ALTER TABLE transactions ADD FOREIGN KEY (journal_entry_id) REFERENCES journal_entries(id);
+
+CREATE INDEX idx_transactions_journal_entry_id ON transactions(journal_entry_id);
+
+-- Checked at commit time, once every leg of the entry has been inserted
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced()
+RETURNS TRIGGER AS $$
+BEGIN
+   IF NEW.journal_entry_id IS NULL THEN
+      RETURN NULL;
+   END IF;
+
+   IF (SELECT SUM(CASE WHEN transaction_type = 'credit' THEN amount ELSE -amount END)
+       FROM transactions
+       WHERE journal_entry_id = NEW.journal_entry_id) <> 0 THEN
+      RAISE EXCEPTION 'journal entry % is not balanced', NEW.journal_entry_id;
+   END IF;
+
+   RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER check_transactions_journal_entry_balanced
+AFTER INSERT OR UPDATE ON transactions
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW
+EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- System accounts represent money entering and leaving the ledger so deposits and
+-- withdrawals balance like any other entry. The ids must match internal/accounts/ledger.go
+INSERT INTO users (id, email) VALUES ('c-0000000000SYSTEM00', 'system@chariot.internal');
+
+INSERT INTO accounts (id, user_id, name) VALUES
+    ('c-00000000CASHIN0000', 'c-0000000000SYSTEM00', 'Cash in'),
+    ('c-0000000CASHOUT0000', 'c-0000000000SYSTEM00', 'Cash out');
@@ -0,0 +1,5 @@
+DROP TRIGGER prevent_accounts_currency_change ON accounts;
+DROP FUNCTION prevent_currency_change();
+
+ALTER TABLE transactions DROP COLUMN currency;
+ALTER TABLE accounts DROP COLUMN currency;
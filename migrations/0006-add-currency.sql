@@ -0,0 +1,22 @@
+-- Existing accounts and transactions were all held in USD
+ALTER TABLE accounts ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE accounts ALTER COLUMN currency DROP DEFAULT;
+
+ALTER TABLE transactions ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE transactions ALTER COLUMN currency DROP DEFAULT;
+
+-- An account's currency is fixed once it's created
+CREATE OR REPLACE FUNCTION prevent_currency_change()
+RETURNS TRIGGER AS $$
+BEGIN
+   IF NEW.currency <> OLD.currency THEN
+      RAISE EXCEPTION 'currency of account % cannot be changed', OLD.id;
+   END IF;
+   RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER prevent_accounts_currency_change
+BEFORE UPDATE ON accounts
+FOR EACH ROW
+EXECUTE FUNCTION prevent_currency_change();
@@ -0,0 +1,6 @@
+-- Fails rather than truncating if any amount no longer fits in an INT
+ALTER TABLE accounts
+    ALTER COLUMN balance TYPE INT,
+    ALTER COLUMN overdraft_limit TYPE INT;
+
+ALTER TABLE transactions ALTER COLUMN amount TYPE INT;
@@ -0,0 +1,6 @@
+-- INT overflows at roughly $21M in cents
+ALTER TABLE accounts
+    ALTER COLUMN balance TYPE BIGINT,
+    ALTER COLUMN overdraft_limit TYPE BIGINT;
+
+ALTER TABLE transactions ALTER COLUMN amount TYPE BIGINT;
@@ -0,0 +1,2 @@
+CREATE INDEX idx_transactions_account_id ON transactions(account_id);
+DROP INDEX idx_transactions_account_id_id;
@@ -0,0 +1,4 @@
+-- ListTransactions pages through an account's transactions in id order, which this index serves
+-- directly. It also covers lookups by account_id alone.
+CREATE INDEX idx_transactions_account_id_id ON transactions(account_id, id);
+DROP INDEX idx_transactions_account_id;
@@ -0,0 +1,2 @@
+DROP INDEX idx_transactions_account_id_transaction_date;
+DROP TABLE balance_snapshots;
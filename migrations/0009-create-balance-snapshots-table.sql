@@ -0,0 +1,14 @@
+-- Checkpoints of each account's balance (complete transactions only) as of snapshot_at, so a
+-- historical balance only has to sum the transactions since the nearest checkpoint
+CREATE TABLE balance_snapshots (
+    account_id CHAR(20) NOT NULL,
+    snapshot_at TIMESTAMP NOT NULL,
+    balance BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (account_id, snapshot_at),
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+
+-- GetBalance sums complete transactions between a checkpoint and the requested time
+CREATE INDEX idx_transactions_account_id_transaction_date ON transactions(account_id, transaction_date)
+WHERE status = 'complete';
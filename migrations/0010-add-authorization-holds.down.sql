@@ -0,0 +1,8 @@
+DROP INDEX idx_transactions_pending_expires_at;
+
+ALTER TABLE accounts DROP COLUMN held_balance;
+
+ALTER TABLE transactions
+    DROP COLUMN expires_at,
+    DROP COLUMN authorized_amount,
+    DROP COLUMN updated_at;
@@ -0,0 +1,14 @@
+-- update_transactions_timestamp has expected this column since 0003, and holds are the first
+-- transactions to be updated
+ALTER TABLE transactions ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- Set on holds only: the amount originally authorized and when the hold lapses if not captured
+ALTER TABLE transactions
+    ADD COLUMN authorized_amount BIGINT,
+    ADD COLUMN expires_at TIMESTAMP;
+
+-- Sum of the account's active holds. Available balance is balance - held_balance.
+ALTER TABLE accounts ADD COLUMN held_balance BIGINT NOT NULL DEFAULT 0 CHECK (held_balance >= 0);
+
+-- Expired holds are swept by expires_at
+CREATE INDEX idx_transactions_pending_expires_at ON transactions(expires_at) WHERE status = 'pending';
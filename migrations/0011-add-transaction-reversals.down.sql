@@ -0,0 +1,2 @@
+-- Drops the foreign key and unique index with it
+ALTER TABLE transactions DROP COLUMN reversal_of;
@@ -0,0 +1,6 @@
+-- Each leg of a reversal points at the leg of the original journal entry it compensates. The
+-- unique index stops a transaction being reversed twice, even by concurrent requests.
+ALTER TABLE transactions ADD COLUMN reversal_of CHAR(20);
+ALTER TABLE transactions ADD FOREIGN KEY (reversal_of) REFERENCES transactions(id);
+
+CREATE UNIQUE INDEX idx_transactions_reversal_of ON transactions(reversal_of);
@@ -0,0 +1,15 @@
+-- Events are written here in the same transaction as the change they describe. The relay
+-- assigns event_id when it publishes a row, so ids are ordered by publication rather than by
+-- commit, and a consumer resuming after an id can't miss a late commit.
+CREATE TABLE outbox (
+    sequence BIGSERIAL PRIMARY KEY,
+    event_id CHAR(20) UNIQUE,
+    event_type VARCHAR(50) NOT NULL,
+    account_ids TEXT[] NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_unpublished ON outbox(sequence) WHERE event_id IS NULL;
+CREATE INDEX idx_outbox_account_ids ON outbox USING GIN (account_ids);
@@ -0,0 +1,4 @@
+DROP TABLE webhook_enqueue_cursor;
+DROP TABLE webhook_deliveries;
+DROP TYPE webhook_delivery_status_type;
+DROP TABLE webhook_endpoints;
@@ -0,0 +1,55 @@
+CREATE TABLE webhook_endpoints (
+    id CHAR(20) PRIMARY KEY,
+    user_id CHAR(20) NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- Endpoints are disabled rather than deleted so their delivery log is kept
+    disabled_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TRIGGER update_webhook_endpoints_timestamp
+BEFORE UPDATE ON webhook_endpoints
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_webhook_endpoints_user_id ON webhook_endpoints(user_id);
+
+CREATE TYPE webhook_delivery_status_type AS ENUM ('pending', 'succeeded', 'dead_letter');
+
+-- One row per event per endpoint, doubling as the delivery log. The event is copied in so a
+-- delivery can be replayed on its own.
+CREATE TABLE webhook_deliveries (
+    id CHAR(20) PRIMARY KEY,
+    endpoint_id CHAR(20) NOT NULL,
+    event_id CHAR(20) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    event_created_at TIMESTAMP NOT NULL,
+    status webhook_delivery_status_type NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_attempt_at TIMESTAMP,
+    last_status_code INT,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints(id),
+    UNIQUE (endpoint_id, event_id)
+);
+
+CREATE TRIGGER update_webhook_deliveries_timestamp
+BEFORE UPDATE ON webhook_deliveries
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- How far through the outbox deliveries have been created. A single row, locked while enqueuing.
+CREATE TABLE webhook_enqueue_cursor (
+    last_event_id CHAR(20) NOT NULL
+);
+
+INSERT INTO webhook_enqueue_cursor (last_event_id) VALUES ('00000000000000000000');
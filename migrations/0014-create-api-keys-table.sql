@@ -0,0 +1,18 @@
+CREATE TABLE api_keys (
+    id CHAR(20) PRIMARY KEY,
+    user_id CHAR(20) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    -- SHA-256 of the key, which is only shown to the user once
+    key_hash BYTEA NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TRIGGER update_api_keys_timestamp
+BEFORE UPDATE ON api_keys
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
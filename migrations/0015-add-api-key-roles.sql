@@ -0,0 +1,3 @@
+ALTER TABLE api_keys
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'owner'
+    CHECK (role IN ('owner', 'viewer', 'operator'));
@@ -0,0 +1,24 @@
+-- accounts.user_id stays as the account's creator, while members decide who can use it
+CREATE TABLE account_members (
+    account_id CHAR(20) NOT NULL,
+    user_id CHAR(20) NOT NULL,
+    permissions TEXT[] NOT NULL
+        CHECK (permissions <@ ARRAY['view', 'deposit', 'withdraw', 'transfer', 'admin']),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (account_id, user_id),
+    FOREIGN KEY (account_id) REFERENCES accounts(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TRIGGER update_account_members_timestamp
+BEFORE UPDATE ON account_members
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_account_members_user_id ON account_members(user_id, account_id);
+
+-- Existing owners become their accounts' first admins
+INSERT INTO account_members (account_id, user_id, permissions)
+SELECT id, user_id, ARRAY['view', 'deposit', 'withdraw', 'transfer', 'admin']
+FROM accounts;
@@ -0,0 +1,10 @@
+-- Token buckets for the Postgres rate limit backend, keyed by method and caller. Unlogged since
+-- losing them in a crash only resets everyone's limits, and they're written on every call.
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Idle buckets are swept by updated_at
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
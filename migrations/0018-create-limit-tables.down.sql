@@ -0,0 +1,2 @@
+DROP TABLE account_limits;
+DROP TABLE limit_tiers;
@@ -0,0 +1,35 @@
+-- Caps on what an account can move out in a window. NULL leaves the cap off, and amounts are in
+-- the account currency's minor unit.
+CREATE TABLE limit_tiers (
+    name VARCHAR(64) PRIMARY KEY,
+    daily_withdrawal_amount BIGINT CHECK (daily_withdrawal_amount >= 0),
+    hourly_transfer_count BIGINT CHECK (hourly_transfer_count >= 0),
+    monthly_outbound_amount BIGINT CHECK (monthly_outbound_amount >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_limit_tiers_timestamp
+BEFORE UPDATE ON limit_tiers
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+-- Caps set on an account override its tier's, and those it leaves NULL fall back to the tier
+CREATE TABLE account_limits (
+    account_id CHAR(20) PRIMARY KEY,
+    tier VARCHAR(64),
+    daily_withdrawal_amount BIGINT CHECK (daily_withdrawal_amount >= 0),
+    hourly_transfer_count BIGINT CHECK (hourly_transfer_count >= 0),
+    monthly_outbound_amount BIGINT CHECK (monthly_outbound_amount >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (account_id) REFERENCES accounts(id),
+    FOREIGN KEY (tier) REFERENCES limit_tiers(name)
+);
+
+CREATE TRIGGER update_account_limits_timestamp
+BEFORE UPDATE ON account_limits
+FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_account_limits_tier ON account_limits(tier);
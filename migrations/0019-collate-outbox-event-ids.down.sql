@@ -0,0 +1 @@
+ALTER TABLE outbox ALTER COLUMN event_id TYPE CHAR(20) COLLATE "default";
@@ -0,0 +1,4 @@
+-- Event ids are mixed-case base62, which only sorts in the order ids were generated when compared
+-- bytewise. Under the default collation 'Z' sorts after 'a', so a watcher resuming after an id
+-- could skip events published after it.
+ALTER TABLE outbox ALTER COLUMN event_id TYPE CHAR(20) COLLATE "C";
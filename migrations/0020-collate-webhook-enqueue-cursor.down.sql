@@ -0,0 +1 @@
+ALTER TABLE webhook_enqueue_cursor ALTER COLUMN last_event_id TYPE CHAR(20) COLLATE "default";
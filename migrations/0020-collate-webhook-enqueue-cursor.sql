@@ -0,0 +1,3 @@
+-- Compared against outbox.event_id, so it sorts bytewise like that column does since 0019.
+-- Otherwise the cursor could move past events that were never enqueued.
+ALTER TABLE webhook_enqueue_cursor ALTER COLUMN last_event_id TYPE CHAR(20) COLLATE "C";
@@ -0,0 +1,5 @@
+INSERT INTO account_members (account_id, user_id, permissions)
+SELECT id, user_id, ARRAY['view', 'deposit', 'withdraw', 'transfer', 'admin']
+FROM accounts
+WHERE user_id = 'c-0000000000SYSTEM00'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+-- 0016 made every account's creator its first admin, which included the system user for the
+-- system accounts. No one acts as a member of those, so the memberships are dropped.
+DELETE FROM account_members WHERE user_id = 'c-0000000000SYSTEM00';
@@ -0,0 +1,4 @@
+DROP INDEX idx_transactions_account_idempotency_key;
+
+CREATE INDEX idx_transactions_idempotency_key ON transactions(idempotency_key);
+ALTER TABLE transactions ADD CONSTRAINT transactions_idempotency_key_key UNIQUE (idempotency_key);
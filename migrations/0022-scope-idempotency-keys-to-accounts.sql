@@ -0,0 +1,6 @@
+-- Clients choose their own idempotency keys, so a key only has to be unique within the account
+-- it was used on. Otherwise one client's key blocks another client from using it.
+ALTER TABLE transactions DROP CONSTRAINT transactions_idempotency_key_key;
+DROP INDEX idx_transactions_idempotency_key;
+
+CREATE UNIQUE INDEX idx_transactions_account_idempotency_key ON transactions(account_id, idempotency_key);
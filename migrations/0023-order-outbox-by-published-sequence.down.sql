@@ -0,0 +1,12 @@
+ALTER TABLE webhook_enqueue_cursor ADD COLUMN last_event_id CHAR(20) COLLATE "C" NOT NULL DEFAULT '00000000000000000000';
+
+UPDATE webhook_enqueue_cursor SET last_event_id = COALESCE(
+    (SELECT event_id FROM outbox WHERE published_sequence = webhook_enqueue_cursor.last_published_sequence),
+    '00000000000000000000'
+);
+
+ALTER TABLE webhook_enqueue_cursor ALTER COLUMN last_event_id DROP DEFAULT;
+ALTER TABLE webhook_enqueue_cursor DROP COLUMN last_published_sequence;
+
+ALTER TABLE outbox DROP COLUMN published_sequence;
+DROP SEQUENCE outbox_published_sequence;
@@ -0,0 +1,27 @@
+-- Event ids are generated from each instance's clock, so a relay on an instance whose clock is
+-- behind could publish an id that sorts before one a consumer has already read, and the event
+-- would be skipped. Published events are ordered by a sequence the relay takes from instead.
+CREATE SEQUENCE outbox_published_sequence;
+
+ALTER TABLE outbox ADD COLUMN published_sequence BIGINT UNIQUE;
+
+-- Events published so far keep the order they were read in
+UPDATE outbox SET published_sequence = ordered.n
+FROM (
+    SELECT sequence, row_number() OVER (ORDER BY event_id) AS n
+    FROM outbox
+    WHERE event_id IS NOT NULL
+) ordered
+WHERE outbox.sequence = ordered.sequence;
+
+SELECT setval('outbox_published_sequence', COALESCE(MAX(published_sequence), 0) + 1, false) FROM outbox;
+
+-- The webhook enqueue cursor moves to the same position, the last event it enqueued
+ALTER TABLE webhook_enqueue_cursor ADD COLUMN last_published_sequence BIGINT NOT NULL DEFAULT 0;
+
+UPDATE webhook_enqueue_cursor SET last_published_sequence = COALESCE(
+    (SELECT MAX(published_sequence) FROM outbox WHERE event_id <= webhook_enqueue_cursor.last_event_id),
+    0
+);
+
+ALTER TABLE webhook_enqueue_cursor DROP COLUMN last_event_id;
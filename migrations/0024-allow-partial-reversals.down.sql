@@ -0,0 +1,4 @@
+-- Fails if a transaction has been reversed more than once since
+DROP INDEX idx_transactions_reversal_of;
+
+CREATE UNIQUE INDEX idx_transactions_reversal_of ON transactions(reversal_of);
@@ -0,0 +1,6 @@
+-- A transaction can be refunded in several partial reversals, so reversal_of can't be unique.
+-- ReverseTransaction locks the original entry's legs and checks its reversals don't add up to
+-- more than the original amount instead.
+DROP INDEX idx_transactions_reversal_of;
+
+CREATE INDEX idx_transactions_reversal_of ON transactions(reversal_of);
@@ -0,0 +1,5 @@
+-- Capturing a hold re-dates it to the capture, when the balance changes, so balance snapshots
+-- stay correct. Spend limits count the hold from when it was authorized instead, or a hold
+-- authorized in one window and captured in the next would count against both. Holds captured
+-- before this migration keep counting from their capture.
+ALTER TABLE transactions ADD COLUMN authorized_at TIMESTAMP;
@@ -0,0 +1,10 @@
+// Package migrations embeds the schema migrations into the binary, so the migrate command always
+// runs the migrations it was built with
+package migrations
+
+import "embed"
+
+// FS holds each migration as NNNN-name.sql, with NNNN-name.down.sql to roll it back
+//
+//go:embed *.sql
+var FS embed.FS